@@ -0,0 +1,180 @@
+/*
+Copyright © 2020 Yevhen Lebid ealebed@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/spf13/pflag"
+
+	"github.com/ealebed/dha/pkg/dockerhub"
+	"github.com/ealebed/dha/pkg/pool"
+)
+
+// repositoryInterval limits rate of starting repositories in bulk commands, shared by all workers
+const repositoryInterval = 300 * time.Millisecond
+
+// newClient returns docker hub client, configured by global flags and configuration file
+func newClient(flags *pflag.FlagSet) (*dockerhub.Client, error) {
+	org, _, err := dockerhub.GetFlags(flags)
+	if err != nil {
+		return nil, err
+	}
+
+	configFile, err := flags.GetString("config")
+	if err != nil {
+		return nil, err
+	}
+	config, err := dockerhub.LoadConfig(configFile)
+	if err != nil {
+		return nil, err
+	}
+
+	protect, err := flags.GetStringSlice("protect")
+	if err != nil {
+		return nil, err
+	}
+
+	verbose, err := flags.GetBool("verbose")
+	if err != nil {
+		return nil, err
+	}
+
+	hubURL, err := flags.GetString("hub-url")
+	if err != nil {
+		return nil, err
+	}
+
+	client := dockerhub.NewClient(org, hubURL)
+	client.Protect = append(client.Protect, config.Protect...)
+	client.Protect = append(client.Protect, protect...)
+	client.Retry.Verbose = verbose
+
+	tokenFile, err := flags.GetString("token-file")
+	if err != nil {
+		return nil, err
+	}
+	if tokenFile != "" {
+		token, err := readTokenFile(tokenFile)
+		if err != nil {
+			return nil, err
+		}
+		client.Credentials = dockerhub.CredentialsFromEnv()
+		client.Credentials.Token = token
+	}
+
+	noTokenCache, err := flags.GetBool("no-token-cache")
+	if err != nil {
+		return nil, err
+	}
+	if !noTokenCache {
+		client.TokenCache = dockerhub.DefaultTokenCache()
+	}
+
+	if isTerminal(os.Stdin) {
+		client.TwoFactorCode = promptTwoFactorCode
+	}
+
+	return client, nil
+}
+
+// newPool returns worker pool for bulk commands, configured by '--concurrency' flag
+func newPool(flags *pflag.FlagSet) (*pool.Pool, error) {
+	concurrency, err := flags.GetInt("concurrency")
+	if err != nil {
+		return nil, err
+	}
+	if concurrency < 1 {
+		return nil, fmt.Errorf("'--concurrency' should be positive")
+	}
+
+	return pool.New(concurrency, repositoryInterval), nil
+}
+
+// printInterrupted prints summary of deletions completed and skipped by client before interruption,
+// with count of processed repositories, when total is provided
+func printInterrupted(client *dockerhub.Client, processed, total int) error {
+	color.Yellow("Interrupted: %s deletions completed, %s failed, %s skipped",
+		dockerhub.BW(client.Progress.Completed()), dockerhub.BW(client.Progress.Failed()), dockerhub.BW(client.Progress.Skipped()))
+	if total > 0 {
+		color.Yellow("Interrupted: %s of %s repositories processed, %s skipped", dockerhub.BW(processed), dockerhub.BW(total), dockerhub.BW(total-processed))
+	}
+
+	return fmt.Errorf("interrupted: %w", context.Canceled)
+}
+
+// printRetries prints total count of retried requests of bulk command in verbose mode, when there were any
+func printRetries(client *dockerhub.Client) {
+	if client.Retry == nil || !client.Retry.Verbose {
+		return
+	}
+	if retries := client.Retry.Retries(); retries > 0 {
+		color.Yellow("Retried %s rate limited or failed requests", dockerhub.BW(retries))
+	}
+}
+
+// readTokenFile returns docker hub access token from file
+func readTokenFile(path string) (string, error) {
+	data, err := os.ReadFile(path) // #nosec G304 -- token file path is provided by the user
+	if err != nil {
+		return "", fmt.Errorf("failed to read token file: %w", err)
+	}
+
+	token := strings.TrimSpace(string(data))
+	if token == "" {
+		return "", fmt.Errorf("token file %s is empty", path)
+	}
+
+	return token, nil
+}
+
+// isTerminal reports whether file is interactive terminal
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// promptTwoFactorCode asks user for two-factor authentication code
+func promptTwoFactorCode(ctx context.Context) (string, error) {
+	fmt.Fprint(os.Stderr, "Docker Hub two-factor authentication code: ")
+
+	code := make(chan string, 1)
+	failed := make(chan error, 1)
+	go func() {
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			failed <- err
+			return
+		}
+		code <- strings.TrimSpace(line)
+	}()
+
+	select {
+	case <-ctx.Done():
+		return "", ctx.Err()
+	case err := <-failed:
+		return "", err
+	case c := <-code:
+		return c, nil
+	}
+}
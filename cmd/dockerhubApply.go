@@ -0,0 +1,91 @@
+/*
+Copyright © 2020 Yevhen Lebid ealebed@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/ealebed/dha/pkg/dockerhub"
+)
+
+// NewDockerhubApplyCmd returns new apply command
+func NewDockerhubApplyCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "apply <planfile>",
+		Short:   "apply deletions from the provided plan file",
+		Long:    "delete exactly the docker image tags and repositories recorded in the provided plan file, refusing if any of them changed since the plan was made",
+		Example: "dha apply dha-plan.json",
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return applyPlan(cmd.Context(), cmd.InheritedFlags(), args[0])
+		},
+	}
+
+	return cmd
+}
+
+// applyPlan deletes docker image tags and repositories recorded in plan file
+func applyPlan(ctx context.Context, flags *pflag.FlagSet, planFile string) error {
+	org, dryRun, err := dockerhub.GetFlags(flags)
+	if err != nil {
+		return err
+	}
+
+	plan, err := dockerhub.LoadPlan(planFile)
+	if err != nil {
+		return err
+	}
+	if flags.Changed("org") && org != plan.Organization {
+		return fmt.Errorf("plan is for organization %s, while '--org' is %s", plan.Organization, org)
+	}
+
+	client, err := newClient(flags)
+	if err != nil {
+		return err
+	}
+	defer printRetries(client)
+	client.ORG = plan.Organization
+
+	if dryRun {
+		if err := client.VerifyPlanContext(ctx, plan); err != nil {
+			return err
+		}
+		for _, tag := range plan.Tags {
+			color.Yellow("[DRY-RUN] Delete tag %s", dockerhub.BW(plan.Organization+"/"+tag.Repository+":"+tag.Name))
+		}
+		for _, repo := range plan.Repositories {
+			color.Yellow("[DRY-RUN] Delete docker image repository: %s", dockerhub.BW(plan.Organization+"/"+repo.Name))
+		}
+		return nil
+	}
+
+	color.Blue("===> %s %s %s %s", dockerhub.BW("Applying plan"), dockerhub.BG(planFile), dockerhub.BW("to organization"), dockerhub.BG(plan.Organization))
+	if err := client.ApplyPlanContext(ctx, plan); err != nil {
+		if ctx.Err() != nil {
+			return printInterrupted(client, 0, 0)
+		}
+		return fmt.Errorf("failed to apply plan: %w", err)
+	}
+	color.Green("Done \u2714")
+
+	return nil
+}
@@ -0,0 +1,240 @@
+/*
+Copyright © 2020 Yevhen Lebid ealebed@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/ealebed/dha/pkg/dockerhub"
+	"github.com/ealebed/dha/pkg/output"
+	"github.com/ealebed/dha/pkg/pool"
+)
+
+// ErrPlatformViolations is returned by audit platforms command, when some tags lack required platforms
+var ErrPlatformViolations = errors.New("tags lack required platforms")
+
+// AuditPlatformsOptions represents options for audit platforms command
+type AuditPlatformsOptions struct {
+	require   []string
+	imageName string
+	tagRegex  string
+	output    OutputOptions
+}
+
+// platformViolation represents docker image tag, which lacks required platforms
+type platformViolation struct {
+	Repository string   `json:"repository"`
+	Tag        string   `json:"tag"`
+	Platforms  []string `json:"platforms"`
+	Missing    []string `json:"missing"`
+}
+
+// repositoryAudit represents platform violations of single repository
+type repositoryAudit struct {
+	Repository string               `json:"repository"`
+	Tags       int                  `json:"tags"`
+	Violations []*platformViolation `json:"violations"`
+}
+
+// violationColumns represents columns of audit platforms CSV, TSV and Markdown output
+var violationColumns = []output.Column[*platformViolation]{
+	{Header: "repository", Value: func(v *platformViolation) string { return v.Repository }},
+	{Header: "tag", Value: func(v *platformViolation) string { return v.Tag }},
+	{Header: "missing", Value: func(v *platformViolation) string { return strings.Join(v.Missing, ",") }},
+	{Header: "platforms", Value: func(v *platformViolation) string { return strings.Join(v.Platforms, ",") }},
+}
+
+// NewDockerhubAuditCmd returns new audit command
+func NewDockerhubAuditCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "audit",
+		Short:   "audit docker image repositories",
+		Long:    "audit docker image repositories of organization and exit with error on violations, e.g. in CI",
+		Example: "dha audit platforms --require=linux/amd64,linux/arm64 [--image=...] [--tagRegEx=...]",
+	}
+
+	cmd.AddCommand(newAuditPlatformsCmd())
+
+	return cmd
+}
+
+// newAuditPlatformsCmd returns new audit platforms command
+func newAuditPlatformsCmd() *cobra.Command {
+	options := AuditPlatformsOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "platforms",
+		Short: "report tags, which lack required platforms",
+		Long: "report docker image tags, which have no images for some of required platforms, grouped by repository, " +
+			"and exit with error when there are such tags",
+		Example: "dha audit platforms --require=linux/amd64,linux/arm64 [--image=...] [--tagRegEx=...] [--output=json|yaml|csv|tsv|table]",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			printer, err := options.output.printer(cmd)
+			if err != nil {
+				return err
+			}
+			return auditPlatforms(cmd.Context(), cmd.InheritedFlags(), printer, &options)
+		},
+	}
+
+	cmd.Flags().StringSliceVar(&options.require, "require", nil, "required platforms as os/architecture[/variant] (e.g. linux/amd64,linux/arm64)")
+	cmd.Flags().StringVarP(&options.imageName, "image", "i", "", "audit single docker image repository instead of all organization repositories")
+	cmd.Flags().StringVar(&options.tagRegex, "tagRegEx", "", "audit only tags matching regular expression (case insensitive, as truncate '--tagRegEx')")
+	addOutputFlags(cmd, &options.output)
+	if err := cmd.MarkFlagRequired("require"); err != nil {
+		// Flag marking should not fail in normal operation
+		return nil
+	}
+
+	return cmd
+}
+
+// auditPlatforms prints tags lacking required platforms, returning ErrPlatformViolations when there are any
+func auditPlatforms(ctx context.Context, flags *pflag.FlagSet, printer *output.Printer, options *AuditPlatformsOptions) error {
+	required := []dockerhub.Platform{}
+	for _, s := range options.require {
+		platform, err := dockerhub.ParsePlatform(s)
+		if err != nil {
+			return err
+		}
+		required = append(required, platform)
+	}
+	if len(required) == 0 {
+		return fmt.Errorf("you should provide required platforms with '--require'")
+	}
+
+	tagRegex, err := regexp.Compile(`(?i)` + options.tagRegex)
+	if err != nil {
+		return fmt.Errorf("invalid '--tagRegEx': %w", err)
+	}
+
+	client, err := newClient(flags)
+	if err != nil {
+		return err
+	}
+	workers, err := newPool(flags)
+	if err != nil {
+		return err
+	}
+
+	repositories := []string{options.imageName}
+	if options.imageName == "" {
+		repos, err := client.ListRepositoriesContext(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to list repositories: %w", err)
+		}
+		repositories = repositories[:0]
+		for _, repo := range repos {
+			repositories = append(repositories, repo.Name)
+		}
+	}
+
+	results := pool.Map(ctx, workers, repositories, func(ctx context.Context, _ int, repo string) (*repositoryAudit, error) {
+		tags, err := client.ListTagsContext(ctx, repo)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", repo, err)
+		}
+		return auditRepositoryPlatforms(repo, tags, tagRegex, required), nil
+	})
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	audits := []*repositoryAudit{}
+	violations := []*platformViolation{}
+	for _, audit := range results.Values {
+		if audit != nil && len(audit.Violations) > 0 {
+			audits = append(audits, audit)
+			violations = append(violations, audit.Violations...)
+		}
+	}
+
+	if err := printPlatformAudits(printer, audits, violations); err != nil {
+		return err
+	}
+
+	if failed := results.Failed(); failed > 0 {
+		return fmt.Errorf("failed to audit %d of %d repositories:\n%w", failed, len(repositories), results.Err())
+	}
+	if len(violations) > 0 {
+		return fmt.Errorf("%d tags in %d repositories: %w %s", len(violations), len(audits), ErrPlatformViolations, strings.Join(options.require, ","))
+	}
+
+	return nil
+}
+
+// auditRepositoryPlatforms returns tags of repository matching regular expression, which lack required platforms
+func auditRepositoryPlatforms(repo string, tags []*dockerhub.Tag, tagRegex *regexp.Regexp, required []dockerhub.Platform) *repositoryAudit {
+	audit := &repositoryAudit{Repository: repo, Violations: []*platformViolation{}}
+	for _, tag := range tags {
+		if !tagRegex.MatchString(tag.Name) {
+			continue
+		}
+		audit.Tags++
+
+		missing := dockerhub.MissingPlatforms(tag, required)
+		if len(missing) == 0 {
+			continue
+		}
+
+		violation := &platformViolation{Repository: repo, Tag: tag.Name, Platforms: []string{}, Missing: []string{}}
+		for _, image := range tag.Images {
+			violation.Platforms = append(violation.Platforms, dockerhub.ImagePlatform(image).String())
+		}
+		for _, platform := range missing {
+			violation.Missing = append(violation.Missing, platform.String())
+		}
+		audit.Violations = append(audit.Violations, violation)
+	}
+
+	return audit
+}
+
+// printPlatformAudits prints violations grouped by repository: JSON, YAML, template and JSONPath get repository audits,
+// CSV, TSV and Markdown get row per tag
+func printPlatformAudits(printer *output.Printer, audits []*repositoryAudit, violations []*platformViolation) error {
+	switch printer.Format {
+	case output.JSON, output.YAML, output.Template, output.JSONPath:
+		return output.PrintList(printer, audits, nil)
+	case output.CSV, output.TSV, output.Markdown:
+		return output.PrintList(printer, violations, violationColumns)
+	}
+
+	if len(audits) == 0 {
+		fmt.Fprintln(printer.Out, color.GreenString("All tags have required platforms"))
+		return nil
+	}
+
+	for _, audit := range audits {
+		fmt.Fprintf(printer.Out, "===> %s: %s of %s tags lack required platforms\n",
+			dockerhub.BW(audit.Repository), dockerhub.BR(len(audit.Violations)), dockerhub.BW(audit.Tags))
+		for _, violation := range audit.Violations {
+			fmt.Fprintf(printer.Out, "\t%-60s | missing %-30s | has %s\n",
+				dockerhub.BW(violation.Tag), dockerhub.BR(strings.Join(violation.Missing, ",")), strings.Join(violation.Platforms, ","))
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,107 @@
+/*
+Copyright © 2020 Yevhen Lebid ealebed@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/ealebed/dha/pkg/dockerhub"
+)
+
+// CreateRepositoryOptions represents options for docker create repository command
+type CreateRepositoryOptions struct {
+	imageName           string
+	private             bool
+	description         string
+	fullDescriptionFile string
+}
+
+// NewDockerhubCreateRepositoryCmd returns new docker create repository command
+func NewDockerhubCreateRepositoryCmd() *cobra.Command {
+	options := CreateRepositoryOptions{}
+
+	cmd := &cobra.Command{
+		Use:     "create",
+		Short:   "create docker repository",
+		Long:    "create docker repository with provided visibility, short description and full description (README)",
+		Example: "dha create [--image=...] [--private] [--description=...] [--full-description-file=README.md]",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return createRepository(cmd.Context(), cmd.InheritedFlags(), &options)
+		},
+	}
+
+	cmd.Flags().StringVarP(&options.imageName, "image", "i", "", "docker image name for create")
+	cmd.Flags().BoolVar(&options.private, "private", false, "create private repository (public by default)")
+	cmd.Flags().StringVar(&options.description, "description", "", "short description of repository")
+	cmd.Flags().StringVar(&options.fullDescriptionFile, "full-description-file", "", "path to file with full description of repository (e.g. README.md)")
+	if err := cmd.MarkFlagRequired("image"); err != nil {
+		// Flag marking should not fail in normal operation
+		return nil
+	}
+
+	return cmd
+}
+
+// createRepository creates docker repository
+func createRepository(ctx context.Context, flags *pflag.FlagSet, options *CreateRepositoryOptions) error {
+	org, dryRun, err := dockerhub.GetFlags(flags)
+	if err != nil {
+		color.Red("Error: %s", err)
+	}
+
+	repo := &dockerhub.Repository{Name: options.imageName, Description: options.description, IsPrivate: options.private}
+	if repo.FullDescription, err = readDescriptionFile(options.fullDescriptionFile); err != nil {
+		return err
+	}
+
+	if dryRun {
+		color.Yellow("[DRY-RUN] Create %s docker image repository: %s/%s", dockerhub.Visibility(repo.IsPrivate), dockerhub.BW(org), dockerhub.BW(repo.Name))
+		return nil
+	}
+
+	color.Blue("===> %s %s", dockerhub.BW("Creating "+dockerhub.Visibility(repo.IsPrivate)+" docker image repository"), dockerhub.BG(org+"/"+repo.Name))
+	client, err := newClient(flags)
+	if err != nil {
+		return err
+	}
+	if _, err := client.CreateRepositoryContext(ctx, repo); err != nil {
+		return fmt.Errorf("failed to create repository: %w", err)
+	}
+	color.Green("Done \u2714")
+
+	return nil
+}
+
+// readDescriptionFile returns content of full description file, or empty description when path isn't provided
+func readDescriptionFile(path string) (string, error) {
+	if path == "" {
+		return "", nil
+	}
+
+	data, err := os.ReadFile(path) // #nosec G304 -- full description file path is provided by the user
+	if err != nil {
+		return "", fmt.Errorf("failed to read full description: %w", err)
+	}
+
+	return string(data), nil
+}
@@ -17,6 +17,7 @@ limitations under the License.
 package cmd
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/fatih/color"
@@ -42,7 +43,7 @@ func NewDockerhubDeleteRepositoryCmd() *cobra.Command {
 		Long:    "delete the specified docker repository",
 		Example: "dha delete [--image=...]",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return deleteRepository(cmd.InheritedFlags(), options.imageName)
+			return deleteRepository(cmd.Context(), cmd.InheritedFlags(), options.imageName)
 		},
 	}
 
@@ -56,7 +57,7 @@ func NewDockerhubDeleteRepositoryCmd() *cobra.Command {
 }
 
 // deleteRepository deletes docker repository
-func deleteRepository(flags *pflag.FlagSet, image string) error {
+func deleteRepository(ctx context.Context, flags *pflag.FlagSet, image string) error {
 	org, dryRun, err := dockerhub.GetFlags(flags)
 	if err != nil {
 		color.Red("Error: %s", err)
@@ -66,7 +67,11 @@ func deleteRepository(flags *pflag.FlagSet, image string) error {
 		color.Yellow("[DRY-RUN] Delete docker image repository: %s/%s", dockerhub.BW(org), dockerhub.BW(image))
 	} else {
 		color.Blue("===> %s %s", dockerhub.BW("Deleting docker image repository"), dockerhub.BG(org+"/"+image))
-		if err := dockerhub.NewClient(org, "").DeleteRepository(image); err != nil {
+		client, err := newClient(flags)
+		if err != nil {
+			return err
+		}
+		if err := client.DeleteRepositoryContext(ctx, image); err != nil {
 			return fmt.Errorf("failed to delete repository: %w", err)
 		}
 		color.Green("Done \u2714")
@@ -17,18 +17,43 @@ limitations under the License.
 package cmd
 
 import (
+	"context"
 	"fmt"
+	"strconv"
+	"strings"
 
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 
 	"github.com/ealebed/dha/pkg/dockerhub"
+	"github.com/ealebed/dha/pkg/output"
 )
 
 // DescribeRepositoryOptions represents options for get command
 type DescribeRepositoryOptions struct {
 	imageName string
+	output    OutputOptions
+}
+
+// repositoryFields represents fields of describe command table, CSV and TSV output
+var repositoryFields = []output.Column[*dockerhub.Repository]{
+	{Header: "User", Value: func(r *dockerhub.Repository) string { return r.User }},
+	{Header: "Name", Value: func(r *dockerhub.Repository) string { return r.Name }},
+	{Header: "Namespace", Value: func(r *dockerhub.Repository) string { return r.Namespace }},
+	{Header: "RepositoryType", Value: func(r *dockerhub.Repository) string { return r.RepositoryType }},
+	{Header: "Status", Value: func(r *dockerhub.Repository) string { return strconv.Itoa(r.Status) }},
+	{Header: "Description", Value: func(r *dockerhub.Repository) string { return r.Description }},
+	{Header: "IsPrivate", Value: func(r *dockerhub.Repository) string { return strconv.FormatBool(r.IsPrivate) }},
+	{Header: "IsAutomated", Value: func(r *dockerhub.Repository) string { return strconv.FormatBool(r.IsAutomated) }},
+	{Header: "CanEdit", Value: func(r *dockerhub.Repository) string { return strconv.FormatBool(r.CanEdit) }},
+	{Header: "StarCount", Value: func(r *dockerhub.Repository) string { return strconv.Itoa(r.StarCount) }},
+	{Header: "PullCount", Value: func(r *dockerhub.Repository) string { return strconv.Itoa(r.PullCount) }},
+	{Header: "LastUpdated", Value: func(r *dockerhub.Repository) string { return r.LastUpdated.String() }},
+	{Header: "IsMigrated", Value: func(r *dockerhub.Repository) string { return strconv.FormatBool(r.IsMigrated) }},
+	{Header: "CollaboratorCount", Value: func(r *dockerhub.Repository) string { return strconv.Itoa(r.CollaboratorCount) }},
+	{Header: "Affiliation", Value: func(r *dockerhub.Repository) string { return r.Affiliation }},
+	{Header: "HubUser", Value: func(r *dockerhub.Repository) string { return r.HubUser }},
 }
 
 // NewDockerhubDescribeRepositoryCmd returns new docker get repository command
@@ -39,13 +64,18 @@ func NewDockerhubDescribeRepositoryCmd() *cobra.Command {
 		Use:     "describe",
 		Short:   "returns info about provided dockerhub repository (image)",
 		Long:    "returns detailed information about provided dockerhub repository (image)",
-		Example: "dha describe [--image=...]",
+		Example: "dha describe [--image=...] [--output=json|yaml|table|jsonpath=...]",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return describeRepository(cmd.InheritedFlags(), options.imageName)
+			printer, err := options.output.printer(cmd)
+			if err != nil {
+				return err
+			}
+			return describeRepository(cmd.Context(), cmd.InheritedFlags(), printer, options.imageName)
 		},
 	}
 
 	cmd.Flags().StringVarP(&options.imageName, "image", "i", "", "docker image name for getting information")
+	addOutputFlags(cmd, &options.output)
 	if err := cmd.MarkFlagRequired("image"); err != nil {
 		// Flag marking should not fail in normal operation
 		return nil
@@ -55,33 +85,26 @@ func NewDockerhubDescribeRepositoryCmd() *cobra.Command {
 }
 
 // describeRepository returns information about the provided dockerhub repository (image)
-func describeRepository(flags *pflag.FlagSet, image string) error {
-	org, _, err := dockerhub.GetFlags(flags)
+func describeRepository(ctx context.Context, flags *pflag.FlagSet, printer *output.Printer, image string) error {
+	client, err := newClient(flags)
 	if err != nil {
-		color.Red("Error: %s", err)
+		return err
 	}
 
-	repoInfo, err := dockerhub.NewClient(org, "").DescribeRepository(image)
+	repoInfo, err := client.DescribeRepositoryContext(ctx, image)
 	if err != nil {
-		color.Red("Error: %s", err)
+		return fmt.Errorf("failed to describe repository: %w", err)
 	}
 
-	color.Blue("User: " + repoInfo.User +
-		"\nName: " + repoInfo.Name +
-		"\nNamespace: " + repoInfo.Namespace +
-		"\nRepositoryType: " + repoInfo.RepositoryType +
-		"\nStatus: " + fmt.Sprintf("%d", repoInfo.Status) +
-		"\nDescription: " + repoInfo.Description +
-		"\nIsPrivate: " + fmt.Sprintf("%t", repoInfo.IsPrivate) +
-		"\nIsAutomated: " + fmt.Sprintf("%t", repoInfo.IsAutomated) +
-		"\nCanEdit: " + fmt.Sprintf("%t", repoInfo.CanEdit) +
-		"\nStarCount: " + fmt.Sprintf("%d", repoInfo.StarCount) +
-		"\nPullCount: " + fmt.Sprintf("%d", repoInfo.PullCount) +
-		"\nLastUpdated: " + repoInfo.LastUpdated.String() +
-		"\nIsMigrated: " + fmt.Sprintf("%t", repoInfo.IsMigrated) +
-		"\nCollaboratorCount: " + fmt.Sprintf("%d", repoInfo.CollaboratorCount) +
-		"\nAffiliation: " + repoInfo.Affiliation +
-		"\nHubUser: " + repoInfo.HubUser)
+	if !printer.IsTable() {
+		return output.PrintObject(printer, repoInfo, repositoryFields)
+	}
+
+	lines := make([]string, 0, len(repositoryFields))
+	for _, field := range repositoryFields {
+		lines = append(lines, field.Header+": "+field.Value(repoInfo))
+	}
+	fmt.Fprintln(printer.Out, color.BlueString("%s", strings.Join(lines, "\n")))
 
 	return nil
 }
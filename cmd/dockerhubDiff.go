@@ -0,0 +1,166 @@
+/*
+Copyright © 2020 Yevhen Lebid ealebed@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/ealebed/dha/pkg/dockerhub"
+	"github.com/ealebed/dha/pkg/output"
+)
+
+// ErrDrift is returned by diff command, when repositories differ from manifest
+var ErrDrift = errors.New("repositories drifted from manifest")
+
+// DiffOptions represents options for diff command
+type DiffOptions struct {
+	file   string
+	output OutputOptions
+}
+
+// driftChange represents single changed field of drifted repository, printed as table, CSV and TSV row
+type driftChange struct {
+	Repository string
+	Action     string
+	*dockerhub.Change
+}
+
+// driftColumns represents columns of diff table, CSV and TSV output
+var driftColumns = []output.Column[*driftChange]{
+	{Header: "repository", Value: func(c *driftChange) string { return c.Repository }},
+	{Header: "action", Value: func(c *driftChange) string { return c.Action }},
+	{Header: "field", Value: func(c *driftChange) string { return c.Field }},
+	{Header: "live", Value: func(c *driftChange) string { return c.Live }},
+	{Header: "desired", Value: func(c *driftChange) string { return c.Desired }},
+}
+
+// NewDockerhubDiffCmd returns new diff command
+func NewDockerhubDiffCmd() *cobra.Command {
+	options := DiffOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "diff",
+		Short: "compare organization repositories with manifest file",
+		Long: "compare visibility, descriptions, team and collaborator permissions of organization repositories with manifest file, " +
+			"and exit with error when some of them are missing or drifted, e.g. in nightly job",
+		Example: "dha diff --file=repos.yaml [--output=json|yaml|csv|tsv|markdown|table]",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			printer, err := options.output.printer(cmd)
+			if err != nil {
+				return err
+			}
+			return diffRepositories(cmd.Context(), cmd.InheritedFlags(), printer, &options)
+		},
+	}
+
+	cmd.Flags().StringVarP(&options.file, "file", "f", "", "path to YAML or JSON manifest file with desired state of repositories")
+	addOutputFlags(cmd, &options.output)
+	if err := cmd.MarkFlagRequired("file"); err != nil {
+		// Flag marking should not fail in normal operation
+		return nil
+	}
+
+	return cmd
+}
+
+// diffRepositories prints repositories differing from manifest, returning ErrDrift when some manifest repositories
+// are missing or drifted, repositories missing in manifest are reported only
+func diffRepositories(ctx context.Context, flags *pflag.FlagSet, printer *output.Printer, options *DiffOptions) error {
+	client, manifest, err := newManifestClient(flags, options.file)
+	if err != nil {
+		return err
+	}
+	workers, err := newPool(flags)
+	if err != nil {
+		return err
+	}
+
+	syncs, unmanaged, err := planSync(ctx, client, workers, manifest, false)
+	if err != nil {
+		return err
+	}
+
+	diffs := []*dockerhub.RepositoryDiff{}
+	for _, sync := range syncs {
+		if sync.diff.Action != dockerhub.ActionNone {
+			diffs = append(diffs, sync.diff)
+		}
+	}
+	drifted := len(diffs)
+	for _, name := range unmanaged {
+		diffs = append(diffs, &dockerhub.RepositoryDiff{Repository: name, Action: dockerhub.ActionUnmanaged, Changes: []*dockerhub.Change{}})
+	}
+
+	if err := printDiffs(printer, client.ORG, diffs, len(syncs)); err != nil {
+		return err
+	}
+	if drifted > 0 {
+		return fmt.Errorf("%d of %d repositories: %w", drifted, len(syncs), ErrDrift)
+	}
+
+	return nil
+}
+
+// printDiffs prints repository diffs: JSON, YAML, template and JSONPath get diffs, CSV, TSV and Markdown get row per change,
+// and table gets changes grouped by repository
+func printDiffs(printer *output.Printer, org string, diffs []*dockerhub.RepositoryDiff, managed int) error {
+	switch printer.Format {
+	case output.JSON, output.YAML, output.Template, output.JSONPath:
+		return output.PrintList(printer, diffs, nil)
+	case output.CSV, output.TSV, output.Markdown:
+		rows := []*driftChange{}
+		for _, diff := range diffs {
+			if len(diff.Changes) == 0 {
+				rows = append(rows, &driftChange{Repository: diff.Repository, Action: diff.Action, Change: &dockerhub.Change{}})
+			}
+			for _, change := range diff.Changes {
+				rows = append(rows, &driftChange{Repository: diff.Repository, Action: diff.Action, Change: change})
+			}
+		}
+		return output.PrintList(printer, rows, driftColumns)
+	}
+
+	var missing, drifted, unmanaged int
+	for _, diff := range diffs {
+		switch diff.Action {
+		case dockerhub.ActionCreate:
+			missing++
+			fmt.Fprintln(printer.Out, color.RedString("+ %s/%s doesn't exist", org, diff.Repository))
+		case dockerhub.ActionUpdate:
+			drifted++
+			fmt.Fprintln(printer.Out, color.YellowString("~ %s/%s drifted (live -> manifest)", org, diff.Repository))
+		case dockerhub.ActionUnmanaged:
+			unmanaged++
+			fmt.Fprintln(printer.Out, color.CyanString("? %s/%s isn't in manifest", org, diff.Repository))
+		}
+		printChanges(printer.Out, diff.Changes)
+	}
+
+	if missing+drifted == 0 {
+		fmt.Fprintln(printer.Out, color.GreenString("All %d repositories match manifest, %d unmanaged", managed, unmanaged))
+		return nil
+	}
+	fmt.Fprintf(printer.Out, "Drift: %d missing, %d drifted of %d repositories, %d unmanaged\n", missing, drifted, managed, unmanaged)
+
+	return nil
+}
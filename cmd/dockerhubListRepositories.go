@@ -17,29 +17,71 @@ limitations under the License.
 package cmd
 
 import (
+	"cmp"
+	"context"
 	"fmt"
-	"runtime"
+	"io"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
-	"github.com/fatih/color"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 
 	"github.com/ealebed/dha/pkg/dockerhub"
+	"github.com/ealebed/dha/pkg/output"
+	"github.com/ealebed/dha/pkg/pool"
 )
 
+// Repository sort orders of list command
+const (
+	sortByName    = "name"
+	sortByPulls   = "pulls"
+	sortBySize    = "size"
+	sortByTags    = "tags"
+	sortByUpdated = "updated"
+)
+
+// repositorySortOrders lists values accepted by list command '--sort' flag
+var repositorySortOrders = []string{sortByName, sortByPulls, sortBySize, sortByTags, sortByUpdated}
+
 // listRepoOptions represents options for list command
 type listRepoOptions struct {
-	expand bool
+	expand  bool
+	sort    string
+	reverse bool
+	filters []string
+	limit   int
+	output  OutputOptions
 }
 
-// listResult represents data for use in channel from goroutines
-type listResult struct {
-	repoName      string
-	repoPullCount int
-	avgSize       float64
-	tagsCount     int
-	lastUpdated   string
+// repositoryFilter represents criteria of list command '--filter' flags, all of which repository should match
+type repositoryFilter struct {
+	name            *regexp.Regexp
+	private         *bool
+	minTags         int
+	notUpdatedSince time.Duration
+}
+
+// repositorySummary represents docker repository with statistics of its tags, printed by list command
+type repositorySummary struct {
+	*dockerhub.Repository
+	TagsCount int     `json:"tags_count"`
+	AvgSize   float64 `json:"avg_size_mb"`
+}
+
+// repositoryColumns represents columns of list command table, CSV and TSV output
+var repositoryColumns = []output.Column[*repositorySummary]{
+	{Header: "namespace", Value: func(r *repositorySummary) string { return r.Namespace }},
+	{Header: "name", Value: func(r *repositorySummary) string { return r.Name }},
+	{Header: "tags_count", Value: func(r *repositorySummary) string { return strconv.Itoa(r.TagsCount) }},
+	{Header: "pull_count", Wide: true, Value: func(r *repositorySummary) string { return strconv.Itoa(r.PullCount) }},
+	{Header: "avg_size_mb", Wide: true, Value: func(r *repositorySummary) string { return fmt.Sprintf("%.2f", r.AvgSize) }},
+	{Header: "star_count", Wide: true, Value: func(r *repositorySummary) string { return strconv.Itoa(r.StarCount) }},
+	{Header: "is_private", Wide: true, Value: func(r *repositorySummary) string { return strconv.FormatBool(r.IsPrivate) }},
+	{Header: "last_updated", Wide: true, Value: func(r *repositorySummary) string { return formatTime(r.LastUpdated) }},
 }
 
 // NewDockerhubListRepositoriesCmd returns new docker repositories list command
@@ -51,111 +93,202 @@ func NewDockerhubListRepositoriesCmd() *cobra.Command {
 		Aliases: []string{"ls"},
 		Short:   "returns list all dockerhub repositories",
 		Long:    "returns list all dockerhub organization repositories",
-		Example: "dha list",
-		Run: func(cmd *cobra.Command, args []string) {
-			listDockerhubRepos(cmd.InheritedFlags(), options)
+		Example: "dha list [--expand] [--sort=name|pulls|size|tags|updated] [--reverse] [--filter=name=...] [--filter=visibility=private|public] " +
+			"[--filter=min-tags=...] [--filter=not-updated-since=...] [--limit=...] [--output=json|yaml|csv|tsv|table|wide] [--template='{{.Name}}']",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			printer, err := options.output.printer(cmd)
+			if err != nil {
+				return err
+			}
+			return listDockerhubRepos(cmd.Context(), cmd.InheritedFlags(), printer, options)
 		},
 	}
 
 	// Note that false here means defaults to false, and flips to true if the flag is present.
 	cmd.PersistentFlags().BoolVarP(&options.expand, "expand", "x", false, "expand docker repositories list payload to include size and pull count")
+	cmd.Flags().StringVar(&options.sort, "sort", sortByName,
+		"sort repositories by: "+strings.Join(repositorySortOrders, ", ")+" (name ascending, others descending, ties by name)")
+	cmd.Flags().BoolVar(&options.reverse, "reverse", false, "reverse sort order")
+	cmd.Flags().StringArrayVar(&options.filters, "filter", nil, "list only repositories matching filter, repeat for several ones: "+
+		"name=REGEX, visibility=private|public, min-tags=N, not-updated-since=AGE (e.g. 90d)")
+	cmd.Flags().IntVar(&options.limit, "limit", 0, "list at most N repositories, after sorting (0 lists all)")
+	addOutputFlags(cmd, &options.output)
 
 	return cmd
 }
 
 // listDockerhubRepos returns list of all Dockerhub repositories
-func listDockerhubRepos(flags *pflag.FlagSet, options *listRepoOptions) {
-	var ret = []listResult{}
+func listDockerhubRepos(ctx context.Context, flags *pflag.FlagSet, printer *output.Printer, options *listRepoOptions) error {
+	if !slices.Contains(repositorySortOrders, options.sort) {
+		return fmt.Errorf("unsupported '--sort' %q, should be one of: %s", options.sort, strings.Join(repositorySortOrders, ", "))
+	}
+	if options.limit < 0 {
+		return fmt.Errorf("'--limit' should not be negative")
+	}
+	filter, err := parseRepositoryFilter(options.filters)
+	if err != nil {
+		return err
+	}
 
-	runtime.GOMAXPROCS(runtime.NumCPU())
-	availableRoutines := runtime.NumCPU()
-	routineReady := make(chan bool)
-	chanRes := make(chan listResult)
+	client, err := newClient(flags)
+	if err != nil {
+		return err
+	}
 
-	org, _, err := dockerhub.GetFlags(flags)
+	workers, err := newPool(flags)
 	if err != nil {
-		color.Red("Error: %s", err)
+		return err
 	}
 
-	repositories, err := dockerhub.NewClient(org, "").ListRepositories()
+	repositories, err := client.ListRepositoriesContext(ctx)
 	if err != nil {
-		color.Red("Error: %s", err)
+		return fmt.Errorf("failed to list repositories: %w", err)
 	}
 
-	go func() {
-		for {
-			r, opened := <-chanRes
-			if !opened {
-				break
-			}
-			ret = append(ret, r)
+	// filter by repository fields before listing tags, so skipped repositories cost no requests
+	now := time.Now()
+	repositories = slices.DeleteFunc(repositories, func(repo *dockerhub.Repository) bool {
+		return !filter.matchRepository(repo, now)
+	})
+
+	results := pool.Map(ctx, workers, repositories, func(ctx context.Context, _ int, repo *dockerhub.Repository) (*repositorySummary, error) {
+		return lister(ctx, client, repo)
+	})
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	ret := slices.DeleteFunc(results.Values, func(r *repositorySummary) bool {
+		return r == nil || r.TagsCount < filter.minTags
+	})
+
+	sortRepositories(ret, options.sort, options.reverse)
+	if options.limit > 0 && len(ret) > options.limit {
+		ret = ret[:options.limit]
+	}
+
+	if !printer.IsTable() {
+		if err := output.PrintList(printer, ret, repositoryColumns); err != nil {
+			return err
 		}
-	}()
+	} else {
+		printRepositoriesTable(printer.Out, ret, options.expand || printer.IsWide())
+	}
 
-	limiter := time.Tick(300 * time.Millisecond)
+	if failed := results.Failed(); failed > 0 {
+		return fmt.Errorf("failed to list tags of %d of %d repositories:\n%w", failed, len(repositories), results.Err())
+	}
+
+	return nil
+}
 
-	for _, repo := range repositories {
-		<-limiter
-		if availableRoutines == 0 {
-			<-routineReady
-			availableRoutines++
+// parseRepositoryFilter parses '--filter' flags in "key=value" format
+func parseRepositoryFilter(filters []string) (*repositoryFilter, error) {
+	filter := &repositoryFilter{}
+	for _, f := range filters {
+		key, value, ok := strings.Cut(f, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid '--filter' %q, should be key=value", f)
 		}
-		availableRoutines--
 
-		go lister(org, repo, chanRes, routineReady)
+		var err error
+		switch key {
+		case "name":
+			filter.name, err = regexp.Compile(value)
+		case "visibility":
+			if value != "private" && value != "public" {
+				err = fmt.Errorf("should be private or public")
+			}
+			private := value == "private"
+			filter.private = &private
+		case "min-tags":
+			filter.minTags, err = strconv.Atoi(value)
+		case "not-updated-since":
+			filter.notUpdatedSince, err = dockerhub.ParseDuration(value)
+		default:
+			err = fmt.Errorf("unknown key, should be one of: name, visibility, min-tags, not-updated-since")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid '--filter' %q: %w", f, err)
+		}
+	}
+
+	return filter, nil
+}
+
+// matchRepository reports whether repository matches filter criteria, which don't depend on its tags
+func (f *repositoryFilter) matchRepository(repo *dockerhub.Repository, now time.Time) bool {
+	if f.name != nil && !f.name.MatchString(repo.Name) {
+		return false
+	}
+	if f.private != nil && repo.IsPrivate != *f.private {
+		return false
 	}
 
-	for availableRoutines < runtime.NumCPU() {
-		<-routineReady
-		availableRoutines++
+	return f.notUpdatedSince == 0 || repo.LastUpdated.Before(now.Add(-f.notUpdatedSince))
+}
+
+// sortRepositories sorts repositories by name ascending, or by other field descending, ties are ordered by name
+func sortRepositories(repositories []*repositorySummary, by string, reverse bool) {
+	compare := func(a, b *repositorySummary) int {
+		var c int
+		switch by {
+		case sortByPulls:
+			c = cmp.Compare(b.PullCount, a.PullCount)
+		case sortBySize:
+			c = cmp.Compare(b.AvgSize, a.AvgSize)
+		case sortByTags:
+			c = cmp.Compare(b.TagsCount, a.TagsCount)
+		case sortByUpdated:
+			c = b.LastUpdated.Compare(a.LastUpdated)
+		}
+		if c == 0 {
+			c = cmp.Compare(a.Name, b.Name)
+		}
+		if reverse {
+			return -c
+		}
+		return c
 	}
 
-	close(chanRes)
+	slices.SortStableFunc(repositories, compare)
+}
 
-	if options.expand {
-		fmt.Printf("| Image Num   | %-44s | %-7s | %-7s | %-7s | %s\n", "Name", "Pulls Count", "AvgSize (MB)", "Tags Count", "Last Updated")
+// printRepositoriesTable prints repositories as table with their tags count, and with pull count, size and update time when expanded
+func printRepositoriesTable(out io.Writer, repositories []*repositorySummary, expand bool) {
+	if expand {
+		fmt.Fprintf(out, "| Image Num   | %-44s | %-7s | %-7s | %-7s | %s\n", "Name", "Pulls Count", "AvgSize (MB)", "Tags Count", "Last Updated")
 	}
-	if !options.expand {
-		fmt.Printf("| Image Num   | %-55s | %s\n", "Name", "Tags Count")
+	if !expand {
+		fmt.Fprintf(out, "| Image Num   | %-55s | %s\n", "Name", "Tags Count")
 	}
 
-	for repoCount, info := range ret {
-		if options.expand {
-			repoName := dockerhub.BW(info.repoName)
+	for repoCount, info := range repositories {
+		if expand {
+			repoName := dockerhub.BW(info.Name)
 			format := "| Image %-5d | %-55s | %-11d | %-12.2f | %-21s | %s\n"
-			if info.tagsCount == 0 {
-				fmt.Printf(format, repoCount+1, repoName, info.repoPullCount, info.avgSize, dockerhub.BR(info.tagsCount), info.lastUpdated)
-			} else if info.tagsCount >= 50 {
-				fmt.Printf(format, repoCount+1, repoName, info.repoPullCount, info.avgSize, dockerhub.BY(info.tagsCount), info.lastUpdated)
+			lastUpdated := info.LastUpdated.String()
+			if info.TagsCount == 0 {
+				fmt.Fprintf(out, format, repoCount+1, repoName, info.PullCount, info.AvgSize, dockerhub.BR(info.TagsCount), lastUpdated)
+			} else if info.TagsCount >= 50 {
+				fmt.Fprintf(out, format, repoCount+1, repoName, info.PullCount, info.AvgSize, dockerhub.BY(info.TagsCount), lastUpdated)
 			} else {
-				fmt.Printf(format, repoCount+1, repoName, info.repoPullCount, info.avgSize, dockerhub.BW(info.tagsCount), info.lastUpdated)
+				fmt.Fprintf(out, format, repoCount+1, repoName, info.PullCount, info.AvgSize, dockerhub.BW(info.TagsCount), lastUpdated)
 			}
 		}
-		if !options.expand {
-			fmt.Printf("| Image %-5d | %-55s | %d\n", repoCount+1, info.repoName, info.tagsCount)
+		if !expand {
+			fmt.Fprintf(out, "| Image %-5d | %-55s | %d\n", repoCount+1, info.Name, info.TagsCount)
 		}
 	}
 }
 
-func lister(org string, repo *dockerhub.Repository, chanRes chan listResult, routineReady chan bool) {
-	r := listResult{}
-
-	tagsCount, err := dockerhub.NewClient(org, "").GetTagsCount(repo.Name)
+// lister returns docker repository with count and average size of its tags, listed with single crawl of repository tags
+func lister(ctx context.Context, client *dockerhub.Client, repo *dockerhub.Repository) (*repositorySummary, error) {
+	tags, err := client.ListTagsContext(ctx, repo.Name)
 	if err != nil {
-		color.Red("Error: %s", err)
+		return nil, fmt.Errorf("%s: %w", repo.Name, err)
 	}
+	report := dockerhub.NewRepositoryReport(repo, tags)
 
-	avgSize, err := dockerhub.NewClient(org, "").GetAvgTagsSize(repo.Name)
-	if err != nil {
-		color.Red("Error: %s", err)
-	}
-
-	r.repoName = repo.Name
-	r.repoPullCount = repo.PullCount
-	r.avgSize = avgSize
-	r.tagsCount = tagsCount
-	r.lastUpdated = repo.LastUpdated.String()
-
-	routineReady <- true
-	chanRes <- r
+	return &repositorySummary{Repository: repo, TagsCount: report.Tags, AvgSize: report.AvgSizeMB()}, nil
 }
@@ -17,18 +17,57 @@ limitations under the License.
 package cmd
 
 import (
+	"cmp"
+	"context"
 	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
-	"github.com/fatih/color"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 
 	"github.com/ealebed/dha/pkg/dockerhub"
+	"github.com/ealebed/dha/pkg/output"
 )
 
+// Tag sort orders of get command
+const (
+	sortByPushed = "pushed"
+	sortByPulled = "pulled"
+	sortBySemver = "semver"
+)
+
+// tagSortOrders lists values accepted by get command '--sort' flag
+var tagSortOrders = []string{sortByPushed, sortByPulled, sortByName, sortBySize, sortBySemver}
+
 // ListTagsOptions represents options for list tags command
 type ListTagsOptions struct {
-	imageName string
+	imageName    string
+	sortBy       string
+	versions     string
+	match        string
+	status       string
+	pushedBefore string
+	pushedAfter  string
+	pulledBefore string
+	minSize      string
+	limit        int
+	platforms    bool
+	output       OutputOptions
+}
+
+// tagColumns represents columns of get command table, CSV and TSV output
+var tagColumns = []output.Column[*dockerhub.Tag]{
+	{Header: "name", Value: func(t *dockerhub.Tag) string { return t.Name }},
+	{Header: "status", Value: func(t *dockerhub.Tag) string { return t.TagStatus }},
+	{Header: "last_updated", Value: func(t *dockerhub.Tag) string { return formatTime(t.LastUpdated) }},
+	{Header: "digest", Wide: true, Value: func(t *dockerhub.Tag) string { return dockerhub.TagDigest(t) }},
+	{Header: "size", Wide: true, Value: func(t *dockerhub.Tag) string { return strconv.Itoa(t.FullSize) }},
+	{Header: "last_pushed", Wide: true, Value: func(t *dockerhub.Tag) string { return formatTime(t.TagLastPushed) }},
+	{Header: "last_pulled", Wide: true, Value: func(t *dockerhub.Tag) string { return formatTime(t.TagLastPulled) }},
+	{Header: "platforms", Wide: true, Value: tagPlatforms},
 }
 
 // NewDockerhubListTagsCmd returns new docker list tags command
@@ -36,16 +75,36 @@ func NewDockerhubListTagsCmd() *cobra.Command {
 	options := ListTagsOptions{}
 
 	cmd := &cobra.Command{
-		Use:     "get",
-		Short:   "returns list tags from the provided dockerhub repository (image)",
-		Long:    "returns list tags from the provided dockerhub repository (image)",
-		Example: "dha get [--image=...]",
+		Use:   "get",
+		Short: "returns list tags from the provided dockerhub repository (image)",
+		Long:  "returns list tags from the provided dockerhub repository (image)",
+		Example: "dha get [--image=...] [--match=...] [--status=active|inactive] [--pushed-before=...] [--pushed-after=...] [--pulled-before=...] " +
+			"[--min-size=...] [--versions=...] [--sort=pushed|pulled|name|size|semver] [--limit=...] [--platforms] " +
+			"[--output=json|yaml|csv|tsv|table|wide|jsonpath=...]",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return listImageTags(cmd.InheritedFlags(), options.imageName)
+			printer, err := options.output.printer(cmd)
+			if err != nil {
+				return err
+			}
+			return listImageTags(cmd.Context(), cmd.InheritedFlags(), printer, &options)
 		},
 	}
 
 	cmd.Flags().StringVarP(&options.imageName, "image", "i", "", "docker image name for getting tags")
+	cmd.Flags().StringVar(&options.sortBy, "sort", "", "sort tags by: "+strings.Join(tagSortOrders, ", ")+
+		" (name ascending, others newest or largest first, 'semver' puts other tags last)")
+	cmd.Flags().StringVar(&options.versions, "versions", "", "show only semantic version tags within range (e.g. '>=1.0.0 <2.0.0')")
+	cmd.Flags().StringVar(&options.match, "match", "", "show only tags matching regular expression (case insensitive, as truncate '--tagRegEx')")
+	cmd.Flags().StringVar(&options.status, "status", "", "show only tags with status: active, inactive")
+	cmd.Flags().StringVar(&options.pushedBefore, "pushed-before", "",
+		"show only tags pushed earlier than provided age ago (e.g. 30d, as truncate '--older-than') or date (e.g. 2024-01-31)")
+	cmd.Flags().StringVar(&options.pushedAfter, "pushed-after", "", "show only tags pushed within provided age (e.g. 30d) or after date (e.g. 2024-01-31)")
+	cmd.Flags().StringVar(&options.pulledBefore, "pulled-before", "",
+		"show only tags not pulled within provided age (e.g. 30d, as truncate '--pulled-before') or since date, including never pulled ones")
+	cmd.Flags().StringVar(&options.minSize, "min-size", "", "show only tags with size at least that (e.g. 500MB, 1GB or bytes)")
+	cmd.Flags().IntVar(&options.limit, "limit", 0, "show at most N tags, after sorting (0 shows all)")
+	cmd.Flags().BoolVar(&options.platforms, "platforms", false, "show digest, size, OS version and pull/push times of every tag platform")
+	addOutputFlags(cmd, &options.output)
 	if err := cmd.MarkFlagRequired("image"); err != nil {
 		// Flag marking should not fail in normal operation
 		return nil
@@ -55,20 +114,151 @@ func NewDockerhubListTagsCmd() *cobra.Command {
 }
 
 // listImageTags returns list tags from the provided dockerhub repository (image)
-func listImageTags(flags *pflag.FlagSet, image string) error {
-	org, _, err := dockerhub.GetFlags(flags)
+func listImageTags(ctx context.Context, flags *pflag.FlagSet, printer *output.Printer, options *ListTagsOptions) error {
+	client, err := newClient(flags)
+	if err != nil {
+		return err
+	}
+
+	if options.sortBy != "" && !slices.Contains(tagSortOrders, options.sortBy) {
+		return fmt.Errorf("unsupported sort %q, should be one of: %s", options.sortBy, strings.Join(tagSortOrders, ", "))
+	}
+	if options.limit < 0 {
+		return fmt.Errorf("'--limit' should not be negative")
+	}
+
+	now := time.Now()
+	selector, err := options.selector(now)
 	if err != nil {
-		color.Red("Error: %s", err)
+		return err
 	}
 
-	tags, err := dockerhub.NewClient(org, "").ListTags(image)
+	tags, err := client.ListTagsContext(ctx, options.imageName)
 	if err != nil {
-		color.Red("Error: %s", err)
+		return fmt.Errorf("failed to list tags: %w", err)
+	}
+
+	if tags, err = selector.Select(tags, now); err != nil {
+		return err
+	}
+	sortTags(tags, options.sortBy)
+	if options.limit > 0 && len(tags) > options.limit {
+		tags = tags[:options.limit]
+	}
+
+	if options.platforms {
+		return output.PrintList(printer, tagPlatformRows(tags), platformColumns)
+	}
+	if !printer.IsTable() {
+		return output.PrintList(printer, tags, tagColumns)
 	}
 
 	for count, tag := range tags {
-		fmt.Printf("| Tag %-3d | %-60s | %-10s | %s\n", count+1, dockerhub.BW(tag.Name), tag.TagStatus, tag.LastUpdated)
+		if printer.IsWide() {
+			fmt.Fprintf(printer.Out, "| Tag %-3d | %-60s | %-10s | %-29s | %-19s | %-10s | %s\n", count+1, dockerhub.BW(tag.Name), tag.TagStatus,
+				tag.LastUpdated, shortDigest(dockerhub.TagDigest(tag)), formatSize(tag.FullSize), tagPlatforms(tag))
+			continue
+		}
+		fmt.Fprintf(printer.Out, "| Tag %-3d | %-60s | %-10s | %s\n", count+1, dockerhub.BW(tag.Name), tag.TagStatus, tag.LastUpdated)
 	}
 
 	return nil
 }
+
+// selector returns tag selector for tag filtering flags, matching tags by the same criteria as truncate flags,
+// though unlike truncate get neither keeps latest tags nor skips protected and shared digest ones
+func (o *ListTagsOptions) selector(now time.Time) (*dockerhub.TagSelector, error) {
+	selector := &dockerhub.TagSelector{TagRegex: o.match, Versions: o.versions}
+
+	switch o.status {
+	case "":
+	case "active":
+		selector.Active = true
+	case "inactive":
+		selector.Inactive = true
+	default:
+		return nil, fmt.Errorf("unsupported '--status' %q, should be active or inactive", o.status)
+	}
+
+	if o.versions != "" {
+		if _, err := dockerhub.ParseVersionRange(o.versions); err != nil {
+			return nil, fmt.Errorf("invalid '--versions': %w", err)
+		}
+	}
+
+	var err error
+	if selector.OlderThan, err = parseAge(o.pushedBefore, now); err != nil {
+		return nil, fmt.Errorf("invalid '--pushed-before': %w", err)
+	}
+	if selector.NewerThan, err = parseAge(o.pushedAfter, now); err != nil {
+		return nil, fmt.Errorf("invalid '--pushed-after': %w", err)
+	}
+	if selector.PulledBefore, err = parseAge(o.pulledBefore, now); err != nil {
+		return nil, fmt.Errorf("invalid '--pulled-before': %w", err)
+	}
+	if selector.MinSize, err = parseSize(o.minSize); err != nil {
+		return nil, fmt.Errorf("invalid '--min-size': %w", err)
+	}
+
+	return selector, nil
+}
+
+// parseAge parses age as duration (e.g. "30d") or as date ("2006-01-02" or RFC 3339), which is converted to age at provided time
+func parseAge(s string, now time.Time) (time.Duration, error) {
+	duration, err := dockerhub.ParseDuration(s)
+	if err == nil {
+		return duration, nil
+	}
+
+	for _, layout := range []string{time.DateOnly, time.RFC3339} {
+		if date, dateErr := time.Parse(layout, s); dateErr == nil {
+			if !date.Before(now) {
+				return 0, fmt.Errorf("date %q isn't in the past", s)
+			}
+			return now.Sub(date), nil
+		}
+	}
+
+	return 0, err
+}
+
+// parseSize parses size in bytes, optionally with KB, MB or GB unit (powers of 1024, as sizes are printed)
+func parseSize(s string) (int, error) {
+	number := strings.ToUpper(strings.TrimSpace(s))
+	if number == "" {
+		return 0, nil
+	}
+
+	multiplier := 1
+	for unit, m := range map[string]int{"KB": 1 << 10, "MB": 1 << 20, "GB": 1 << 30} {
+		if n, ok := strings.CutSuffix(number, unit); ok {
+			number, multiplier = strings.TrimSpace(n), m
+			break
+		}
+	}
+	number = strings.TrimSuffix(number, "B")
+
+	size, err := strconv.ParseFloat(number, 64)
+	if err != nil || size < 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+
+	return int(size * float64(multiplier)), nil
+}
+
+// sortTags sorts docker image tags by name ascending, by push or pull time or size descending, or by semantic version,
+// ties keep docker hub order
+func sortTags(tags []*dockerhub.Tag, by string) {
+	switch by {
+	case sortBySemver:
+		dockerhub.SortTagsByVersion(tags)
+	case sortByName:
+		slices.SortStableFunc(tags, func(a, b *dockerhub.Tag) int { return cmp.Compare(a.Name, b.Name) })
+	case sortBySize:
+		slices.SortStableFunc(tags, func(a, b *dockerhub.Tag) int { return cmp.Compare(b.FullSize, a.FullSize) })
+	case sortByPushed:
+		slices.SortStableFunc(tags, func(a, b *dockerhub.Tag) int { return dockerhub.TagPushedAt(b).Compare(dockerhub.TagPushedAt(a)) })
+	case sortByPulled:
+		slices.SortStableFunc(tags, func(a, b *dockerhub.Tag) int { return b.TagLastPulled.Compare(a.TagLastPulled) })
+	}
+}
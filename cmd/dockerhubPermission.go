@@ -0,0 +1,354 @@
+/*
+Copyright © 2020 Yevhen Lebid ealebed@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/ealebed/dha/pkg/dockerhub"
+	"github.com/ealebed/dha/pkg/output"
+	"github.com/ealebed/dha/pkg/pool"
+)
+
+// Kinds of repository permission holders
+const (
+	holderTeam = "team"
+	holderUser = "user"
+)
+
+// PermissionOptions represents options for permission commands
+type PermissionOptions struct {
+	imageName  string
+	allImages  bool
+	teamName   string
+	userName   string
+	permission string
+	output     OutputOptions
+}
+
+// repositoryPermission represents permission of team or individual collaborator on docker repository
+type repositoryPermission struct {
+	Repository string `json:"repository"`
+	Holder     string `json:"holder"`
+	Name       string `json:"name"`
+	Permission string `json:"permission"`
+}
+
+// permissionColumns represents columns of permission list table, CSV and TSV output
+var permissionColumns = []output.Column[*repositoryPermission]{
+	{Header: "repository", Value: func(p *repositoryPermission) string { return p.Repository }},
+	{Header: "holder", Value: func(p *repositoryPermission) string { return p.Holder }},
+	{Header: "name", Value: func(p *repositoryPermission) string { return p.Name }},
+	{Header: "permission", Value: func(p *repositoryPermission) string { return p.Permission }},
+}
+
+// NewDockerhubPermissionCmd returns new permission command
+func NewDockerhubPermissionCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "permission",
+		Aliases: []string{"perm"},
+		Short:   "manage team and collaborator permissions on docker image repositories",
+		Long:    "list, grant and revoke permissions (read, write or admin) of organization teams and individual collaborators on docker image repositories",
+		Example: "dha permission list --image=...\n" +
+			"dha permission grant --image=... --team=... --permission=write\n" +
+			"dha permission revoke --all --user=...",
+	}
+
+	cmd.AddCommand(newPermissionListCmd())
+	cmd.AddCommand(newPermissionGrantCmd())
+	cmd.AddCommand(newPermissionRevokeCmd())
+
+	return cmd
+}
+
+// addPermissionFlags adds repositories and permission holder flags to permission command,
+// holder is required unless command lists permissions
+func addPermissionFlags(cmd *cobra.Command, options *PermissionOptions, holderRequired bool) {
+	cmd.Flags().StringVarP(&options.imageName, "image", "i", "", "docker image name")
+	cmd.Flags().BoolVar(&options.allImages, "all", false, "all organization repositories")
+	cmd.Flags().StringVar(&options.teamName, "team", "", "organization team name")
+	cmd.Flags().StringVar(&options.userName, "user", "", "individual collaborator user name")
+	cmd.MarkFlagsOneRequired("image", "all")
+	cmd.MarkFlagsMutuallyExclusive("image", "all")
+	cmd.MarkFlagsMutuallyExclusive("team", "user")
+	if holderRequired {
+		cmd.MarkFlagsOneRequired("team", "user")
+	}
+}
+
+// newPermissionListCmd returns new permission list command
+func newPermissionListCmd() *cobra.Command {
+	options := PermissionOptions{}
+
+	cmd := &cobra.Command{
+		Use:     "list",
+		Aliases: []string{"ls"},
+		Short:   "returns team and collaborator permissions on docker image repositories",
+		Long: "returns permissions of teams and individual collaborators on the specified or all organization repositories, " +
+			"optionally only of the specified team or user (e.g. to find repositories of leaving engineer)",
+		Example: "dha permission list (--image=...|--all) [--team=...|--user=...] [--output=json|yaml|csv|tsv|markdown|table]",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			printer, err := options.output.printer(cmd)
+			if err != nil {
+				return err
+			}
+			return listPermissions(cmd.Context(), cmd.InheritedFlags(), printer, &options)
+		},
+	}
+
+	addPermissionFlags(cmd, &options, false)
+	addOutputFlags(cmd, &options.output)
+
+	return cmd
+}
+
+// newPermissionGrantCmd returns new permission grant command
+func newPermissionGrantCmd() *cobra.Command {
+	options := PermissionOptions{}
+
+	cmd := &cobra.Command{
+		Use:     "grant",
+		Short:   "grant team or collaborator permission on docker image repositories",
+		Long:    "grant permission to team or individual collaborator on the specified or all organization repositories, replacing permission they already have",
+		Example: "dha permission grant (--image=...|--all) (--team=...|--user=...) --permission=read|write|admin [--dry-run=false]",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := dockerhub.ValidatePermission(options.permission); err != nil {
+				return err
+			}
+			return changePermissions(cmd.Context(), cmd.InheritedFlags(), &options)
+		},
+	}
+
+	addPermissionFlags(cmd, &options, true)
+	cmd.Flags().StringVar(&options.permission, "permission", "", "permission to grant: "+strings.Join(dockerhub.Permissions, ", "))
+	if err := cmd.MarkFlagRequired("permission"); err != nil {
+		// Flag marking should not fail in normal operation
+		return nil
+	}
+
+	return cmd
+}
+
+// newPermissionRevokeCmd returns new permission revoke command
+func newPermissionRevokeCmd() *cobra.Command {
+	options := PermissionOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "revoke",
+		Short: "revoke team or collaborator permission on docker image repositories",
+		Long: "revoke permission of team or individual collaborator on the specified or all organization repositories, " +
+			"e.g. to offboard engineer from every repository at once",
+		Example: "dha permission revoke (--image=...|--all) (--team=...|--user=...) [--dry-run=false]",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return changePermissions(cmd.Context(), cmd.InheritedFlags(), &options)
+		},
+	}
+
+	addPermissionFlags(cmd, &options, true)
+
+	return cmd
+}
+
+// repositories returns the specified repository, or all organization repositories
+func (o *PermissionOptions) repositories(ctx context.Context, client *dockerhub.Client) ([]string, error) {
+	if !o.allImages {
+		return []string{o.imageName}, nil
+	}
+
+	repos, err := client.ListRepositoriesContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list repositories: %w", err)
+	}
+	repositories := []string{}
+	for _, repo := range repos {
+		repositories = append(repositories, repo.Name)
+	}
+
+	return repositories, nil
+}
+
+// holder returns permission holder kind and name
+func (o *PermissionOptions) holder() (string, string) {
+	if o.teamName != "" {
+		return holderTeam, o.teamName
+	}
+
+	return holderUser, o.userName
+}
+
+// repositoryPermissions returns permissions of teams and collaborators on docker repository,
+// only of the specified team or user when provided
+func (o *PermissionOptions) repositoryPermissions(ctx context.Context, client *dockerhub.Client, repo string) ([]*repositoryPermission, error) {
+	permissions := []*repositoryPermission{}
+	if o.userName == "" {
+		teams, err := client.ListRepositoryTeamsContext(ctx, repo)
+		if err != nil {
+			return nil, fmt.Errorf("%s: failed to list team permissions: %w", repo, err)
+		}
+		for _, team := range teams {
+			if o.teamName == "" || team.TeamName == o.teamName {
+				permissions = append(permissions, &repositoryPermission{Repository: repo, Holder: holderTeam, Name: team.TeamName, Permission: team.Permission})
+			}
+		}
+	}
+	if o.teamName == "" {
+		collaborators, err := client.ListCollaboratorsContext(ctx, repo)
+		if err != nil {
+			return nil, fmt.Errorf("%s: failed to list collaborators: %w", repo, err)
+		}
+		for _, collaborator := range collaborators {
+			if o.userName == "" || collaborator.User == o.userName {
+				permissions = append(permissions, &repositoryPermission{Repository: repo, Holder: holderUser, Name: collaborator.User, Permission: collaborator.Permission})
+			}
+		}
+	}
+
+	return permissions, nil
+}
+
+// listPermissions prints permissions of teams and collaborators on repositories
+func listPermissions(ctx context.Context, flags *pflag.FlagSet, printer *output.Printer, options *PermissionOptions) error {
+	client, err := newClient(flags)
+	if err != nil {
+		return err
+	}
+	workers, err := newPool(flags)
+	if err != nil {
+		return err
+	}
+
+	repositories, err := options.repositories(ctx, client)
+	if err != nil {
+		return err
+	}
+
+	results := pool.Map(ctx, workers, repositories, func(ctx context.Context, _ int, repo string) ([]*repositoryPermission, error) {
+		return options.repositoryPermissions(ctx, client, repo)
+	})
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	permissions := []*repositoryPermission{}
+	for _, values := range results.Values {
+		permissions = append(permissions, values...)
+	}
+	if err := output.PrintList(printer, permissions, permissionColumns); err != nil {
+		return err
+	}
+
+	if failed := results.Failed(); failed > 0 {
+		return fmt.Errorf("failed to list permissions of %d of %d repositories:\n%w", failed, len(repositories), results.Err())
+	}
+
+	return nil
+}
+
+// changePermissions grants (or revokes, when permission isn't provided) permission of team or collaborator on repositories,
+// skipping repositories where holder already has the permission
+func changePermissions(ctx context.Context, flags *pflag.FlagSet, options *PermissionOptions) error {
+	org, dryRun, err := dockerhub.GetFlags(flags)
+	if err != nil {
+		return err
+	}
+
+	client, err := newClient(flags)
+	if err != nil {
+		return err
+	}
+	workers, err := newPool(flags)
+	if err != nil {
+		return err
+	}
+
+	var team *dockerhub.Team
+	if options.teamName != "" {
+		if team, err = client.GetTeamContext(ctx, options.teamName); err != nil {
+			return err
+		}
+	}
+
+	repositories, err := options.repositories(ctx, client)
+	if err != nil {
+		return err
+	}
+
+	holder, name := options.holder()
+	action := fmt.Sprintf("Grant %s permission to %s %s on", options.permission, holder, name)
+	if options.permission == "" {
+		action = fmt.Sprintf("Revoke permission of %s %s on", holder, name)
+	}
+
+	results := pool.Run(ctx, workers, repositories, func(ctx context.Context, _ int, repo string) error {
+		permissions, err := options.repositoryPermissions(ctx, client, repo)
+		if err != nil {
+			return err
+		}
+		current := ""
+		if len(permissions) > 0 {
+			current = permissions[0].Permission
+		}
+		if current == options.permission {
+			switch {
+			case options.allImages:
+			case current == "":
+				color.Green("%s %s has no permission on %s", holder, name, dockerhub.BW(org+"/"+repo))
+			default:
+				color.Green("%s %s already has %s permission on %s", holder, name, current, dockerhub.BW(org+"/"+repo))
+			}
+			return nil
+		}
+
+		if dryRun {
+			color.Yellow("[DRY-RUN] %s %s", action, dockerhub.BW(org+"/"+repo))
+			return nil
+		}
+
+		color.Blue("===> %s %s", dockerhub.BW(action), dockerhub.BG(org+"/"+repo))
+		switch {
+		case team != nil && options.permission == "":
+			err = client.RemoveTeamPermissionContext(ctx, repo, team)
+		case team != nil:
+			err = client.SetTeamPermissionContext(ctx, repo, team, options.permission)
+		case options.permission == "":
+			err = client.RemoveCollaboratorContext(ctx, repo, name)
+		default:
+			err = client.SetCollaboratorPermissionContext(ctx, repo, name, options.permission)
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", repo, err)
+		}
+		return nil
+	})
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if failed := results.Failed(); failed > 0 {
+		return fmt.Errorf("failed to change permissions on %d of %d repositories:\n%w", failed, len(repositories), results.Err())
+	}
+	if !dryRun {
+		color.Green("Done \u2714")
+	}
+
+	return nil
+}
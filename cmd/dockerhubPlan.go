@@ -0,0 +1,173 @@
+/*
+Copyright © 2020 Yevhen Lebid ealebed@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/ealebed/dha/pkg/dockerhub"
+	"github.com/ealebed/dha/pkg/pool"
+)
+
+// PlanOptions represents options for plan command
+type PlanOptions struct {
+	planFile string
+}
+
+// NewDockerhubPlanCmd returns new plan command
+func NewDockerhubPlanCmd() *cobra.Command {
+	options := &PlanOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "plan",
+		Short: "write plan of deletions to file for review and later apply",
+		Long:  "compute docker image tags and repositories deletions, which truncate or delete commands would perform, and write them to JSON plan file",
+		Example: "dha plan truncate [--image=...] || [--imageRegEx=...] || [--all] [--inactive] || [--tagRegEx=...] " +
+			"[--keep-last=...] [--older-than=...] [--pulled-before=...] || [--policy=...] [--out=...]\n" +
+			"dha plan delete [--image=...] [--out=...]",
+	}
+
+	cmd.PersistentFlags().StringVar(&options.planFile, "out", "dha-plan.json", "path to plan file")
+
+	cmd.AddCommand(newPlanTruncateCmd(options))
+	cmd.AddCommand(newPlanDeleteCmd(options))
+
+	return cmd
+}
+
+// newPlanTruncateCmd returns new plan truncate command
+func newPlanTruncateCmd(options *PlanOptions) *cobra.Command {
+	truncateOptions := TruncateTagsOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "truncate",
+		Short: "write plan of tags deletions, which truncate command would perform",
+		Long:  "write plan of docker image tags deletions, which truncate command would perform, to JSON plan file",
+		Example: "dha plan truncate [--image=...] || [--imageRegEx=...] || [--all] [--inactive] || [--tagRegEx=...] " +
+			"[--keep-last=...] [--older-than=...] [--pulled-before=...] || [--policy=...] [--out=...]",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			truncateOptions.keepLastSet = cmd.Flags().Changed("keep-last")
+			return planTruncate(cmd.Context(), cmd.InheritedFlags(), options.planFile, &truncateOptions)
+		},
+	}
+
+	bindTruncateFlags(cmd.Flags(), &truncateOptions)
+
+	return cmd
+}
+
+// newPlanDeleteCmd returns new plan delete command
+func newPlanDeleteCmd(options *PlanOptions) *cobra.Command {
+	var imageName string
+
+	cmd := &cobra.Command{
+		Use:     "delete",
+		Short:   "write plan of repository deletion, which delete command would perform",
+		Long:    "write plan of docker repository deletion, which delete command would perform, to JSON plan file",
+		Example: "dha plan delete [--image=...] [--out=...]",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return planDelete(cmd.Context(), cmd.InheritedFlags(), options.planFile, imageName)
+		},
+	}
+
+	cmd.Flags().StringVarP(&imageName, "image", "i", "", "docker image name for delete")
+	if err := cmd.MarkFlagRequired("image"); err != nil {
+		// Flag marking should not fail in normal operation
+		return nil
+	}
+
+	return cmd
+}
+
+// planTruncate writes plan of docker image tags deletions, which truncate command would perform
+func planTruncate(ctx context.Context, flags *pflag.FlagSet, planFile string, options *TruncateTagsOptions) error {
+	if err := options.validate(); err != nil {
+		return err
+	}
+
+	client, err := newClient(flags)
+	if err != nil {
+		return err
+	}
+
+	repositories, err := truncateRepositoryNames(ctx, client, options.imageName, options.imageNameRegex, options.allImages)
+	if err != nil {
+		return err
+	}
+
+	workers, err := newPool(flags)
+	if err != nil {
+		return err
+	}
+
+	results := pool.Map(ctx, workers, repositories, func(ctx context.Context, _ int, repo string) (*dockerhub.TruncatePlan, error) {
+		return options.planRepository(ctx, client, repo)
+	})
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	plan := dockerhub.NewPlan(client.ORG)
+	for i, repo := range repositories {
+		truncatePlan, err := results.Values[i], results.Errors[i]
+		if err != nil {
+			return fmt.Errorf("failed to plan truncate for %s: %w", repo, err)
+		}
+		color.Blue("===> %s %s: %s tags (%s)", dockerhub.BW("Planned deletion in"), dockerhub.BG(client.ORG+"/"+repo),
+			dockerhub.BW(len(truncatePlan.Delete)), dockerhub.BW(formatSize(truncatePlan.DeleteSize())))
+		plan.AddTruncatePlan(truncatePlan)
+	}
+
+	return savePlan(plan, planFile)
+}
+
+// planDelete writes plan of docker repository deletion, which delete command would perform
+func planDelete(ctx context.Context, flags *pflag.FlagSet, planFile, image string) error {
+	client, err := newClient(flags)
+	if err != nil {
+		return err
+	}
+	org := client.ORG
+
+	repo, err := client.DescribeRepositoryContext(ctx, image)
+	if err != nil {
+		return fmt.Errorf("failed to describe repository: %w", err)
+	}
+
+	plan := dockerhub.NewPlan(org)
+	plan.AddRepository(repo)
+	color.Blue("===> %s %s", dockerhub.BW("Planned deletion of docker image repository"), dockerhub.BG(org+"/"+image))
+
+	return savePlan(plan, planFile)
+}
+
+// savePlan writes plan to file and prints its summary
+func savePlan(plan *dockerhub.Plan, planFile string) error {
+	if err := plan.Save(planFile); err != nil {
+		return fmt.Errorf("failed to write plan: %w", err)
+	}
+
+	color.Green("Plan with %s tags (%s) and %s repositories deletions written to %s",
+		dockerhub.BW(len(plan.Tags)), dockerhub.BW(formatSize(plan.DeleteSize())), dockerhub.BW(len(plan.Repositories)), dockerhub.BW(planFile))
+
+	return nil
+}
@@ -17,15 +17,16 @@ limitations under the License.
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"os"
-	"runtime"
 
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 
 	"github.com/ealebed/dha/pkg/dockerhub"
+	"github.com/ealebed/dha/pkg/pool"
 )
 
 // RenewTagsOptions represents options for list tags command
@@ -44,7 +45,7 @@ func NewDockerhubRenewTagsCmd() *cobra.Command {
 		Long:    "renew tags from the provided dockerhub repository (image) or all organization repositories",
 		Example: "dha renew [--image=...] || [--all]",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return renewImageTags(cmd.InheritedFlags(), options.imageName, options.allImages)
+			return renewImageTags(cmd.Context(), cmd.InheritedFlags(), options.imageName, options.allImages)
 		},
 	}
 
@@ -55,7 +56,7 @@ func NewDockerhubRenewTagsCmd() *cobra.Command {
 }
 
 // renewImageTags renew tags from the provided dockerhub repository (image)
-func renewImageTags(flags *pflag.FlagSet, image string, allImages bool) error {
+func renewImageTags(ctx context.Context, flags *pflag.FlagSet, image string, allImages bool) error {
 	org, dryRun, err := dockerhub.GetFlags(flags)
 	if err != nil {
 		color.Red("Error: %s", err)
@@ -68,25 +69,45 @@ func renewImageTags(flags *pflag.FlagSet, image string, allImages bool) error {
 			color.Red("You should provide image or set flag --all")
 			os.Exit(1)
 		} else if allImages && image == "" {
-			runtime.GOMAXPROCS(runtime.NumCPU())
-			availableRoutines := runtime.NumCPU()
-			routineReady := make(chan bool)
-
-			repositories, err := dockerhub.NewClient(org, "").ListRepositories()
+			client, err := newClient(flags)
 			if err != nil {
-				color.Red("Error: %s", err)
+				return err
+			}
+			defer printRetries(client)
+			workers, err := newPool(flags)
+			if err != nil {
+				return err
+			}
+			repositories, err := client.ListRepositoriesContext(ctx)
+			if err != nil {
+				return fmt.Errorf("failed to list repositories: %w", err)
 			}
-			for repoCount, repo := range repositories {
-				if availableRoutines == 0 {
-					<-routineReady
-					availableRoutines++
-				}
-				availableRoutines--
 
-				go renewer(repoCount, len(repositories), org, repo, routineReady)
+			results := pool.Run(ctx, workers, repositories, func(ctx context.Context, index int, repo *dockerhub.Repository) error {
+				color.Blue("===> %s %s %s/%s ", dockerhub.BW("Processing docker image repository"), dockerhub.BG(client.ORG+"/"+repo.Name),
+					dockerhub.BW(index+1), dockerhub.BW(len(repositories)))
+				if err := client.RenewDockerImageContext(ctx, repo.Name); err != nil && ctx.Err() == nil {
+					return fmt.Errorf("%s: %w", repo.Name, err)
+				}
+				dockerhub.BG("Done \u2714")
+				return nil
+			})
+
+			if ctx.Err() != nil {
+				color.Yellow("Interrupted: %s of %s repositories processed, %s skipped",
+					dockerhub.BW(results.Started), dockerhub.BW(len(repositories)), dockerhub.BW(len(repositories)-results.Started))
+				return fmt.Errorf("interrupted: %w", context.Canceled)
+			}
+			if failed := results.Failed(); failed > 0 {
+				return fmt.Errorf("failed to renew %d of %d images:\n%w", failed, len(repositories), results.Err())
 			}
 		} else {
-			if err := dockerhub.NewClient(org, "").RenewDockerImage(image); err != nil {
+			client, err := newClient(flags)
+			if err != nil {
+				return err
+			}
+			defer printRetries(client)
+			if err := client.RenewDockerImageContext(ctx, image); err != nil {
 				return fmt.Errorf("failed to renew image: %w", err)
 			}
 			dockerhub.BG("Done \u2714")
@@ -95,15 +116,3 @@ func renewImageTags(flags *pflag.FlagSet, image string, allImages bool) error {
 
 	return nil
 }
-
-func renewer(repoCount, repositories int, org string, repo *dockerhub.Repository, routineReady chan bool) {
-	msg := "Processing docker image repository"
-	repoName := org + "/" + repo.Name
-	color.Blue("===> %s %s %s/%s ", dockerhub.BW(msg), dockerhub.BG(repoName), dockerhub.BW(repoCount+1), dockerhub.BW(repositories))
-	if err := dockerhub.NewClient(org, "").RenewDockerImage(repo.Name); err != nil {
-		color.Red("Error renewing image %s: %v", repo.Name, err)
-	}
-	dockerhub.BG("Done \u2714")
-
-	routineReady <- true
-}
@@ -0,0 +1,249 @@
+/*
+Copyright © 2020 Yevhen Lebid ealebed@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/ealebed/dha/pkg/dockerhub"
+	"github.com/ealebed/dha/pkg/output"
+	"github.com/ealebed/dha/pkg/pool"
+)
+
+// ReportOptions represents options for report command
+type ReportOptions struct {
+	top    int
+	output OutputOptions
+}
+
+// organizationReport represents storage and usage statistics of organization repositories
+type organizationReport struct {
+	Organization string                        `json:"organization"`
+	Repositories []*dockerhub.RepositoryReport `json:"repositories"`
+	Totals       *dockerhub.RepositoryReport   `json:"totals"`
+	Top          []*topList                    `json:"top"`
+}
+
+// topList represents repositories with the highest value of single statistic
+type topList struct {
+	By           string      `json:"by"`
+	Repositories []*topEntry `json:"repositories"`
+}
+
+// topEntry represents repository in top list
+type topEntry struct {
+	Repository string `json:"repository"`
+	Value      int    `json:"value"`
+	size       bool
+}
+
+// reportStatistics lists statistics, which get top list, and whether they are sizes
+var reportStatistics = []struct {
+	name  string
+	size  bool
+	value func(r *dockerhub.RepositoryReport) int
+}{
+	{name: "size", size: true, value: func(r *dockerhub.RepositoryReport) int { return r.Size }},
+	{name: "pull_count", value: func(r *dockerhub.RepositoryReport) int { return r.PullCount }},
+	{name: "inactive_size", size: true, value: func(r *dockerhub.RepositoryReport) int { return r.InactiveSize }},
+	{name: "tags", value: func(r *dockerhub.RepositoryReport) int { return r.Tags }},
+}
+
+// topColumns represents columns of top lists table and Markdown output
+var topColumns = []output.Column[*topEntry]{
+	{Header: "repository", Value: func(e *topEntry) string { return e.Repository }},
+	{Header: "value", Value: func(e *topEntry) string {
+		if e.size {
+			return formatSize(e.Value)
+		}
+		return strconv.Itoa(e.Value)
+	}},
+}
+
+// reportColumns returns columns of report output, sizes are printed in megabytes when human is set, and in bytes otherwise
+func reportColumns(human bool) []output.Column[*dockerhub.RepositoryReport] {
+	size := strconv.Itoa
+	if human {
+		size = formatSize
+	}
+
+	return []output.Column[*dockerhub.RepositoryReport]{
+		{Header: "repository", Value: func(r *dockerhub.RepositoryReport) string { return r.Repository }},
+		{Header: "tags", Value: func(r *dockerhub.RepositoryReport) string { return strconv.Itoa(r.Tags) }},
+		{Header: "size", Value: func(r *dockerhub.RepositoryReport) string { return size(r.Size) }},
+		{Header: "avg_size", Value: func(r *dockerhub.RepositoryReport) string { return size(r.AvgSize) }},
+		{Header: "pull_count", Value: func(r *dockerhub.RepositoryReport) string { return strconv.Itoa(r.PullCount) }},
+		{Header: "inactive_tags", Value: func(r *dockerhub.RepositoryReport) string { return strconv.Itoa(r.InactiveTags) }},
+		{Header: "inactive_size", Value: func(r *dockerhub.RepositoryReport) string { return size(r.InactiveSize) }},
+		{Header: "last_pull", Value: func(r *dockerhub.RepositoryReport) string { return formatTime(r.LastPull) }},
+		{Header: "images_size", Wide: true, Value: func(r *dockerhub.RepositoryReport) string { return size(r.ImagesSize) }},
+		{Header: "oldest_push", Wide: true, Value: func(r *dockerhub.RepositoryReport) string { return formatTime(r.OldestPush) }},
+		{Header: "newest_push", Wide: true, Value: func(r *dockerhub.RepositoryReport) string { return formatTime(r.NewestPush) }},
+	}
+}
+
+// NewDockerhubReportCmd returns new report command
+func NewDockerhubReportCmd() *cobra.Command {
+	options := ReportOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "report",
+		Short: "report storage and usage of organization repositories",
+		Long: "report tags count, size, pull count, inactive tags and push/pull times of every organization repository, " +
+			"with organization totals and top repositories, listing tags of every repository once",
+		Example: "dha report [--top=10] [--output=markdown|json|yaml|csv|tsv|table|wide]",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			printer, err := options.output.printer(cmd)
+			if err != nil {
+				return err
+			}
+			return reportRepositories(cmd.Context(), cmd.InheritedFlags(), printer, &options)
+		},
+	}
+
+	cmd.Flags().IntVar(&options.top, "top", 10, "number of repositories in every top list")
+	addOutputFlags(cmd, &options.output)
+
+	return cmd
+}
+
+// reportRepositories prints statistics of organization repositories, computed from single tags listing per repository
+func reportRepositories(ctx context.Context, flags *pflag.FlagSet, printer *output.Printer, options *ReportOptions) error {
+	if options.top < 0 {
+		return fmt.Errorf("'--top' should not be negative")
+	}
+
+	client, err := newClient(flags)
+	if err != nil {
+		return err
+	}
+	workers, err := newPool(flags)
+	if err != nil {
+		return err
+	}
+
+	repositories, err := client.ListRepositoriesContext(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list repositories: %w", err)
+	}
+
+	results := pool.Map(ctx, workers, repositories, func(ctx context.Context, _ int, repo *dockerhub.Repository) (*dockerhub.RepositoryReport, error) {
+		tags, err := client.ListTagsContext(ctx, repo.Name)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", repo.Name, err)
+		}
+		return dockerhub.NewRepositoryReport(repo, tags), nil
+	})
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	report := &organizationReport{Organization: client.ORG, Repositories: []*dockerhub.RepositoryReport{}}
+	for _, r := range results.Values {
+		if r != nil {
+			report.Repositories = append(report.Repositories, r)
+		}
+	}
+	report.Totals = dockerhub.SumReports("total", report.Repositories)
+	for _, statistic := range reportStatistics {
+		report.Top = append(report.Top, topRepositories(report.Repositories, statistic.name, statistic.size, statistic.value, options.top))
+	}
+
+	if err := printReport(printer, report); err != nil {
+		return err
+	}
+
+	if failed := results.Failed(); failed > 0 {
+		return fmt.Errorf("failed to report %d of %d repositories:\n%w", failed, len(repositories), results.Err())
+	}
+
+	return nil
+}
+
+// topRepositories returns up to n repositories with the highest non-zero value, ties are ordered by name
+func topRepositories(reports []*dockerhub.RepositoryReport, by string, size bool, value func(*dockerhub.RepositoryReport) int, n int) *topList {
+	top := &topList{By: by, Repositories: []*topEntry{}}
+	for _, r := range reports {
+		if v := value(r); v > 0 {
+			top.Repositories = append(top.Repositories, &topEntry{Repository: r.Repository, Value: v, size: size})
+		}
+	}
+
+	sort.SliceStable(top.Repositories, func(i, j int) bool {
+		a, b := top.Repositories[i], top.Repositories[j]
+		if a.Value != b.Value {
+			return a.Value > b.Value
+		}
+		return a.Repository < b.Repository
+	})
+	if len(top.Repositories) > n {
+		top.Repositories = top.Repositories[:n]
+	}
+
+	return top
+}
+
+// printReport prints report: JSON, YAML, template and JSONPath get whole report, CSV and TSV get row per repository in bytes,
+// table and Markdown get repositories, totals and top lists sections
+func printReport(printer *output.Printer, report *organizationReport) error {
+	switch printer.Format {
+	case output.JSON, output.YAML, output.Template, output.JSONPath:
+		return output.PrintObject(printer, report, nil)
+	case output.CSV, output.TSV:
+		return output.PrintList(printer, report.Repositories, reportColumns(false))
+	}
+
+	columns := reportColumns(true)
+	printSection(printer, "Repositories of "+report.Organization, true)
+	if err := output.PrintList(printer, report.Repositories, columns); err != nil {
+		return err
+	}
+
+	printSection(printer, fmt.Sprintf("Totals of %d repositories", len(report.Repositories)), false)
+	if err := output.PrintObject(printer, report.Totals, columns[1:]); err != nil {
+		return err
+	}
+
+	for _, top := range report.Top {
+		printSection(printer, fmt.Sprintf("Top %d repositories by %s", len(top.Repositories), top.By), false)
+		if err := output.PrintList(printer, top.Repositories, topColumns); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// printSection prints section title as Markdown heading or table header, separated from previous section by empty line
+func printSection(printer *output.Printer, title string, first bool) {
+	if !first {
+		fmt.Fprintln(printer.Out)
+	}
+
+	if printer.Format == output.Markdown {
+		fmt.Fprintf(printer.Out, "## %s\n\n", title)
+		return
+	}
+
+	fmt.Fprintf(printer.Out, "===> %s\n", dockerhub.BW(title))
+}
@@ -0,0 +1,357 @@
+/*
+Copyright © 2020 Yevhen Lebid ealebed@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/ealebed/dha/pkg/dockerhub"
+	"github.com/ealebed/dha/pkg/pool"
+)
+
+// SyncOptions represents options for sync command
+type SyncOptions struct {
+	file  string
+	prune bool
+}
+
+// repositorySync represents manifest repository with changes bringing it to manifest state
+type repositorySync struct {
+	manifest *dockerhub.ManifestRepository
+	diff     *dockerhub.RepositoryDiff
+	// retention lists tags deleted by retention rule, nil without rule or for missing repository
+	retention *dockerhub.TruncatePlan
+}
+
+// NewDockerhubSyncCmd returns new sync command
+func NewDockerhubSyncCmd() *cobra.Command {
+	options := SyncOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "sync",
+		Short: "reconcile organization repositories with manifest file",
+		Long: "create repositories missing in organization, update drifted visibility, descriptions, team and collaborator permissions, " +
+			"apply retention rules and report (or delete with '--prune') repositories missing in manifest, printing changes before applying them",
+		Example: "dha sync --file=repos.yaml [--prune] [--dry-run=false]",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return syncRepositories(cmd.Context(), cmd.InheritedFlags(), cmd.OutOrStdout(), &options)
+		},
+	}
+
+	cmd.Flags().StringVarP(&options.file, "file", "f", "", "path to YAML or JSON manifest file with desired state of repositories")
+	cmd.Flags().BoolVar(&options.prune, "prune", false, "delete organization repositories missing in manifest")
+	if err := cmd.MarkFlagRequired("file"); err != nil {
+		// Flag marking should not fail in normal operation
+		return nil
+	}
+
+	return cmd
+}
+
+// syncRepositories prints changes bringing organization repositories to manifest state, and applies them unless in dry-run mode
+func syncRepositories(ctx context.Context, flags *pflag.FlagSet, out io.Writer, options *SyncOptions) error {
+	org, dryRun, err := dockerhub.GetFlags(flags)
+	if err != nil {
+		return err
+	}
+
+	client, manifest, err := newManifestClient(flags, options.file)
+	if err != nil {
+		return err
+	}
+	if options.prune {
+		// guard against deleting every organization repository by empty or foreign manifest
+		if len(manifest.Repositories) == 0 {
+			return fmt.Errorf("refusing to prune by manifest %s without repositories", options.file)
+		}
+		if client.ORG != org {
+			return fmt.Errorf("refusing to prune by manifest for organization %s, while '--org' is %s", client.ORG, org)
+		}
+	}
+	workers, err := newPool(flags)
+	if err != nil {
+		return err
+	}
+
+	syncs, unmanaged, err := planSync(ctx, client, workers, manifest, true)
+	if err != nil {
+		return err
+	}
+
+	printSyncPlan(out, client.ORG, syncs, unmanaged, options.prune)
+	if dryRun {
+		color.Yellow("[DRY-RUN] No changes applied, run with '--dry-run=false' to apply them")
+		return nil
+	}
+
+	return applySync(ctx, client, syncs, unmanaged, options.prune)
+}
+
+// newManifestClient returns docker hub client for organization of manifest file, which defaults to '--org'
+func newManifestClient(flags *pflag.FlagSet, path string) (*dockerhub.Client, *dockerhub.Manifest, error) {
+	manifest, err := dockerhub.LoadManifest(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	client, err := newClient(flags)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if manifest.Organization != "" {
+		if flags.Changed("org") && client.ORG != manifest.Organization {
+			return nil, nil, fmt.Errorf("manifest is for organization %s, while '--org' is %s", manifest.Organization, client.ORG)
+		}
+		client.ORG = manifest.Organization
+	}
+
+	return client, manifest, nil
+}
+
+// planSync returns changes of every manifest repository and names of organization repositories missing in manifest,
+// retention plans are made only when requested
+func planSync(ctx context.Context, client *dockerhub.Client, workers *pool.Pool, manifest *dockerhub.Manifest,
+	retention bool) ([]*repositorySync, []string, error) {
+	repositories, err := client.ListRepositoriesContext(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list repositories: %w", err)
+	}
+
+	existing := map[string]bool{}
+	unmanaged := []string{}
+	for _, repo := range repositories {
+		existing[repo.Name] = true
+		if manifest.Repository(repo.Name) == nil {
+			unmanaged = append(unmanaged, repo.Name)
+		}
+	}
+
+	results := pool.Map(ctx, workers, manifest.Repositories, func(ctx context.Context, _ int, repo *dockerhub.ManifestRepository) (*repositorySync, error) {
+		var err error
+		state := &dockerhub.RepositoryState{}
+		sync := &repositorySync{manifest: repo}
+		if existing[repo.Name] {
+			if state.Repository, err = client.DescribeRepositoryContext(ctx, repo.Name); err != nil {
+				return nil, fmt.Errorf("%s: %w", repo.Name, err)
+			}
+			if repo.Teams != nil {
+				if state.Teams, err = client.ListRepositoryTeamsContext(ctx, repo.Name); err != nil {
+					return nil, fmt.Errorf("%s: failed to list team permissions: %w", repo.Name, err)
+				}
+			}
+			if repo.Collaborators != nil {
+				if state.Collaborators, err = client.ListCollaboratorsContext(ctx, repo.Name); err != nil {
+					return nil, fmt.Errorf("%s: failed to list collaborators: %w", repo.Name, err)
+				}
+			}
+			if policy := repo.RetentionPolicy(); policy != nil && retention {
+				if sync.retention, err = client.PlanRetentionContext(ctx, repo.Name, policy); err != nil {
+					return nil, fmt.Errorf("%s: failed to plan retention: %w", repo.Name, err)
+				}
+			}
+		}
+		sync.diff = repo.Diff(state)
+		return sync, nil
+	})
+	if ctx.Err() != nil {
+		return nil, nil, ctx.Err()
+	}
+	if failed := results.Failed(); failed > 0 {
+		return nil, nil, fmt.Errorf("failed to get state of %d of %d repositories:\n%w", failed, len(manifest.Repositories), results.Err())
+	}
+
+	return results.Values, unmanaged, nil
+}
+
+// printSyncPlan prints changes of repositories as "+" created, "~" updated, "-" deleted and "?" unmanaged ones, followed by summary
+func printSyncPlan(out io.Writer, org string, syncs []*repositorySync, unmanaged []string, prune bool) {
+	var created, updated, retained int
+	for _, sync := range syncs {
+		switch sync.diff.Action {
+		case dockerhub.ActionCreate:
+			created++
+			fmt.Fprintln(out, color.GreenString("+ %s/%s will be created", org, sync.diff.Repository))
+		case dockerhub.ActionUpdate:
+			updated++
+			fmt.Fprintln(out, color.YellowString("~ %s/%s will be updated", org, sync.diff.Repository))
+		}
+		printChanges(out, sync.diff.Changes)
+
+		if sync.retention != nil && len(sync.retention.Delete) > 0 {
+			retained += len(sync.retention.Delete)
+			fmt.Fprintln(out, color.YellowString("~ %s/%s retention will delete %d tags (%s)", org, sync.diff.Repository,
+				len(sync.retention.Delete), formatSize(sync.retention.DeleteSize())))
+		}
+	}
+
+	for _, name := range unmanaged {
+		if prune {
+			fmt.Fprintln(out, color.RedString("- %s/%s will be deleted", org, name))
+		} else {
+			fmt.Fprintln(out, color.CyanString("? %s/%s isn't in manifest", org, name))
+		}
+	}
+
+	unmanagedSummary := strconv.Itoa(len(unmanaged)) + " unmanaged"
+	if prune {
+		unmanagedSummary = strconv.Itoa(len(unmanaged)) + " to delete"
+	}
+	fmt.Fprintf(out, "Plan: %d to create, %d to update, %s, %d tags to delete by retention\n", created, updated, unmanagedSummary, retained)
+}
+
+// printChanges prints changed fields of repository as "field: live -> desired"
+func printChanges(out io.Writer, changes []*dockerhub.Change) {
+	for _, change := range changes {
+		fmt.Fprintf(out, "    %s: %s -> %s\n", change.Field, formatChangeValue(change.Field, change.Live), formatChangeValue(change.Field, change.Desired))
+	}
+}
+
+// formatChangeValue returns printable field value, full descriptions are shortened to their size
+func formatChangeValue(field, value string) string {
+	switch {
+	case value == "":
+		return "(none)"
+	case field == dockerhub.FieldFullDescription:
+		return fmt.Sprintf("(%d bytes)", len(value))
+	case field == dockerhub.FieldDescription:
+		return strconv.Quote(value)
+	}
+
+	return value
+}
+
+// applySync creates and updates repositories, applies retention plans and deletes unmanaged repositories when pruning,
+// failure of one repository doesn't stop others
+func applySync(ctx context.Context, client *dockerhub.Client, syncs []*repositorySync, unmanaged []string, prune bool) error {
+	var teams map[string]*dockerhub.Team
+	var errs []error
+	var failed int
+
+	for _, sync := range syncs {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err := applyRepositorySync(ctx, client, sync, &teams); err != nil {
+			color.Red("Error: %s: %s", sync.diff.Repository, err)
+			errs = append(errs, fmt.Errorf("%s: %w", sync.diff.Repository, err))
+			failed++
+		}
+	}
+
+	if prune {
+		for _, name := range unmanaged {
+			color.Blue("===> %s %s", dockerhub.BW("Deleting docker image repository"), dockerhub.BG(client.ORG+"/"+name))
+			if err := client.DeleteRepositoryContext(ctx, name); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", name, err))
+				failed++
+			}
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("failed to sync %d repositories:\n%w", failed, errors.Join(errs...))
+	}
+	color.Green("Done \u2714")
+
+	return nil
+}
+
+// applyRepositorySync brings single repository to manifest state, organization teams are listed once, on first team change
+func applyRepositorySync(ctx context.Context, client *dockerhub.Client, sync *repositorySync, teams *map[string]*dockerhub.Team) error {
+	name := sync.diff.Repository
+	update := sync.diff.RepositoryUpdate()
+
+	switch sync.diff.Action {
+	case dockerhub.ActionCreate:
+		color.Blue("===> %s %s", dockerhub.BW("Creating docker image repository"), dockerhub.BG(client.ORG+"/"+name))
+		repo := &dockerhub.Repository{Name: name, IsPrivate: update.IsPrivate != nil && *update.IsPrivate}
+		if update.Description != nil {
+			repo.Description = *update.Description
+		}
+		if update.FullDescription != nil {
+			repo.FullDescription = *update.FullDescription
+		}
+		if _, err := client.CreateRepositoryContext(ctx, repo); err != nil {
+			return fmt.Errorf("failed to create repository: %w", err)
+		}
+	case dockerhub.ActionUpdate:
+		color.Blue("===> %s %s", dockerhub.BW("Updating docker image repository"), dockerhub.BG(client.ORG+"/"+name))
+		if err := client.UpdateRepositoryContext(ctx, name, update); err != nil {
+			return fmt.Errorf("failed to update repository: %w", err)
+		}
+	}
+
+	if changes := sync.diff.TeamChanges(); len(changes) > 0 {
+		if *teams == nil {
+			list, err := client.ListTeamsContext(ctx)
+			if err != nil {
+				return fmt.Errorf("failed to list teams: %w", err)
+			}
+			*teams = map[string]*dockerhub.Team{}
+			for _, team := range list {
+				(*teams)[team.Name] = team
+			}
+		}
+		for _, teamName := range slices.Sorted(maps.Keys(changes)) {
+			permission := changes[teamName]
+			team, ok := (*teams)[teamName]
+			if !ok {
+				return fmt.Errorf("team %s not found in organization %s", teamName, client.ORG)
+			}
+			if permission == "" {
+				if err := client.RemoveTeamPermissionContext(ctx, name, team); err != nil {
+					return fmt.Errorf("failed to revoke permission of team %s: %w", teamName, err)
+				}
+				continue
+			}
+			if err := client.SetTeamPermissionContext(ctx, name, team, permission); err != nil {
+				return fmt.Errorf("failed to grant %s permission to team %s: %w", permission, teamName, err)
+			}
+		}
+	}
+
+	changes := sync.diff.CollaboratorChanges()
+	for _, user := range slices.Sorted(maps.Keys(changes)) {
+		if permission := changes[user]; permission == "" {
+			if err := client.RemoveCollaboratorContext(ctx, name, user); err != nil {
+				return fmt.Errorf("failed to revoke permission of collaborator %s: %w", user, err)
+			}
+		} else if err := client.SetCollaboratorPermissionContext(ctx, name, user, permission); err != nil {
+			return fmt.Errorf("failed to grant %s permission to collaborator %s: %w", permission, user, err)
+		}
+	}
+
+	if sync.retention != nil && len(sync.retention.Delete) > 0 {
+		color.Blue("===> %s %s", dockerhub.BW("Applying retention to docker image repository"), dockerhub.BG(client.ORG+"/"+name))
+		if err := client.ApplyTruncatePlanContext(ctx, sync.retention); err != nil {
+			return fmt.Errorf("failed to apply retention: %w", err)
+		}
+	}
+
+	return nil
+}
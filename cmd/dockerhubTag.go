@@ -0,0 +1,154 @@
+/*
+Copyright © 2020 Yevhen Lebid ealebed@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/ealebed/dha/pkg/dockerhub"
+	"github.com/ealebed/dha/pkg/output"
+)
+
+// InspectTagOptions represents options for tag inspect command
+type InspectTagOptions struct {
+	imageName string
+	tagName   string
+	output    OutputOptions
+}
+
+// tagPlatform represents single platform image of docker image tag, printed by tag inspect and get --platforms commands
+type tagPlatform struct {
+	Tag      string `json:"tag"`
+	Platform string `json:"platform"`
+	*dockerhub.Image
+}
+
+// platformColumns represents columns of docker image tag platforms table, CSV and TSV output
+var platformColumns = []output.Column[*tagPlatform]{
+	{Header: "tag", Value: func(p *tagPlatform) string { return p.Tag }},
+	{Header: "platform", Value: func(p *tagPlatform) string { return p.Platform }},
+	{Header: "digest", Value: func(p *tagPlatform) string { return p.image().Digest }},
+	{Header: "size", Value: func(p *tagPlatform) string { return strconv.Itoa(p.image().Size) }},
+	{Header: "os_version", Value: func(p *tagPlatform) string { return p.image().OSVersion }},
+	{Header: "status", Value: func(p *tagPlatform) string { return p.image().Status }},
+	{Header: "last_pushed", Value: func(p *tagPlatform) string { return formatTime(p.image().LastPushed) }},
+	{Header: "last_pulled", Value: func(p *tagPlatform) string { return formatTime(p.image().LastPulled) }},
+	{Header: "features", Wide: true, Value: func(p *tagPlatform) string { return p.image().Features }},
+	{Header: "os_features", Wide: true, Value: func(p *tagPlatform) string { return p.image().OSFeatures }},
+}
+
+// image returns platform image, or empty one for tags without images
+func (p *tagPlatform) image() *dockerhub.Image {
+	if p.Image == nil {
+		return &dockerhub.Image{}
+	}
+
+	return p.Image
+}
+
+// tagPlatformRows returns row per platform image of docker image tags, tags without images get single empty row
+func tagPlatformRows(tags []*dockerhub.Tag) []*tagPlatform {
+	rows := []*tagPlatform{}
+	for _, tag := range tags {
+		if len(tag.Images) == 0 {
+			rows = append(rows, &tagPlatform{Tag: tag.Name})
+			continue
+		}
+		for _, image := range tag.Images {
+			rows = append(rows, &tagPlatform{Tag: tag.Name, Platform: dockerhub.ImagePlatform(image).String(), Image: image})
+		}
+	}
+
+	return rows
+}
+
+// NewDockerhubTagCmd returns new tag command
+func NewDockerhubTagCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "tag",
+		Short:   "inspect docker image tags",
+		Long:    "inspect docker image tags of dockerhub repository (image)",
+		Example: "dha tag inspect [--image=...] [--tag=...]",
+	}
+
+	cmd.AddCommand(newTagInspectCmd())
+
+	return cmd
+}
+
+// newTagInspectCmd returns new tag inspect command
+func newTagInspectCmd() *cobra.Command {
+	options := InspectTagOptions{}
+
+	cmd := &cobra.Command{
+		Use:     "inspect",
+		Short:   "returns details of docker image tag for every platform",
+		Long:    "returns digest, size, OS version and pull/push times of every platform (architecture) of docker image tag",
+		Example: "dha tag inspect [--image=...] [--tag=...] [--output=json|yaml|csv|tsv|table|wide|jsonpath=...]",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			printer, err := options.output.printer(cmd)
+			if err != nil {
+				return err
+			}
+			return inspectTag(cmd.Context(), cmd.InheritedFlags(), printer, &options)
+		},
+	}
+
+	cmd.Flags().StringVarP(&options.imageName, "image", "i", "", "docker image name")
+	cmd.Flags().StringVarP(&options.tagName, "tag", "t", "", "docker image tag name")
+	addOutputFlags(cmd, &options.output)
+	for _, name := range []string{"image", "tag"} {
+		if err := cmd.MarkFlagRequired(name); err != nil {
+			// Flag marking should not fail in normal operation
+			return nil
+		}
+	}
+
+	return cmd
+}
+
+// inspectTag prints docker image tag with its platform images: JSON, YAML, template and JSONPath get whole tag,
+// table, CSV and TSV get row per platform
+func inspectTag(ctx context.Context, flags *pflag.FlagSet, printer *output.Printer, options *InspectTagOptions) error {
+	client, err := newClient(flags)
+	if err != nil {
+		return err
+	}
+
+	tag, err := client.GetTagContext(ctx, options.imageName, options.tagName)
+	if err != nil {
+		return fmt.Errorf("failed to get tag: %w", err)
+	}
+
+	switch printer.Format {
+	case output.JSON, output.YAML, output.Template, output.JSONPath:
+		return output.PrintObject(printer, tag, tagColumns)
+	}
+
+	if printer.IsTable() {
+		fmt.Fprintf(printer.Out, "Tag: %s:%s\nDigest: %s\nStatus: %s\nSize: %s\nLast pushed: %s\nLast pulled: %s\n\n",
+			dockerhub.BW(client.ORG+"/"+options.imageName), dockerhub.BW(tag.Name), dockerhub.TagDigest(tag), tag.TagStatus,
+			formatSize(tag.FullSize), formatTime(tag.TagLastPushed), formatTime(tag.TagLastPulled))
+	}
+
+	return output.PrintList(printer, tagPlatformRows([]*dockerhub.Tag{tag}), platformColumns)
+}
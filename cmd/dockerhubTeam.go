@@ -0,0 +1,148 @@
+/*
+Copyright © 2020 Yevhen Lebid ealebed@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/ealebed/dha/pkg/dockerhub"
+	"github.com/ealebed/dha/pkg/output"
+)
+
+// TeamMembersOptions represents options for team members command
+type TeamMembersOptions struct {
+	teamName string
+	output   OutputOptions
+}
+
+// teamColumns represents columns of team list table, CSV and TSV output
+var teamColumns = []output.Column[*dockerhub.Team]{
+	{Header: "name", Value: func(t *dockerhub.Team) string { return t.Name }},
+	{Header: "members", Value: func(t *dockerhub.Team) string { return strconv.Itoa(t.MemberCount) }},
+	{Header: "description", Value: func(t *dockerhub.Team) string { return t.Description }},
+	{Header: "id", Wide: true, Value: func(t *dockerhub.Team) string { return strconv.FormatInt(t.ID, 10) }},
+}
+
+// memberColumns represents columns of team members table, CSV and TSV output
+var memberColumns = []output.Column[*dockerhub.Member]{
+	{Header: "username", Value: func(m *dockerhub.Member) string { return m.Username }},
+	{Header: "full_name", Value: func(m *dockerhub.Member) string { return m.FullName }},
+	{Header: "id", Wide: true, Value: func(m *dockerhub.Member) string { return m.ID }},
+}
+
+// NewDockerhubTeamCmd returns new team command
+func NewDockerhubTeamCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "team",
+		Short:   "list organization teams and members",
+		Long:    "list teams of organization and members of organization or its team",
+		Example: "dha team list\ndha team members [--team=...]",
+	}
+
+	cmd.AddCommand(newTeamListCmd())
+	cmd.AddCommand(newTeamMembersCmd())
+
+	return cmd
+}
+
+// newTeamListCmd returns new team list command
+func newTeamListCmd() *cobra.Command {
+	options := OutputOptions{}
+
+	cmd := &cobra.Command{
+		Use:     "list",
+		Aliases: []string{"ls"},
+		Short:   "returns list of organization teams",
+		Long:    "returns name, members count and description of every organization team",
+		Example: "dha team list [--output=json|yaml|csv|tsv|markdown|table|wide]",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			printer, err := options.printer(cmd)
+			if err != nil {
+				return err
+			}
+			return listTeams(cmd.Context(), cmd.InheritedFlags(), printer)
+		},
+	}
+
+	addOutputFlags(cmd, &options)
+
+	return cmd
+}
+
+// listTeams prints teams of organization
+func listTeams(ctx context.Context, flags *pflag.FlagSet, printer *output.Printer) error {
+	client, err := newClient(flags)
+	if err != nil {
+		return err
+	}
+
+	teams, err := client.ListTeamsContext(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list teams: %w", err)
+	}
+
+	return output.PrintList(printer, teams, teamColumns)
+}
+
+// newTeamMembersCmd returns new team members command
+func newTeamMembersCmd() *cobra.Command {
+	options := TeamMembersOptions{}
+
+	cmd := &cobra.Command{
+		Use:     "members",
+		Short:   "returns list of organization or team members",
+		Long:    "returns username and full name of every member of organization, or of the specified organization team",
+		Example: "dha team members [--team=...] [--output=json|yaml|csv|tsv|markdown|table|wide]",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			printer, err := options.output.printer(cmd)
+			if err != nil {
+				return err
+			}
+			return listMembers(cmd.Context(), cmd.InheritedFlags(), printer, &options)
+		},
+	}
+
+	cmd.Flags().StringVar(&options.teamName, "team", "", "list members of the specified team instead of whole organization")
+	addOutputFlags(cmd, &options.output)
+
+	return cmd
+}
+
+// listMembers prints members of organization, or of its team when provided
+func listMembers(ctx context.Context, flags *pflag.FlagSet, printer *output.Printer, options *TeamMembersOptions) error {
+	client, err := newClient(flags)
+	if err != nil {
+		return err
+	}
+
+	var members []*dockerhub.Member
+	if options.teamName == "" {
+		members, err = client.ListMembersContext(ctx)
+	} else {
+		members, err = client.ListTeamMembersContext(ctx, options.teamName)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to list members: %w", err)
+	}
+
+	return output.PrintList(printer, members, memberColumns)
+}
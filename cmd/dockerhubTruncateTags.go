@@ -17,17 +17,17 @@ limitations under the License.
 package cmd
 
 import (
+	"context"
 	"fmt"
-	"os"
 	"regexp"
-	"runtime"
-	"time"
+	"strings"
 
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 
 	"github.com/ealebed/dha/pkg/dockerhub"
+	"github.com/ealebed/dha/pkg/pool"
 )
 
 // TruncateTagsOptions represents options for truncate command
@@ -37,6 +37,16 @@ type TruncateTagsOptions struct {
 	allImages            bool
 	truncateInactiveTags bool
 	imageTagRegex        string
+	keepLast             int
+	keepLastSet          bool
+	olderThan            string
+	pulledBefore         string
+	digestSafe           bool
+	versions             string
+	prereleases          bool
+	keepPatches          int
+	policyFile           string
+	policy               *dockerhub.RetentionPolicy
 }
 
 // NewDockerhubTruncateTagsCmd returns new docker truncate tags command
@@ -44,137 +54,302 @@ func NewDockerhubTruncateTagsCmd() *cobra.Command {
 	options := TruncateTagsOptions{}
 
 	cmd := &cobra.Command{
-		Use:     "truncate",
-		Short:   "truncate tags in the specified docker repository",
-		Long:    "truncate tags in the specified docker image repository (by default, except latest 30 ones)",
-		Example: "dha truncate [--image=...] || [--imageRegEx=...] || [--all] [--inactive=...] || [--tagRegEx=...]",
+		Use:   "truncate",
+		Short: "truncate tags in the specified docker repository",
+		Long: "truncate tags, matching regular expression, inactive or older than provided age, in the specified docker image repository, " +
+			"always keeping newest '--keep-last' ones",
+		Example: "dha truncate [--image=...] || [--imageRegEx=...] || [--all] [--inactive=...] || [--tagRegEx=...] " +
+			"[--keep-last=...] [--older-than=...] [--pulled-before=...] [--versions=...] [--prereleases] [--keep-patches=...] || [--policy=...]",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return truncateTags(cmd.InheritedFlags(), options.imageName, options.imageNameRegex, options.allImages, options.truncateInactiveTags, options.imageTagRegex)
+			options.keepLastSet = cmd.Flags().Changed("keep-last")
+			return truncateTags(cmd.Context(), cmd.InheritedFlags(), &options)
 		},
 	}
 
-	cmd.Flags().StringVarP(&options.imageName, "image", "i", "", "docker image name for truncating tags")
-	cmd.Flags().StringVar(&options.imageNameRegex, "imageRegEx", "", "docker image name, matching specified regular expression string")
-	cmd.Flags().BoolVar(&options.allImages, "all", false, "truncate tags in all organization repositories")
-	cmd.Flags().BoolVar(&options.truncateInactiveTags, "inactive", false, "truncate inactive image tags (tags that haven't been pushed or pulled in over a month)")
-	cmd.Flags().StringVar(&options.imageTagRegex, "tagRegEx", "", "truncate image tags, matching specified regular expression string")
+	bindTruncateFlags(cmd.Flags(), &options)
 
 	return cmd
 }
 
-// truncateTags truncate tags in docker repository except latest 30 ones
-func truncateTags(flags *pflag.FlagSet, image, imageRegex string, allImages, truncateInactive bool, tagRegex string) error {
-	org, dryRun, err := dockerhub.GetFlags(flags)
+// bindTruncateFlags adds flags selecting repositories and tags for truncate to provided flag set
+func bindTruncateFlags(flags *pflag.FlagSet, options *TruncateTagsOptions) {
+	flags.StringVarP(&options.imageName, "image", "i", "", "docker image name for truncating tags")
+	flags.StringVar(&options.imageNameRegex, "imageRegEx", "", "docker image name, matching specified regular expression string")
+	flags.BoolVar(&options.allImages, "all", false, "truncate tags in all organization repositories")
+	flags.BoolVar(&options.truncateInactiveTags, "inactive", false, "truncate inactive image tags (tags that haven't been pushed or pulled in over a month)")
+	flags.StringVar(&options.imageTagRegex, "tagRegEx", "", "truncate image tags, matching specified regular expression string")
+	flags.IntVar(&options.keepLast, "keep-last", 0, "always keep newest (by push time) N selected tags (with '--inactive' defaults to 1)")
+	flags.StringVar(&options.olderThan, "older-than", "", "truncate only tags pushed earlier than provided age ago (e.g. 30d, 2w, 12h)")
+	flags.StringVar(&options.pulledBefore, "pulled-before", "", "truncate only tags not pulled within provided age (e.g. 60d)")
+	flags.BoolVar(&options.digestSafe, "digest-safe", false, "keep tags sharing digest with kept tags, deleting digest only when all its tags are selected")
+	flags.StringVar(&options.versions, "versions", "", "truncate only semantic version tags within range (e.g. '<2.0.0' or '>=1.0.0 <1.5.0')")
+	flags.BoolVar(&options.prereleases, "prereleases", false, "truncate only pre-release semantic version tags (e.g. 1.2.0-rc.1)")
+	flags.IntVar(&options.keepPatches, "keep-patches", 0, "always keep newest N patch releases per major.minor version line, selecting only semantic version tags")
+	flags.StringVar(&options.policyFile, "policy", "",
+		"truncate image tags by retention policy rules from YAML or JSON file (all repositories, unless image is provided), instead of tag flags")
+}
+
+// truncateTags truncate tags, selected by tag flags or retention policy, in docker repositories
+func truncateTags(ctx context.Context, flags *pflag.FlagSet, options *TruncateTagsOptions) error {
+	_, dryRun, err := dockerhub.GetFlags(flags)
 	if err != nil {
 		color.Red("Error: %s", err)
 	}
 
-	if dryRun {
-		color.Yellow("[DRY-RUN] Truncating tags for docker image repository: %s/%s", dockerhub.BW(org), dockerhub.BW(image))
-		return nil
+	if err := options.validate(); err != nil {
+		return err
+	}
+
+	client, err := newClient(flags)
+	if err != nil {
+		return err
 	}
+	defer printRetries(client)
 
-	if err := validateTruncateFlags(truncateInactive, tagRegex, allImages, image, imageRegex); err != nil {
+	repositories, err := truncateRepositoryNames(ctx, client, options.imageName, options.imageNameRegex, options.allImages)
+	if err != nil {
 		return err
 	}
 
-	if allImages && (image == "" || imageRegex == "") {
-		return truncateAllRepositories(org, tagRegex, truncateInactive)
+	workers, err := newPool(flags)
+	if err != nil {
+		return err
 	}
 
-	if !allImages && image == "" && imageRegex != "" {
-		return truncateRepositoriesByRegex(org, imageRegex, truncateInactive, tagRegex)
+	if dryRun {
+		return planTruncateTags(ctx, client, workers, repositories, options)
 	}
 
-	return truncateSingleRepository(org, image, truncateInactive, tagRegex)
+	if (options.allImages && (options.imageName == "" || options.imageNameRegex == "")) ||
+		(!options.allImages && options.imageName == "" && options.imageNameRegex != "") {
+		return truncateRepositories(ctx, client, workers, repositories, options)
+	}
+
+	return truncateSingleRepository(ctx, client, options.imageName, options)
 }
 
-func validateTruncateFlags(truncateInactive bool, tagRegex string, allImages bool, image, imageRegex string) error {
-	if !truncateInactive && tagRegex == "" {
-		color.Red("You should provide RegExp for image tag or set flag '--inactive'")
-		os.Exit(1)
+// validate checks truncate flags and loads retention policy file
+func (o *TruncateTagsOptions) validate() error {
+	if o.policyFile != "" {
+		if o.truncateInactiveTags || o.imageTagRegex != "" || o.keepLastSet || o.keepLast != 0 || o.olderThan != "" || o.pulledBefore != "" ||
+			o.digestSafe || o.versions != "" || o.prereleases || o.keepPatches != 0 {
+			return fmt.Errorf("'--policy' can't be combined with '--inactive', '--tagRegEx', '--keep-last', '--older-than', '--pulled-before', " +
+				"'--digest-safe', '--versions', '--prereleases' or '--keep-patches', set them in policy rules instead")
+		}
+		policy, err := dockerhub.LoadRetentionPolicy(o.policyFile)
+		if err != nil {
+			return err
+		}
+		o.policy = policy
+		if o.imageName == "" && o.imageNameRegex == "" {
+			o.allImages = true
+		}
 	}
-	if !allImages && image == "" && imageRegex == "" {
-		color.Red("You should provide image (fixed name or RegExp) or set flag '--all'")
-		os.Exit(1)
+
+	if o.keepLast < 0 {
+		return fmt.Errorf("'--keep-last' should not be negative")
+	}
+	if o.keepPatches < 0 {
+		return fmt.Errorf("'--keep-patches' should not be negative")
+	}
+	if o.versions != "" {
+		if _, err := dockerhub.ParseVersionRange(o.versions); err != nil {
+			return fmt.Errorf("invalid '--versions': %w", err)
+		}
+	}
+	if _, err := dockerhub.ParseDuration(o.olderThan); err != nil {
+		return fmt.Errorf("invalid '--older-than': %w", err)
+	}
+	if _, err := dockerhub.ParseDuration(o.pulledBefore); err != nil {
+		return fmt.Errorf("invalid '--pulled-before': %w", err)
+	}
+
+	if o.policy == nil && !o.truncateInactiveTags && o.imageTagRegex == "" && o.keepLast == 0 && o.olderThan == "" && o.pulledBefore == "" &&
+		o.versions == "" && !o.prereleases && o.keepPatches == 0 {
+		return fmt.Errorf("you should provide RegExp for image tag, set flag '--inactive', '--keep-last', '--older-than', " +
+			"'--pulled-before', '--versions', '--prereleases', '--keep-patches' or provide '--policy' file")
+	}
+	if !o.allImages && o.imageName == "" && o.imageNameRegex == "" {
+		return fmt.Errorf("you should provide image (fixed name or RegExp) or set flag '--all'")
 	}
+
 	return nil
 }
 
-func truncateAllRepositories(org, tagRegex string, truncateInactive bool) error {
-	runtime.GOMAXPROCS(runtime.NumCPU())
-	availableRoutines := runtime.NumCPU()
-	routineReady := make(chan bool)
+// selector returns tag selector for tag selection flags
+func (o *TruncateTagsOptions) selector() *dockerhub.TagSelector {
+	olderThan, _ := dockerhub.ParseDuration(o.olderThan)
+	pulledBefore, _ := dockerhub.ParseDuration(o.pulledBefore)
+
+	selector := &dockerhub.TagSelector{
+		Inactive:     o.truncateInactiveTags,
+		TagRegex:     o.imageTagRegex,
+		KeepLast:     o.keepLast,
+		OlderThan:    olderThan,
+		PulledBefore: pulledBefore,
+		DigestSafe:   o.digestSafe,
+		Versions:     o.versions,
+		Prereleases:  o.prereleases,
+		KeepPatches:  o.keepPatches,
+	}
+
+	// leave latest inactive tag, unless '--keep-last' is provided
+	if o.truncateInactiveTags && !o.keepLastSet {
+		selector.KeepLast = 1
+	}
+
+	return selector
+}
+
+// planRepository returns tags selected for deletion in single repository by retention policy or tag flags
+func (o *TruncateTagsOptions) planRepository(ctx context.Context, client *dockerhub.Client, image string) (*dockerhub.TruncatePlan, error) {
+	if o.policy != nil {
+		return client.PlanRetentionContext(ctx, image, o.policy)
+	}
 
-	repositories, err := dockerhub.NewClient(org, "").ListRepositories()
+	return client.PlanTruncateTagsContext(ctx, image, o.selector())
+}
+
+// truncateRepository deletes tags selected for deletion in single repository
+func (o *TruncateTagsOptions) truncateRepository(ctx context.Context, client *dockerhub.Client, image string) error {
+	plan, err := o.planRepository(ctx, client, image)
 	if err != nil {
-		return fmt.Errorf("failed to list repositories: %w", err)
+		return err
 	}
 
-	limiter := time.Tick(300 * time.Millisecond)
+	return client.ApplyTruncatePlanContext(ctx, plan)
+}
 
-	for repoCount, repo := range repositories {
-		<-limiter
-		if availableRoutines == 0 {
-			<-routineReady
-			availableRoutines++
+// planTruncateTags prints tags which would be deleted and kept in every selected repository, without deleting anything
+func planTruncateTags(ctx context.Context, client *dockerhub.Client, workers *pool.Pool, repositories []string, options *TruncateTagsOptions) error {
+	results := pool.Map(ctx, workers, repositories, func(ctx context.Context, _ int, repo string) (*dockerhub.TruncatePlan, error) {
+		return options.planRepository(ctx, client, repo)
+	})
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	var deleteCount, protectedCount, sharedCount, keepCount, deleteSize int
+	for i, plan := range results.Values {
+		if err := results.Errors[i]; err != nil {
+			color.Red("Error planning truncate for %s: %v", repositories[i], err)
+			continue
 		}
-		availableRoutines--
+		printTruncatePlan(client.ORG, plan)
 
-		go truncater(repoCount, len(repositories), org, tagRegex, truncateInactive, repo, routineReady)
+		deleteCount += len(plan.Delete)
+		protectedCount += len(plan.Protected)
+		sharedCount += len(plan.Shared)
+		keepCount += len(plan.Keep)
+		deleteSize += plan.DeleteSize()
 	}
 
-	for availableRoutines < runtime.NumCPU() {
-		<-routineReady
-		availableRoutines++
-	}
+	color.Yellow("[DRY-RUN] Total: %s tags to delete (%s), %s protected and %s shared digest tags skipped, %s tags to keep in %s repositories",
+		dockerhub.BW(deleteCount), dockerhub.BW(formatSize(deleteSize)), dockerhub.BW(protectedCount), dockerhub.BW(sharedCount),
+		dockerhub.BW(keepCount), dockerhub.BW(len(repositories)))
 
 	return nil
 }
 
-func truncateRepositoriesByRegex(org, imageRegex string, truncateInactive bool, tagRegex string) error {
-	repositories, err := dockerhub.NewClient(org, "").ListRepositories()
+// truncateRepositoryNames returns names of repositories selected by truncate command flags
+func truncateRepositoryNames(ctx context.Context, client *dockerhub.Client, image, imageRegex string, allImages bool) ([]string, error) {
+	truncateAll := allImages && (image == "" || imageRegex == "")
+	if !truncateAll && (image != "" || imageRegex == "") {
+		return []string{image}, nil
+	}
+
+	repositories, err := client.ListRepositoriesContext(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to list repositories: %w", err)
+		return nil, fmt.Errorf("failed to list repositories: %w", err)
 	}
 
 	regexPattern := fmt.Sprintf(`(?i)%s`, imageRegex)
-	var repositoriesToTruncate []string
+	var names []string
 	for _, repo := range repositories {
-		matched, _ := regexp.MatchString(regexPattern, repo.Name)
-		if matched {
-			repositoriesToTruncate = append(repositoriesToTruncate, repo.Name)
+		if !truncateAll {
+			if matched, _ := regexp.MatchString(regexPattern, repo.Name); !matched {
+				continue
+			}
 		}
+		names = append(names, repo.Name)
 	}
 
-	for _, image := range repositoriesToTruncate {
-		color.Blue("===> %s %s ", dockerhub.BW("Processing docker image repository"), dockerhub.BG(org+"/"+image))
-		if err := dockerhub.NewClient(org, "").TruncateTags(image, truncateInactive, tagRegex); err != nil {
-			color.Red("Error truncating tags for %s: %v", image, err)
+	return names, nil
+}
+
+// printTruncatePlan prints tags which would be deleted and kept in single repository
+func printTruncatePlan(org string, plan *dockerhub.TruncatePlan) {
+	color.Yellow("[DRY-RUN] Truncate plan for docker image repository: %s", dockerhub.BW(org+"/"+plan.Repository))
+	printPlannedTags(dockerhub.BR("delete   "), plan.Delete)
+	printPlannedTags(dockerhub.BY("protected"), plan.Protected)
+	printPlannedTags(dockerhub.BY("shared   "), plan.Shared)
+	printPlannedTags(dockerhub.BG("keep     "), plan.Keep)
+
+	shared := plan.SharedDigests()
+	for _, tag := range plan.Delete {
+		if kept, ok := shared[dockerhub.TagDigest(tag)]; ok {
+			color.Yellow("\tWarning: tag %s shares digest %s with kept tags %s", dockerhub.BW(tag.Name),
+				shortDigest(dockerhub.TagDigest(tag)), dockerhub.BW(strings.Join(kept, ", ")))
 		}
-		dockerhub.BG("Done \u2714")
 	}
 
-	return nil
+	fmt.Printf("\tTags to delete: %d (%s), protected tags skipped: %d, shared digest tags skipped: %d, tags to keep: %d\n",
+		len(plan.Delete), formatSize(plan.DeleteSize()), len(plan.Protected), len(plan.Shared), len(plan.Keep))
 }
 
-func truncateSingleRepository(org, image string, truncateInactive bool, tagRegex string) error {
-	color.Blue("===> %s %s ", dockerhub.BW("Processing docker image repository"), dockerhub.BG(org+"/"+image))
-	if err := dockerhub.NewClient(org, "").TruncateTags(image, truncateInactive, tagRegex); err != nil {
-		return fmt.Errorf("failed to truncate tags: %w", err)
+// printPlannedTags prints docker image tags with provided action label
+func printPlannedTags(action string, tags []*dockerhub.Tag) {
+	for _, tag := range tags {
+		fmt.Printf("\t%s | %-60s | %-19s | %-10s | %-29s | %s\n",
+			action, dockerhub.BW(tag.Name), shortDigest(dockerhub.TagDigest(tag)), tag.TagStatus, tag.LastUpdated, formatSize(tag.FullSize))
 	}
-	dockerhub.BG("Done \u2714")
+}
+
+// shortDigest returns digest truncated to 12 hex characters
+func shortDigest(digest string) string {
+	if len(digest) > 19 {
+		return digest[:19]
+	}
+
+	return digest
+}
+
+// formatSize returns human readable size in megabytes
+func formatSize(size int) string {
+	return fmt.Sprintf("%.2f MB", float64(size)/1024/1024)
+}
+
+// truncateRepositories truncates tags in repositories concurrently, on interruption stops starting new repositories
+// and waits for started ones
+func truncateRepositories(ctx context.Context, client *dockerhub.Client, workers *pool.Pool, repositories []string, options *TruncateTagsOptions) error {
+	results := pool.Run(ctx, workers, repositories, func(ctx context.Context, index int, repo string) error {
+		color.Blue("===> %s %s %s/%s ", dockerhub.BW("Processing docker image repository"), dockerhub.BG(client.ORG+"/"+repo),
+			dockerhub.BW(index+1), dockerhub.BW(len(repositories)))
+		if err := options.truncateRepository(ctx, client, repo); err != nil && ctx.Err() == nil {
+			return fmt.Errorf("%s: %w", repo, err)
+		}
+		dockerhub.BG("Done \u2714")
+		return nil
+	})
+
+	if ctx.Err() != nil {
+		return printInterrupted(client, results.Started, len(repositories))
+	}
+	if failed := results.Failed(); failed > 0 {
+		return fmt.Errorf("failed to truncate tags in %d of %d repositories:\n%w", failed, len(repositories), results.Err())
+	}
+
 	return nil
 }
 
-func truncater(repoCount, repositories int, org, tagRegex string, truncateInactive bool, repo *dockerhub.Repository, routineReady chan bool) {
-	msg := "Processing docker image repository"
-	repoName := org + "/" + repo.Name
-	color.Blue("===> %s %s %s/%s ", dockerhub.BW(msg), dockerhub.BG(repoName), dockerhub.BW(repoCount+1), dockerhub.BW(repositories))
-	if err := dockerhub.NewClient(org, "").TruncateTags(repo.Name, truncateInactive, tagRegex); err != nil {
-		color.Red("Error truncating tags for %s: %v", repo.Name, err)
+func truncateSingleRepository(ctx context.Context, client *dockerhub.Client, image string, options *TruncateTagsOptions) error {
+	color.Blue("===> %s %s ", dockerhub.BW("Processing docker image repository"), dockerhub.BG(client.ORG+"/"+image))
+	if err := options.truncateRepository(ctx, client, image); err != nil {
+		if ctx.Err() != nil {
+			return printInterrupted(client, 0, 0)
+		}
+		return fmt.Errorf("failed to truncate tags: %w", err)
 	}
 	dockerhub.BG("Done \u2714")
-
-	routineReady <- true
+	return nil
 }
@@ -0,0 +1,125 @@
+/*
+Copyright © 2020 Yevhen Lebid ealebed@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/ealebed/dha/pkg/dockerhub"
+)
+
+// UpdateRepositoryOptions represents options for docker update repository command
+type UpdateRepositoryOptions struct {
+	imageName           string
+	private             bool
+	public              bool
+	description         string
+	fullDescriptionFile string
+}
+
+// NewDockerhubUpdateRepositoryCmd returns new docker update repository command
+func NewDockerhubUpdateRepositoryCmd() *cobra.Command {
+	options := UpdateRepositoryOptions{}
+
+	cmd := &cobra.Command{
+		Use:     "update",
+		Short:   "update docker repository descriptions and visibility",
+		Long:    "update short description, full description (README) and visibility of docker repository, leaving not provided ones as is",
+		Example: "dha update [--image=...] [--description=...] [--full-description-file=README.md] [--private || --public]",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return updateRepository(cmd.Context(), cmd.InheritedFlags(), cmd.Flags(), &options)
+		},
+	}
+
+	cmd.Flags().StringVarP(&options.imageName, "image", "i", "", "docker image name for update")
+	cmd.Flags().StringVar(&options.description, "description", "", "short description of repository (empty one clears it)")
+	cmd.Flags().StringVar(&options.fullDescriptionFile, "full-description-file", "", "path to file with full description of repository (e.g. README.md)")
+	cmd.Flags().BoolVar(&options.private, "private", false, "make repository private")
+	cmd.Flags().BoolVar(&options.public, "public", false, "make repository public")
+	cmd.MarkFlagsMutuallyExclusive("private", "public")
+	if err := cmd.MarkFlagRequired("image"); err != nil {
+		// Flag marking should not fail in normal operation
+		return nil
+	}
+
+	return cmd
+}
+
+// updateRepository updates docker repository fields, which flags are provided
+func updateRepository(ctx context.Context, flags, localFlags *pflag.FlagSet, options *UpdateRepositoryOptions) error {
+	org, dryRun, err := dockerhub.GetFlags(flags)
+	if err != nil {
+		color.Red("Error: %s", err)
+	}
+
+	update, changes, err := options.update(localFlags)
+	if err != nil {
+		return err
+	}
+	if len(changes) == 0 {
+		return fmt.Errorf("you should provide '--description', '--full-description-file', '--private' or '--public'")
+	}
+
+	if dryRun {
+		color.Yellow("[DRY-RUN] Update docker image repository %s/%s: %s", dockerhub.BW(org), dockerhub.BW(options.imageName), strings.Join(changes, ", "))
+		return nil
+	}
+
+	color.Blue("===> %s %s: %s", dockerhub.BW("Updating docker image repository"), dockerhub.BG(org+"/"+options.imageName), strings.Join(changes, ", "))
+	client, err := newClient(flags)
+	if err != nil {
+		return err
+	}
+	if err := client.UpdateRepositoryContext(ctx, options.imageName, update); err != nil {
+		return fmt.Errorf("failed to update repository: %w", err)
+	}
+	color.Green("Done \u2714")
+
+	return nil
+}
+
+// update returns repository update for provided flags, with description of every change
+func (o *UpdateRepositoryOptions) update(flags *pflag.FlagSet) (*dockerhub.RepositoryUpdate, []string, error) {
+	update := &dockerhub.RepositoryUpdate{}
+	changes := []string{}
+
+	if flags.Changed("description") {
+		update.Description = &o.description
+		changes = append(changes, fmt.Sprintf("description %q", o.description))
+	}
+	if o.fullDescriptionFile != "" {
+		fullDescription, err := readDescriptionFile(o.fullDescriptionFile)
+		if err != nil {
+			return nil, nil, err
+		}
+		update.FullDescription = &fullDescription
+		changes = append(changes, fmt.Sprintf("full description from %s (%d bytes)", o.fullDescriptionFile, len(fullDescription)))
+	}
+	if o.private || o.public {
+		private := o.private
+		update.IsPrivate = &private
+		changes = append(changes, "make "+dockerhub.Visibility(private))
+	}
+
+	return update, changes, nil
+}
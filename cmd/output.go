@@ -0,0 +1,80 @@
+/*
+Copyright © 2020 Yevhen Lebid ealebed@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"os"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/ealebed/dha/pkg/dockerhub"
+	"github.com/ealebed/dha/pkg/output"
+)
+
+// OutputOptions represents output flags of read commands
+type OutputOptions struct {
+	format   string
+	template string
+}
+
+// addOutputFlags adds '--output' and '--template' flags to command
+func addOutputFlags(cmd *cobra.Command, options *OutputOptions) {
+	cmd.Flags().StringVarP(&options.format, "output", "o", output.Table,
+		"output format: "+strings.Join(output.Formats, ", ")+" (e.g. 'jsonpath={.name}')")
+	cmd.Flags().StringVar(&options.template, "template", "", "Go template, applied to every printed item (e.g. '{{.Name}}')")
+}
+
+// printer returns printer for command output, color is disabled unless table is written to terminal,
+// and messages of machine-readable formats go to stderr, so they never mix with printed results
+func (o *OutputOptions) printer(cmd *cobra.Command) (*output.Printer, error) {
+	out := cmd.OutOrStdout()
+	printer, err := output.NewPrinter(out, o.format, o.template)
+	if err != nil {
+		return nil, err
+	}
+
+	if f, ok := out.(*os.File); !ok || !isTerminal(f) || !printer.IsTable() {
+		color.NoColor = true
+	}
+	if !printer.IsTable() {
+		color.Output = os.Stderr
+	}
+
+	return printer, nil
+}
+
+// formatTime returns time in RFC 3339 format, or empty string for zero time
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+
+	return t.UTC().Format(time.RFC3339)
+}
+
+// tagPlatforms returns platforms ("os/architecture[/variant]") of docker image tag
+func tagPlatforms(tag *dockerhub.Tag) string {
+	platforms := make([]string, 0, len(tag.Images))
+	for _, image := range tag.Images {
+		platforms = append(platforms, dockerhub.ImagePlatform(image).String())
+	}
+
+	return strings.Join(platforms, ",")
+}
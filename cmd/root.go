@@ -17,24 +17,46 @@ limitations under the License.
 package cmd
 
 import (
+	"context"
 	"io"
 	"os"
+	"os/signal"
+	"runtime"
+	"syscall"
 
 	"github.com/spf13/cobra"
 
 	"github.com/ealebed/dha/cmd/version"
+	"github.com/ealebed/dha/pkg/dockerhub"
 )
 
 // RootOptions implements global flags for all commands
 type RootOptions struct {
 	organization string
 	dryRun       bool
+	configFile   string
+	protect      []string
+	verbose      bool
+	hubURL       string
+	tokenFile    string
+	noTokenCache bool
+	concurrency  int
 }
 
-// Execute adds all child commands to the root command and sets flags appropriately
+// Execute adds all child commands to the root command and sets flags appropriately,
+// SIGINT or SIGTERM cancels commands context, so they stop scheduling new work
 func Execute(out io.Writer) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	go func() {
+		<-ctx.Done()
+		// restore default signals behavior, so repeated signal terminates immediately
+		stop()
+	}()
+
 	cmd := NewCmdRoot(out)
-	return cmd.Execute()
+	return cmd.ExecuteContext(ctx)
 }
 
 // NewCmdRoot returns new root command
@@ -46,17 +68,40 @@ func NewCmdRoot(out io.Writer) *cobra.Command {
 		SilenceErrors: true,
 		Version:       version.String(),
 	}
+	if out != nil {
+		cmd.SetOut(out)
+	}
 
 	cmd.PersistentFlags().StringVar(&options.organization, "org", os.Getenv("DOCKERHUB_USERNAME"), "repository source owner (user/organization)")
 	cmd.PersistentFlags().BoolVar(&options.dryRun, "dry-run", true, "print output only")
+	cmd.PersistentFlags().StringVar(&options.configFile, "config", dockerhub.DefaultConfigPath(), "path to dha configuration file")
+	cmd.PersistentFlags().StringSliceVar(&options.protect, "protect", nil,
+		"regular expressions of image tags, which are never deleted (in addition to 'latest' and configuration file ones)")
+	cmd.PersistentFlags().StringVar(&options.hubURL, "hub-url", os.Getenv("DHA_HUB_URL"), "docker hub address (default https://hub.docker.com)")
+	cmd.PersistentFlags().StringVar(&options.tokenFile, "token-file", "",
+		"path to file with docker hub personal or organization access token (instead of DOCKERHUB_TOKEN or DOCKERHUB_PASSWORD)")
+	cmd.PersistentFlags().BoolVar(&options.noTokenCache, "no-token-cache", false, "don't reuse docker hub login between runs")
+	cmd.PersistentFlags().IntVar(&options.concurrency, "concurrency", runtime.NumCPU(), "number of repositories processed at once by bulk commands")
+	cmd.PersistentFlags().BoolVar(&options.verbose, "verbose", false, "print retries of rate limited and failed docker hub requests")
 
 	// create subcommands
+	cmd.AddCommand(NewDockerhubApplyCmd())
+	cmd.AddCommand(NewDockerhubAuditCmd())
+	cmd.AddCommand(NewDockerhubCreateRepositoryCmd())
 	cmd.AddCommand(NewDockerhubDeleteRepositoryCmd())
 	cmd.AddCommand(NewDockerhubDescribeRepositoryCmd())
+	cmd.AddCommand(NewDockerhubDiffCmd())
 	cmd.AddCommand(NewDockerhubListRepositoriesCmd())
 	cmd.AddCommand(NewDockerhubListTagsCmd())
+	cmd.AddCommand(NewDockerhubPermissionCmd())
+	cmd.AddCommand(NewDockerhubPlanCmd())
 	cmd.AddCommand(NewDockerhubRenewTagsCmd())
+	cmd.AddCommand(NewDockerhubReportCmd())
+	cmd.AddCommand(NewDockerhubSyncCmd())
+	cmd.AddCommand(NewDockerhubTagCmd())
+	cmd.AddCommand(NewDockerhubTeamCmd())
 	cmd.AddCommand(NewDockerhubTruncateTagsCmd())
+	cmd.AddCommand(NewDockerhubUpdateRepositoryCmd())
 
 	return cmd
 }
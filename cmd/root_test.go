@@ -18,10 +18,27 @@ package cmd
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/fatih/color"
 	"github.com/spf13/cobra"
+
+	"github.com/ealebed/dha/pkg/dockerhub"
+	"github.com/ealebed/dha/pkg/pool"
 )
 
 func TestNewCmdRoot(t *testing.T) {
@@ -74,12 +91,23 @@ func TestNewCmdRootSubcommands(t *testing.T) {
 
 	// Verify all subcommands are added
 	expectedCommands := []string{
+		"apply",
+		"audit",
+		"create",
 		"delete", "del",
 		"describe",
+		"diff",
 		"list", "ls",
 		"get",
+		"permission", "perm",
+		"plan",
 		"renew",
+		"report",
+		"sync",
+		"tag",
+		"team",
 		"truncate",
+		"update",
 	}
 
 	commands := cmd.Commands()
@@ -90,7 +118,7 @@ func TestNewCmdRootSubcommands(t *testing.T) {
 	// Check that expected commands exist
 	commandMap := make(map[string]bool)
 	for _, c := range commands {
-		commandMap[c.Use] = true
+		commandMap[c.Name()] = true
 		for _, alias := range c.Aliases {
 			commandMap[alias] = true
 		}
@@ -117,6 +145,38 @@ func TestNewCmdRootFlags(t *testing.T) {
 		t.Error("NewCmdRoot() should have 'dry-run' persistent flag")
 	}
 
+	protectFlag := cmd.PersistentFlags().Lookup("protect")
+	if protectFlag == nil {
+		t.Fatal("NewCmdRoot() should have 'protect' persistent flag")
+	}
+	if protectFlag.Value.Type() != "stringSlice" {
+		t.Errorf("protect flag type = %v, want stringSlice", protectFlag.Value.Type())
+	}
+
+	if cmd.PersistentFlags().Lookup("config") == nil {
+		t.Error("NewCmdRoot() should have 'config' persistent flag")
+	}
+
+	if cmd.PersistentFlags().Lookup("verbose") == nil {
+		t.Error("NewCmdRoot() should have 'verbose' persistent flag")
+	}
+
+	if cmd.PersistentFlags().Lookup("hub-url") == nil {
+		t.Error("NewCmdRoot() should have 'hub-url' persistent flag")
+	}
+
+	if cmd.PersistentFlags().Lookup("token-file") == nil {
+		t.Error("NewCmdRoot() should have 'token-file' persistent flag")
+	}
+
+	if cmd.PersistentFlags().Lookup("no-token-cache") == nil {
+		t.Error("NewCmdRoot() should have 'no-token-cache' persistent flag")
+	}
+
+	if cmd.PersistentFlags().Lookup("concurrency") == nil {
+		t.Error("NewCmdRoot() should have 'concurrency' persistent flag")
+	}
+
 	// Verify flag types
 	if orgFlag.Value.Type() != "string" {
 		t.Errorf("org flag type = %v, want string", orgFlag.Value.Type())
@@ -207,6 +267,12 @@ func TestNewDockerhubListTagsCmd(t *testing.T) {
 	if imageFlag == nil {
 		t.Error("Command should have 'image' flag")
 	}
+
+	for _, name := range []string{"sort", "versions"} {
+		if cmd.Flags().Lookup(name) == nil {
+			t.Errorf("Command should have '%s' flag", name)
+		}
+	}
 }
 
 func TestNewDockerhubRenewTagsCmd(t *testing.T) {
@@ -268,4 +334,998 @@ func TestNewDockerhubTruncateTagsCmd(t *testing.T) {
 	if tagRegexFlag == nil {
 		t.Error("Command should have 'tagRegEx' flag")
 	}
+
+	policyFlag := cmd.Flags().Lookup("policy")
+	if policyFlag == nil {
+		t.Error("Command should have 'policy' flag")
+	}
+
+	for _, name := range []string{"keep-last", "older-than", "pulled-before", "digest-safe", "versions", "prereleases", "keep-patches"} {
+		if cmd.Flags().Lookup(name) == nil {
+			t.Errorf("Command should have '%s' flag", name)
+		}
+	}
+}
+
+func TestTruncateTagsOptionsSelector(t *testing.T) {
+	tests := []struct {
+		name         string
+		options      TruncateTagsOptions
+		wantKeepLast int
+		wantErr      bool
+	}{
+		{
+			name:         "inactive leaves latest inactive tag",
+			options:      TruncateTagsOptions{imageName: "image", truncateInactiveTags: true},
+			wantKeepLast: 1,
+		},
+		{
+			name:         "inactive with explicit keep-last",
+			options:      TruncateTagsOptions{imageName: "image", truncateInactiveTags: true, keepLast: 0, keepLastSet: true},
+			wantKeepLast: 0,
+		},
+		{
+			name:         "regex with keep-last and age",
+			options:      TruncateTagsOptions{imageName: "image", imageTagRegex: "dev", keepLast: 5, keepLastSet: true, olderThan: "2w"},
+			wantKeepLast: 5,
+		},
+		{
+			name:    "no tag selection",
+			options: TruncateTagsOptions{imageName: "image"},
+			wantErr: true,
+		},
+		{
+			name:    "no image selection",
+			options: TruncateTagsOptions{truncateInactiveTags: true},
+			wantErr: true,
+		},
+		{
+			name:    "invalid age",
+			options: TruncateTagsOptions{imageName: "image", olderThan: "month"},
+			wantErr: true,
+		},
+		{
+			name:         "semantic versions only",
+			options:      TruncateTagsOptions{imageName: "image", versions: "<2.0.0", keepPatches: 2},
+			wantKeepLast: 0,
+		},
+		{
+			name:    "invalid versions range",
+			options: TruncateTagsOptions{imageName: "image", versions: "~2"},
+			wantErr: true,
+		},
+		{
+			name:    "negative keep-last",
+			options: TruncateTagsOptions{imageName: "image", keepLast: -1, keepLastSet: true},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.options.validate()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			selector := tt.options.selector()
+			if selector.KeepLast != tt.wantKeepLast {
+				t.Errorf("selector() KeepLast = %v, want %v", selector.KeepLast, tt.wantKeepLast)
+			}
+			if selector.Inactive != tt.options.truncateInactiveTags || selector.TagRegex != tt.options.imageTagRegex {
+				t.Errorf("selector() = %+v", selector)
+			}
+		})
+	}
+
+	policy := filepath.Join(t.TempDir(), "retention.yaml")
+	if err := os.WriteFile(policy, []byte("rules:\n  - tags: ^dev-\n    keep_last: 1\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := (&TruncateTagsOptions{policyFile: policy}).validate(); err != nil {
+		t.Errorf("validate() of policy error = %v", err)
+	}
+	for _, options := range []*TruncateTagsOptions{
+		{policyFile: policy, imageTagRegex: "dev"},
+		{policyFile: policy, keepLastSet: true},
+		{policyFile: policy, truncateInactiveTags: true},
+	} {
+		if err := options.validate(); err == nil {
+			t.Errorf("validate() of policy with tag flags %+v should fail", options)
+		}
+	}
+}
+
+func TestNewDockerhubPlanCmd(t *testing.T) {
+	cmd := NewDockerhubPlanCmd()
+
+	if cmd == nil {
+		t.Fatal("NewDockerhubPlanCmd() returned nil")
+	}
+
+	if cmd.Use != "plan" {
+		t.Errorf("Command Use = %v, want plan", cmd.Use)
+	}
+
+	outFlag := cmd.PersistentFlags().Lookup("out")
+	if outFlag == nil {
+		t.Error("Command should have 'out' flag")
+	}
+
+	subcommands := make(map[string]*cobra.Command)
+	for _, c := range cmd.Commands() {
+		subcommands[c.Name()] = c
+	}
+
+	truncateCmd, ok := subcommands["truncate"]
+	if !ok {
+		t.Fatal("Command should have 'truncate' subcommand")
+	}
+	for _, name := range []string{"image", "imageRegEx", "all", "inactive", "tagRegEx"} {
+		if truncateCmd.Flags().Lookup(name) == nil {
+			t.Errorf("truncate subcommand should have '%s' flag", name)
+		}
+	}
+
+	deleteCmd, ok := subcommands["delete"]
+	if !ok {
+		t.Fatal("Command should have 'delete' subcommand")
+	}
+	if deleteCmd.Flags().Lookup("image") == nil {
+		t.Error("delete subcommand should have 'image' flag")
+	}
+}
+
+func TestNewDockerhubApplyCmd(t *testing.T) {
+	cmd := NewDockerhubApplyCmd()
+
+	if cmd == nil {
+		t.Fatal("NewDockerhubApplyCmd() returned nil")
+	}
+
+	if cmd.Name() != "apply" {
+		t.Errorf("Command Name = %v, want apply", cmd.Name())
+	}
+
+	if err := cmd.Args(cmd, []string{}); err == nil {
+		t.Error("Command should require plan file argument")
+	}
+}
+
+func TestApplyPlanOrganization(t *testing.T) {
+	plan := filepath.Join(t.TempDir(), "plan.json")
+	content := `{"version": 1, "organization": "other", "repositories": [{"name": "api"}]}`
+	if err := os.WriteFile(plan, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var requests []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+	})
+	if _, err := executeCmd(t, mux, "apply", plan, "--dry-run=false"); err == nil || len(requests) != 0 {
+		t.Errorf("apply of plan for other organization than '--org' sent %v, error %v, want error and no requests", requests, err)
+	}
+}
+
+func TestTruncateRepositoriesInterrupted(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	client := dockerhub.NewClient("testorg", "")
+	options := &TruncateTagsOptions{allImages: true, imageTagRegex: "dev"}
+
+	err := truncateRepositories(ctx, client, pool.New(2, 0), []string{"image-1", "image-2"}, options)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("truncateRepositories() error = %v, want context.Canceled", err)
+	}
+}
+
+func TestHubURLFlag(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv("DOCKER_CONFIG", t.TempDir())
+
+	var deleted string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/v2/users/login":
+			_, _ = w.Write([]byte(`{"token": "test-token"}`))
+		case r.Method == http.MethodDelete:
+			deleted = r.URL.Path
+			w.WriteHeader(http.StatusAccepted)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	cmd := NewCmdRoot(&bytes.Buffer{})
+	cmd.SetArgs([]string{"delete", "--image=api", "--org=testorg", "--dry-run=false", "--config=", "--hub-url=" + server.URL})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+
+	if deleted != "/v2/repositories/testorg/api/" {
+		t.Errorf("deleted = %v, want /v2/repositories/testorg/api/", deleted)
+	}
+}
+
+func TestReadTokenFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "token")
+	if err := os.WriteFile(path, []byte("dckr_pat_secret\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	token, err := readTokenFile(path)
+	if err != nil || token != "dckr_pat_secret" {
+		t.Errorf("readTokenFile() = %q, %v, want dckr_pat_secret", token, err)
+	}
+
+	empty := filepath.Join(dir, "empty")
+	if err := os.WriteFile(empty, []byte("\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readTokenFile(empty); err == nil {
+		t.Error("readTokenFile() with empty file should return error")
+	}
+
+	if _, err := readTokenFile(filepath.Join(dir, "missing")); err == nil {
+		t.Error("readTokenFile() with missing file should return error")
+	}
+}
+
+// executeCmd runs root command with provided arguments against docker hub stand-in, serving mux routes, and returns its output
+func executeCmd(t *testing.T, mux *http.ServeMux, args ...string) (string, error) {
+	t.Helper()
+	return executeCmdContext(t, context.Background(), mux, args...)
+}
+
+// executeCmdContext runs root command like executeCmd, using provided context
+func executeCmdContext(t *testing.T, ctx context.Context, mux *http.ServeMux, args ...string) (string, error) {
+	t.Helper()
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv("DOCKER_CONFIG", t.TempDir())
+
+	noColor, colorOutput := color.NoColor, color.Output
+	t.Cleanup(func() {
+		color.NoColor, color.Output = noColor, colorOutput
+	})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost && r.URL.Path == "/v2/users/login" {
+			_, _ = w.Write([]byte(`{"token": "test-token"}`))
+			return
+		}
+		mux.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	var out bytes.Buffer
+	cmd := NewCmdRoot(&out)
+	cmd.SetArgs(append(args, "--org=testorg", "--config=", "--hub-url="+server.URL))
+	err := cmd.ExecuteContext(ctx)
+
+	return out.String(), err
+}
+
+// serveJSON returns handler, responding with provided JSON body
+func serveJSON(body string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(body))
+	}
+}
+
+func TestOutputFormats(t *testing.T) {
+	tags := `{"count": 2, "results": [
+		{"name": "1.0.0", "tag_status": "active", "full_size": 100, "images": [{"os": "linux", "architecture": "amd64"}]},
+		{"name": "latest", "tag_status": "inactive", "full_size": 200, "images": [{"os": "linux", "architecture": "arm64", "variant": "v8"}]}
+	]}`
+	repo := `{"name": "api", "namespace": "testorg", "pull_count": 42, "is_private": true}`
+
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{
+			name: "get jsonpath",
+			args: []string{"get", "--image=api", "--output", "jsonpath={.name} {.images[*].architecture}"},
+			want: "1.0.0 amd64\nlatest arm64\n",
+		},
+		{
+			name: "get csv",
+			args: []string{"get", "--image=api", "-o", "csv"},
+			want: "name,status,last_updated,digest,size,last_pushed,last_pulled,platforms\n" +
+				"1.0.0,active,,,100,,,linux/amd64\nlatest,inactive,,,200,,,linux/arm64/v8\n",
+		},
+		{
+			name: "get template",
+			args: []string{"get", "--image=api", "--template", "{{.Name}}:{{.FullSize}}"},
+			want: "1.0.0:100\nlatest:200\n",
+		},
+		{
+			name: "describe jsonpath",
+			args: []string{"describe", "--image=api", "-o", "jsonpath={.namespace}/{.name} {.pull_count} {.is_private}"},
+			want: "testorg/api 42 true\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mux := http.NewServeMux()
+			mux.HandleFunc("GET /v2/repositories/testorg/api/tags/", serveJSON(tags))
+			mux.HandleFunc("GET /v2/repositories/testorg/api", serveJSON(repo))
+
+			out, err := executeCmd(t, mux, tt.args...)
+			if err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+			if out != tt.want {
+				t.Errorf("output =\n%s\nwant\n%s", out, tt.want)
+			}
+		})
+	}
+}
+
+func TestOutputFormatsJSON(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v2/repositories/testorg/{$}", serveJSON(`{"count": 1, "results": [{"name": "api", "pull_count": 7}]}`))
+	mux.HandleFunc("GET /v2/repositories/testorg/api/tags/", serveJSON(`{"count": 3, "results": [
+		{"name": "a", "full_size": 1048576}, {"name": "b", "full_size": 1048576}, {"name": "c", "full_size": 1048576}
+	]}`))
+
+	out, err := executeCmd(t, mux, "list", "--output=json")
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+
+	var repos []map[string]any
+	if err := json.Unmarshal([]byte(out), &repos); err != nil {
+		t.Fatalf("list output isn't JSON: %v\n%s", err, out)
+	}
+	if len(repos) != 1 || repos[0]["name"] != "api" || repos[0]["pull_count"] != float64(7) || repos[0]["tags_count"] != float64(3) {
+		t.Errorf("list output = %v", repos)
+	}
+
+	if _, err := executeCmd(t, http.NewServeMux(), "list", "--output=xml"); err == nil {
+		t.Error("list with unsupported output should fail")
+	}
+
+	broken := http.NewServeMux()
+	broken.HandleFunc("GET /v2/repositories/testorg/{$}", serveJSON(`{"count": 2, "results": [{"name": "api"}, {"name": "web"}]}`))
+	broken.HandleFunc("GET /v2/repositories/testorg/api/tags/", serveJSON(`{"count": 1, "results": [{"name": "a"}]}`))
+	broken.HandleFunc("GET /v2/repositories/testorg/web/tags/", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"detail": "forbidden"}`, http.StatusForbidden)
+	})
+	out, err = executeCmd(t, broken, "list", "-o", "csv")
+	if err == nil || !strings.Contains(err.Error(), "failed to list tags of 1 of 2 repositories") || !strings.HasSuffix(out, "\n,api,1,0,0.00,0,false,\n") {
+		t.Errorf("list with failing repository: output = %q, error = %v", out, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	interrupted := http.NewServeMux()
+	interrupted.HandleFunc("GET /v2/repositories/testorg/{$}", serveJSON(`{"count": 2, "results": [{"name": "api"}, {"name": "web"}]}`))
+	interrupted.HandleFunc("GET /v2/repositories/testorg/{repo}/tags/", func(w http.ResponseWriter, r *http.Request) {
+		cancel()
+		_, _ = w.Write([]byte(`{"count": 0, "results": []}`))
+	})
+	if out, err := executeCmdContext(t, ctx, interrupted, "list", "--concurrency=1", "-o", "json"); !errors.Is(err, context.Canceled) || out != "" {
+		t.Errorf("interrupted list: output = %q, error = %v, want context.Canceled and no output", out, err)
+	}
+
+	failing := http.NewServeMux()
+	failing.HandleFunc("GET /v2/", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"detail": "forbidden"}`, http.StatusForbidden)
+	})
+	for _, args := range [][]string{{"list", "-o", "json"}, {"get", "--image=api", "-o", "json"}} {
+		if out, err := executeCmd(t, failing, args...); err == nil || out != "" {
+			t.Errorf("%v with failing hub: output = %q, error = %v, want error and no output", args, out, err)
+		}
+	}
+}
+
+func TestTagPlatforms(t *testing.T) {
+	tag := `{"name": "1.0.0", "digest": "sha256:index", "tag_status": "active", "images": [
+		{"os": "linux", "architecture": "amd64", "digest": "sha256:amd", "size": 100, "last_pushed": "2024-01-02T03:04:05Z"},
+		{"os": "linux", "architecture": "arm", "variant": "v7", "digest": "sha256:arm", "size": 90}
+	]}`
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v2/repositories/testorg/api/tags/1.0.0/", serveJSON(tag))
+	mux.HandleFunc("GET /v2/repositories/testorg/api/tags/", serveJSON(`{"count": 2, "results": [`+tag+`, {"name": "old"}]}`))
+
+	out, err := executeCmd(t, mux, "tag", "inspect", "--image=api", "--tag=1.0.0", "-o", "csv")
+	if err != nil {
+		t.Fatalf("tag inspect error = %v", err)
+	}
+	want := "tag,platform,digest,size,os_version,status,last_pushed,last_pulled,features,os_features\n" +
+		"1.0.0,linux/amd64,sha256:amd,100,,,2024-01-02T03:04:05Z,,,\n" +
+		"1.0.0,linux/arm/v7,sha256:arm,90,,,,,,\n"
+	if out != want {
+		t.Errorf("tag inspect output =\n%s\nwant\n%s", out, want)
+	}
+
+	out, err = executeCmd(t, mux, "get", "--image=api", "--platforms", "-o", "jsonpath={.tag} {.platform}")
+	if err != nil {
+		t.Fatalf("get --platforms error = %v", err)
+	}
+	if want := "1.0.0 linux/amd64\n1.0.0 linux/arm/v7\nold \n"; out != want {
+		t.Errorf("get --platforms output = %q, want %q", out, want)
+	}
+}
+
+func TestAuditPlatforms(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v2/repositories/testorg/{$}", serveJSON(`{"count": 2, "results": [{"name": "api"}, {"name": "web"}]}`))
+	mux.HandleFunc("GET /v2/repositories/testorg/api/tags/", serveJSON(`{"count": 2, "results": [
+		{"name": "1.0.0", "images": [{"os": "linux", "architecture": "amd64"}, {"os": "linux", "architecture": "arm64"}]},
+		{"name": "0.9.0", "images": [{"os": "linux", "architecture": "amd64"}]}
+	]}`))
+	mux.HandleFunc("GET /v2/repositories/testorg/web/tags/", serveJSON(`{"count": 1, "results": [
+		{"name": "1.0.0", "images": [{"os": "linux", "architecture": "amd64"}, {"os": "linux", "architecture": "arm64", "variant": "v8"}]}
+	]}`))
+
+	out, err := executeCmd(t, mux, "audit", "platforms", "--require=linux/amd64,linux/arm64", "-o", "json")
+	if !errors.Is(err, ErrPlatformViolations) {
+		t.Fatalf("audit platforms error = %v, want ErrPlatformViolations", err)
+	}
+
+	var audits []*repositoryAudit
+	if err := json.Unmarshal([]byte(out), &audits); err != nil {
+		t.Fatalf("audit platforms output isn't JSON: %v\n%s", err, out)
+	}
+	if len(audits) != 1 || audits[0].Repository != "api" || audits[0].Tags != 2 || len(audits[0].Violations) != 1 {
+		t.Fatalf("audit platforms output = %s", out)
+	}
+	if violation := audits[0].Violations[0]; violation.Tag != "0.9.0" || strings.Join(violation.Missing, ",") != "linux/arm64" {
+		t.Errorf("violation = %+v, want 0.9.0 missing linux/arm64", violation)
+	}
+
+	if _, err := executeCmd(t, mux, "audit", "platforms", "--require=linux/amd64", "--image=api"); err != nil {
+		t.Errorf("audit platforms without violations error = %v", err)
+	}
+	if _, err := executeCmd(t, mux, "audit", "platforms", "--require=amd64"); err == nil {
+		t.Error("audit platforms with invalid platform should fail")
+	}
+
+	out, _ = executeCmd(t, mux, "audit", "platforms", "--require=linux/amd64,linux/arm64", "-o", "markdown")
+	if out != "| repository | tag | missing | platforms |\n| --- | --- | --- | --- |\n| api | 0.9.0 | linux/arm64 | linux/amd64 |\n" {
+		t.Errorf("audit platforms markdown output =\n%s", out)
+	}
+	// tag regular expression is case insensitive, as in truncate
+	mux.HandleFunc("GET /v2/repositories/testorg/cli/tags/", serveJSON(`{"count": 1, "results": [
+		{"name": "Nightly", "images": [{"os": "linux", "architecture": "amd64"}]}
+	]}`))
+	if _, err := executeCmd(t, mux, "audit", "platforms", "--require=linux/arm64", "--image=cli", "--tagRegEx=nightly"); !errors.Is(err, ErrPlatformViolations) {
+		t.Errorf("audit platforms with lower case tag expression error = %v, want ErrPlatformViolations", err)
+	}
+}
+
+func TestReport(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v2/repositories/testorg/{$}", serveJSON(`{"count": 2, "results": [{"name": "api", "pull_count": 5}, {"name": "web", "pull_count": 9}]}`))
+	mux.HandleFunc("GET /v2/repositories/testorg/api/tags/", serveJSON(`{"count": 2, "results": [
+		{"name": "1.0.0", "full_size": 300, "tag_status": "active", "tag_last_pushed": "2024-05-01T00:00:00Z"},
+		{"name": "0.9.0", "full_size": 100, "tag_status": "inactive", "tag_last_pushed": "2024-01-01T00:00:00Z"}
+	]}`))
+	mux.HandleFunc("GET /v2/repositories/testorg/web/tags/", serveJSON(`{"count": 1, "results": [
+		{"name": "1.0.0", "full_size": 200, "tag_status": "active", "tag_last_pulled": "2024-06-01T00:00:00Z"}
+	]}`))
+
+	out, err := executeCmd(t, mux, "report", "--top=1", "-o", "json")
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+
+	var report organizationReport
+	if err := json.Unmarshal([]byte(out), &report); err != nil {
+		t.Fatalf("report output isn't JSON: %v\n%s", err, out)
+	}
+	if len(report.Repositories) != 2 || report.Repositories[0].Size != 400 || report.Repositories[0].InactiveSize != 100 {
+		t.Errorf("report repositories = %s", out)
+	}
+	if totals := report.Totals; totals.Tags != 3 || totals.Size != 600 || totals.AvgSize != 200 || totals.PullCount != 14 ||
+		totals.InactiveTags != 1 || totals.LastPull.IsZero() {
+		t.Errorf("report totals = %+v", totals)
+	}
+
+	top := map[string]string{}
+	for _, list := range report.Top {
+		if len(list.Repositories) > 1 {
+			t.Errorf("top %s has %d repositories, want at most 1", list.By, len(list.Repositories))
+		}
+		for _, entry := range list.Repositories {
+			top[list.By] = entry.Repository
+		}
+	}
+	if top["size"] != "api" || top["pull_count"] != "web" || top["inactive_size"] != "api" || top["tags"] != "api" {
+		t.Errorf("report top = %v", top)
+	}
+
+	out, err = executeCmd(t, mux, "report", "-o", "csv")
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if lines := strings.Split(strings.TrimSpace(out), "\n"); len(lines) != 3 || !strings.HasPrefix(lines[1], "api,2,400,200,5,1,100,") {
+		t.Errorf("report csv output =\n%s", out)
+	}
+
+	out, err = executeCmd(t, mux, "report", "-o", "markdown")
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if !strings.HasPrefix(out, "## Repositories of testorg\n\n| repository | tags |") || !strings.Contains(out, "## Top 2 repositories by pull_count") {
+		t.Errorf("report markdown output =\n%s", out)
+	}
+}
+
+func TestListSortFilterLimit(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v2/repositories/testorg/{$}", serveJSON(`{"count": 4, "results": [
+		{"name": "web", "pull_count": 5, "last_updated": "2024-01-01T00:00:00Z"},
+		{"name": "api", "pull_count": 9, "last_updated": "2024-03-01T00:00:00Z"},
+		{"name": "db", "pull_count": 5, "is_private": true, "last_updated": "2024-02-01T00:00:00Z"},
+		{"name": "cache", "pull_count": 1, "last_updated": "2024-04-01T00:00:00Z"}
+	]}`))
+	mux.HandleFunc("GET /v2/repositories/testorg/{repo}/tags/", func(w http.ResponseWriter, r *http.Request) {
+		count := map[string]int{"web": 3, "api": 1, "db": 2, "cache": 0}[r.PathValue("repo")]
+		_, _ = fmt.Fprintf(w, `{"count": %d, "results": [%s]}`, count, strings.TrimSuffix(strings.Repeat(`{"name": "dev"},`, count), ","))
+	})
+
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{want: "api cache db web"},
+		{args: []string{"--sort=pulls"}, want: "api db web cache"},
+		{args: []string{"--sort=pulls", "--reverse"}, want: "cache web db api"},
+		{args: []string{"--sort=tags", "--limit=2"}, want: "web db"},
+		{args: []string{"--sort=updated"}, want: "cache api db web"},
+		{args: []string{"--filter=visibility=public", "--filter=min-tags=1"}, want: "api web"},
+		{args: []string{"--filter=name=^(api|web)$", "--filter=not-updated-since=30d"}, want: "api web"},
+		{args: []string{"--filter=visibility=private"}, want: "db"},
+	}
+
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.args, " "), func(t *testing.T) {
+			out, err := executeCmd(t, mux, append([]string{"list", "--template={{.Name}}"}, tt.args...)...)
+			if err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+			if got := strings.Join(strings.Fields(out), " "); got != tt.want {
+				t.Errorf("list %v = %q, want %q", tt.args, got, tt.want)
+			}
+		})
+	}
+
+	for _, args := range [][]string{{"--sort=stars"}, {"--limit=-1"}, {"--filter=name"}, {"--filter=visibility=internal"}, {"--filter=owner=me"}} {
+		if _, err := executeCmd(t, mux, append([]string{"list"}, args...)...); err == nil {
+			t.Errorf("list %v should fail", args)
+		}
+	}
+}
+
+func TestGetFilterSort(t *testing.T) {
+	now := time.Now().UTC()
+	ago := func(days int) string { return now.AddDate(0, 0, -days).Format(time.RFC3339) }
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v2/repositories/testorg/api/tags/", serveJSON(fmt.Sprintf(`{"count": 4, "results": [
+		{"name": "latest", "tag_status": "active", "full_size": 1048576, "tag_last_pushed": %q, "tag_last_pulled": %q},
+		{"name": "1.1.0", "tag_status": "active", "full_size": 3145728, "tag_last_pushed": %q, "tag_last_pulled": %q},
+		{"name": "1.0.0", "tag_status": "inactive", "full_size": 2097152, "tag_last_pushed": %q},
+		{"name": "dev", "tag_status": "inactive", "full_size": 524288, "tag_last_pushed": %q, "tag_last_pulled": %q}
+	]}`, ago(1), ago(0), ago(10), ago(2), ago(40), ago(90), ago(80))))
+
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{want: "latest 1.1.0 1.0.0 dev"},
+		{args: []string{"--match=^1\\.", "--sort=name"}, want: "1.0.0 1.1.0"},
+		{args: []string{"--status=inactive"}, want: "1.0.0 dev"},
+		{args: []string{"--pushed-before=30d"}, want: "1.0.0 dev"},
+		{args: []string{"--pushed-after=30d", "--pushed-before=5d"}, want: "1.1.0"},
+		{args: []string{"--pushed-after=" + now.AddDate(0, 0, -20).Format(time.DateOnly)}, want: "latest 1.1.0"},
+		{args: []string{"--pulled-before=30d"}, want: "1.0.0 dev"},
+		{args: []string{"--min-size=2MB", "--sort=size"}, want: "1.1.0 1.0.0"},
+		{args: []string{"--sort=pulled"}, want: "latest 1.1.0 dev 1.0.0"},
+		{args: []string{"--sort=pushed", "--limit=2"}, want: "latest 1.1.0"},
+		{args: []string{"--sort=semver", "--versions=<2.0.0"}, want: "1.1.0 1.0.0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.args, " "), func(t *testing.T) {
+			out, err := executeCmd(t, mux, append([]string{"get", "--image=api", "--template={{.Name}}"}, tt.args...)...)
+			if err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+			if got := strings.Join(strings.Fields(out), " "); got != tt.want {
+				t.Errorf("get %v = %q, want %q", tt.args, got, tt.want)
+			}
+		})
+	}
+
+	for _, args := range [][]string{{"--sort=digest"}, {"--status=deleted"}, {"--limit=-1"}, {"--min-size=big"}, {"--pushed-before=soon"},
+		{"--pushed-after=2999-01-01"}, {"--match=("}} {
+		if _, err := executeCmd(t, mux, append([]string{"get", "--image=api"}, args...)...); err == nil {
+			t.Errorf("get %v should fail", args)
+		}
+	}
+}
+
+func TestCreateRepository(t *testing.T) {
+	readme := filepath.Join(t.TempDir(), "README.md")
+	if err := os.WriteFile(readme, []byte("# API\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var created map[string]any
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v2/repositories/{$}", func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&created); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(created)
+	})
+
+	if _, err := executeCmd(t, mux, "create", "--image=api", "--private"); err != nil || created != nil {
+		t.Fatalf("create in dry-run mode = %v, error %v, want no request", created, err)
+	}
+
+	_, err := executeCmd(t, mux, "create", "--image=api", "--private", "--description=API service", "--full-description-file="+readme, "--dry-run=false")
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	want := map[string]any{"namespace": "testorg", "name": "api", "description": "API service", "full_description": "# API\n", "is_private": true}
+	if !reflect.DeepEqual(created, want) {
+		t.Errorf("created repository = %v, want %v", created, want)
+	}
+
+	if _, err := executeCmd(t, mux, "create", "--image=api", "--full-description-file=missing.md"); err == nil {
+		t.Error("create with missing full description file should fail")
+	}
+}
+
+func TestUpdateRepository(t *testing.T) {
+	readme := filepath.Join(t.TempDir(), "README.md")
+	if err := os.WriteFile(readme, []byte("# API\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var requests []string
+	mux := http.NewServeMux()
+	record := func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests = append(requests, r.Method+" "+r.URL.Path+" "+string(body))
+		_, _ = w.Write([]byte(`{"name": "api"}`))
+	}
+	mux.HandleFunc("PATCH /v2/repositories/testorg/api/{$}", record)
+	mux.HandleFunc("POST /v2/repositories/testorg/api/privacy/{$}", record)
+
+	if _, err := executeCmd(t, mux, "update", "--image=api", "--public"); err != nil || len(requests) != 0 {
+		t.Fatalf("update in dry-run mode sent %v, error %v, want no requests", requests, err)
+	}
+
+	_, err := executeCmd(t, mux, "update", "--image=api", "--description=", "--full-description-file="+readme, "--private", "--dry-run=false")
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	want := []string{
+		`PATCH /v2/repositories/testorg/api/ {"description":"","full_description":"# API\n"}`,
+		`POST /v2/repositories/testorg/api/privacy/ {"is_private":true}`,
+	}
+	if !reflect.DeepEqual(requests, want) {
+		t.Errorf("update requests = %q, want %q", requests, want)
+	}
+
+	for _, args := range [][]string{{}, {"--private", "--public"}, {"--full-description-file=missing.md"}} {
+		if _, err := executeCmd(t, mux, append([]string{"update", "--image=api"}, args...)...); err == nil {
+			t.Errorf("update %v should fail", args)
+		}
+	}
+}
+
+func TestSync(t *testing.T) {
+	manifest := filepath.Join(t.TempDir(), "repos.yaml")
+	content := `
+repositories:
+  - name: api
+    visibility: private
+    description: API service
+    teams:
+      backend: write
+    retention:
+      keep_last: 1
+  - name: web
+    visibility: public
+`
+	if err := os.WriteFile(manifest, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var requests []string
+	mux := http.NewServeMux()
+	record := func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests = append(requests, strings.TrimSpace(r.Method+" "+r.URL.Path+" "+string(body)))
+		_, _ = w.Write([]byte(`{"name": "web"}`))
+	}
+	mux.HandleFunc("GET /v2/repositories/testorg/{$}", serveJSON(`{"count": 2, "results": [{"name": "api"}, {"name": "legacy"}]}`))
+	mux.HandleFunc("GET /v2/repositories/testorg/api/{$}", serveJSON(`{"name": "api", "description": "old", "is_private": false}`))
+	mux.HandleFunc("GET /v2/repositories/testorg/api/groups/", serveJSON(`{"count": 1, "results": [{"group_id": 2, "group_name": "qa", "permission": "read"}]}`))
+	mux.HandleFunc("GET /v2/repositories/testorg/api/tags/", serveJSON(`{"count": 2, "results": [
+		{"name": "2.0.0", "full_size": 1048576, "last_updated": "2024-02-01T00:00:00Z"},
+		{"name": "1.0.0", "full_size": 1048576, "last_updated": "2024-01-01T00:00:00Z"}
+	]}`))
+	mux.HandleFunc("GET /v2/orgs/testorg/groups/", serveJSON(`{"count": 2, "results": [{"id": 1, "name": "backend"}, {"id": 2, "name": "qa"}]}`))
+	mux.HandleFunc("POST /v2/repositories/{$}", record)
+	mux.HandleFunc("PATCH /v2/repositories/testorg/api/{$}", record)
+	mux.HandleFunc("POST /v2/repositories/testorg/api/privacy/{$}", record)
+	mux.HandleFunc("POST /v2/repositories/testorg/api/groups/{$}", record)
+	mux.HandleFunc("DELETE /v2/repositories/testorg/api/groups/2/{$}", record)
+	mux.HandleFunc("DELETE /v2/repositories/testorg/api/tags/1.0.0/{$}", record)
+	mux.HandleFunc("DELETE /v2/repositories/testorg/legacy/{$}", record)
+
+	out, err := executeCmd(t, mux, "sync", "--file="+manifest)
+	if err != nil || len(requests) != 0 {
+		t.Fatalf("sync in dry-run mode sent %v, error %v, want no requests", requests, err)
+	}
+	for _, want := range []string{
+		"~ testorg/api will be updated",
+		`    description: "old" -> "API service"`,
+		"    team/backend: (none) -> write",
+		"    team/qa: read -> (none)",
+		"~ testorg/api retention will delete 1 tags (1.00 MB)",
+		"+ testorg/web will be created",
+		"? testorg/legacy isn't in manifest",
+		"Plan: 1 to create, 1 to update, 1 unmanaged, 1 tags to delete by retention",
+	} {
+		if !strings.Contains(out, want+"\n") {
+			t.Errorf("sync output lacks %q:\n%s", want, out)
+		}
+	}
+
+	if _, err := executeCmd(t, mux, "sync", "--file="+manifest, "--prune", "--dry-run=false"); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	want := []string{
+		`PATCH /v2/repositories/testorg/api/ {"description":"API service"}`,
+		`POST /v2/repositories/testorg/api/privacy/ {"is_private":true}`,
+		`POST /v2/repositories/testorg/api/groups/ {"group_id":1,"permission":"write"}`,
+		`DELETE /v2/repositories/testorg/api/groups/2/`,
+		`DELETE /v2/repositories/testorg/api/tags/1.0.0/`,
+		`POST /v2/repositories/ {"namespace":"testorg","name":"web","description":"","full_description":"","is_private":false}`,
+		`DELETE /v2/repositories/testorg/legacy/`,
+	}
+	if !reflect.DeepEqual(requests, want) {
+		t.Errorf("sync requests:\n%s\nwant:\n%s", strings.Join(requests, "\n"), strings.Join(want, "\n"))
+	}
+
+	collaborators := filepath.Join(t.TempDir(), "collaborators.yaml")
+	if err := os.WriteFile(collaborators, []byte("repositories:\n  - name: api\n    collaborators: {alice: write}\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	mux.HandleFunc("GET /v2/repositories/testorg/api/collaborators/", serveJSON(`{"count": 1, "results": [{"user": "mallory", "permission": "admin"}]}`))
+	mux.HandleFunc("POST /v2/repositories/testorg/api/collaborators/{$}", record)
+	mux.HandleFunc("DELETE /v2/repositories/testorg/api/collaborators/mallory/{$}", record)
+	requests = nil
+	if _, err := executeCmd(t, mux, "sync", "--file="+collaborators, "--dry-run=false"); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	want = []string{
+		`POST /v2/repositories/testorg/api/collaborators/ {"user":"alice","permission":"write"}`,
+		`DELETE /v2/repositories/testorg/api/collaborators/mallory/`,
+	}
+	if !reflect.DeepEqual(requests, want) {
+		t.Errorf("sync collaborator requests = %q, want %q", requests, want)
+	}
+
+	if _, err := executeCmd(t, mux, "sync", "--file=missing.yaml"); err == nil {
+		t.Error("sync with missing manifest should fail")
+	}
+	other := filepath.Join(t.TempDir(), "other.yaml")
+	if err := os.WriteFile(other, []byte("organization: other\nrepositories: []\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := executeCmd(t, mux, "sync", "--file="+other); err == nil {
+		t.Error("sync of manifest for other organization than '--org' should fail")
+	}
+	empty := filepath.Join(t.TempDir(), "empty.yaml")
+	if err := os.WriteFile(empty, []byte("repositories: []\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	requests = nil
+	if _, err := executeCmd(t, mux, "sync", "--file="+empty, "--prune", "--dry-run=false"); err == nil || len(requests) != 0 {
+		t.Errorf("prune by empty manifest sent %v, error %v, want error and no requests", requests, err)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	manifest := filepath.Join(t.TempDir(), "repos.yaml")
+	content := `
+repositories:
+  - name: api
+    visibility: private
+    description: API service
+    collaborators:
+      alice: write
+  - name: web
+    visibility: public
+`
+	if err := os.WriteFile(manifest, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v2/repositories/testorg/{$}", serveJSON(`{"count": 3, "results": [{"name": "api"}, {"name": "web"}, {"name": "legacy"}]}`))
+	mux.HandleFunc("GET /v2/repositories/testorg/api/{$}", serveJSON(`{"name": "api", "description": "API service", "is_private": false}`))
+	mux.HandleFunc("GET /v2/repositories/testorg/api/collaborators/", serveJSON(`{"count": 2, "results": [
+		{"user": "alice", "permission": "write"}, {"user": "mallory", "permission": "admin"}
+	]}`))
+	mux.HandleFunc("GET /v2/repositories/testorg/web/{$}", serveJSON(`{"name": "web", "is_private": false}`))
+
+	out, err := executeCmd(t, mux, "diff", "--file="+manifest)
+	if !errors.Is(err, ErrDrift) {
+		t.Fatalf("diff error = %v, want ErrDrift", err)
+	}
+	for _, want := range []string{
+		"~ testorg/api drifted (live -> manifest)",
+		"    visibility: public -> private",
+		"    collaborator/mallory: admin -> (none)",
+		"? testorg/legacy isn't in manifest",
+		"Drift: 0 missing, 1 drifted of 2 repositories, 1 unmanaged",
+	} {
+		if !strings.Contains(out, want+"\n") {
+			t.Errorf("diff output lacks %q:\n%s", want, out)
+		}
+	}
+
+	out, _ = executeCmd(t, mux, "diff", "--file="+manifest, "-o", "json")
+	var diffs []*dockerhub.RepositoryDiff
+	if err := json.Unmarshal([]byte(out), &diffs); err != nil {
+		t.Fatalf("diff output isn't JSON: %v\n%s", err, out)
+	}
+	if len(diffs) != 2 || diffs[0].Repository != "api" || len(diffs[0].Changes) != 2 || diffs[1].Action != dockerhub.ActionUnmanaged {
+		t.Errorf("diff output = %s", out)
+	}
+
+	out, _ = executeCmd(t, mux, "diff", "--file="+manifest, "-o", "csv")
+	if !strings.Contains(out, "api,update,visibility,public,private\n") || !strings.Contains(out, "legacy,unmanaged,,,\n") {
+		t.Errorf("diff CSV output = %s", out)
+	}
+
+	// unmanaged repositories alone aren't drift
+	inSync := filepath.Join(t.TempDir(), "repos.yaml")
+	if err := os.WriteFile(inSync, []byte("repositories:\n  - name: web\n    visibility: public\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if out, err := executeCmd(t, mux, "diff", "--file="+inSync); err != nil || !strings.Contains(out, "All 1 repositories match manifest, 2 unmanaged") {
+		t.Errorf("diff of repositories matching manifest = %s, error %v", out, err)
+	}
+}
+
+func TestTeam(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v2/orgs/testorg/groups/", serveJSON(`{"count": 1, "results": [{"id": 1, "name": "backend", "member_count": 2}]}`))
+	mux.HandleFunc("GET /v2/orgs/testorg/members/", serveJSON(`{"count": 2, "results": [{"username": "alice"}, {"username": "bob"}]}`))
+	mux.HandleFunc("GET /v2/orgs/testorg/groups/backend/members/", serveJSON(`{"count": 1, "results": [{"username": "bob", "full_name": "Bob"}]}`))
+
+	out, err := executeCmd(t, mux, "team", "list", "-o", "csv")
+	if err != nil || out != "name,members,description,id\nbackend,2,,1\n" {
+		t.Errorf("team list = %q, error %v", out, err)
+	}
+	out, err = executeCmd(t, mux, "team", "members", "-o", "jsonpath={.username} ")
+	if err != nil || out != "alice \nbob \n" {
+		t.Errorf("team members = %q, error %v", out, err)
+	}
+	out, err = executeCmd(t, mux, "team", "members", "--team=backend", "-o", "csv")
+	if err != nil || out != "username,full_name,id\nbob,Bob,\n" {
+		t.Errorf("team members --team = %q, error %v", out, err)
+	}
+}
+
+func TestPermission(t *testing.T) {
+	var mu sync.Mutex
+	var requests []string
+	mux := http.NewServeMux()
+	record := func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		requests = append(requests, strings.TrimSpace(r.Method+" "+r.URL.Path+" "+string(body)))
+		mu.Unlock()
+		_, _ = w.Write([]byte(`{}`))
+	}
+	mux.HandleFunc("GET /v2/repositories/testorg/{$}", serveJSON(`{"count": 2, "results": [{"name": "api"}, {"name": "web"}]}`))
+	mux.HandleFunc("GET /v2/orgs/testorg/groups/", serveJSON(`{"count": 1, "results": [{"id": 1, "name": "backend"}]}`))
+	mux.HandleFunc("GET /v2/repositories/testorg/api/groups/", serveJSON(`{"count": 1, "results": [
+		{"group_id": 1, "group_name": "backend", "permission": "read"}
+	]}`))
+	mux.HandleFunc("GET /v2/repositories/testorg/web/groups/", serveJSON(`{"count": 0, "results": []}`))
+	mux.HandleFunc("GET /v2/repositories/testorg/api/collaborators/", serveJSON(`{"count": 1, "results": [{"user": "alice", "permission": "write"}]}`))
+	mux.HandleFunc("GET /v2/repositories/testorg/web/collaborators/", serveJSON(`{"count": 2, "results": [
+		{"user": "alice", "permission": "admin"}, {"user": "bob", "permission": "read"}
+	]}`))
+	mux.HandleFunc("PUT /v2/repositories/testorg/{repo}/groups/{id}/{$}", record)
+	mux.HandleFunc("POST /v2/repositories/testorg/{repo}/groups/{$}", record)
+	mux.HandleFunc("DELETE /v2/repositories/testorg/{repo}/collaborators/{user}/{$}", record)
+
+	out, err := executeCmd(t, mux, "permission", "list", "--image=api", "-o", "csv")
+	if err != nil || out != "repository,holder,name,permission\napi,team,backend,read\napi,user,alice,write\n" {
+		t.Errorf("permission list = %q, error %v", out, err)
+	}
+	out, err = executeCmd(t, mux, "permission", "list", "--all", "--user=alice", "-o", "csv")
+	if err != nil || out != "repository,holder,name,permission\napi,user,alice,write\nweb,user,alice,admin\n" {
+		t.Errorf("permission list --user = %q, error %v", out, err)
+	}
+
+	if _, err := executeCmd(t, mux, "permission", "revoke", "--all", "--user=alice"); err != nil || len(requests) != 0 {
+		t.Fatalf("revoke in dry-run mode sent %v, error %v, want no requests", requests, err)
+	}
+	if _, err := executeCmd(t, mux, "permission", "revoke", "--all", "--user=alice", "--dry-run=false"); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	slices.Sort(requests)
+	want := []string{"DELETE /v2/repositories/testorg/api/collaborators/alice/", "DELETE /v2/repositories/testorg/web/collaborators/alice/"}
+	if !reflect.DeepEqual(requests, want) {
+		t.Errorf("revoke requests = %q, want %q", requests, want)
+	}
+
+	requests = nil
+	if _, err := executeCmd(t, mux, "permission", "grant", "--all", "--team=backend", "--permission=read", "--dry-run=false"); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	// backend team already has read permission on api
+	want = []string{`POST /v2/repositories/testorg/web/groups/ {"group_id":1,"permission":"read"}`}
+	if !reflect.DeepEqual(requests, want) {
+		t.Errorf("grant requests = %q, want %q", requests, want)
+	}
+
+	for _, args := range [][]string{
+		{"grant", "--image=api", "--team=backend", "--permission=owner"},
+		{"grant", "--image=api", "--team=missing", "--permission=read"},
+		{"revoke", "--image=api"},
+		{"revoke", "--image=api", "--all", "--user=alice"},
+		{"revoke", "--image=api", "--team=backend", "--user=alice"},
+	} {
+		if _, err := executeCmd(t, mux, append([]string{"permission"}, args...)...); err == nil {
+			t.Errorf("permission %v should fail", args)
+		}
+	}
+}
+
+func TestVerboseRetries(t *testing.T) {
+	colorOutput := color.Output
+	t.Cleanup(func() { color.Output = colorOutput })
+	var out bytes.Buffer
+	color.Output = &out
+
+	var listed int
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v2/repositories/testorg/api/tags/", func(w http.ResponseWriter, r *http.Request) {
+		if listed++; listed == 1 {
+			w.Header().Set("Retry-After", "0")
+			http.Error(w, `{"detail": "too many requests"}`, http.StatusTooManyRequests)
+			return
+		}
+		_, _ = w.Write([]byte(`{"count": 1, "results": [{"name": "dev-1"}]}`))
+	})
+	mux.HandleFunc("DELETE /v2/repositories/testorg/api/tags/dev-1/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	if _, err := executeCmd(t, mux, "truncate", "--image=api", "--tagRegEx=dev", "--dry-run=false", "--verbose"); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if !strings.Contains(out.String(), "Retried 1 rate limited or failed requests") {
+		t.Errorf("truncate --verbose output lacks retries count:\n%s", out.String())
+	}
 }
@@ -0,0 +1,288 @@
+/*
+Copyright © 2020 Yevhen Lebid ealebed@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dockerhub
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+var (
+	// ErrInvalidCredentials is returned when docker hub rejects username, password or access token
+	ErrInvalidCredentials = errors.New("invalid docker hub credentials")
+	// ErrExpiredToken is returned when docker hub access token is expired or revoked
+	ErrExpiredToken = errors.New("docker hub access token is expired or revoked")
+	// ErrTwoFactorRequired is returned when account requires two-factor authentication code, but client can't get it
+	ErrTwoFactorRequired = errors.New("docker hub account requires two-factor authentication code")
+)
+
+// Credentials represents docker hub login with password, personal access token or organization access token
+type Credentials struct {
+	Username string
+	Password string
+	// Token is personal or organization access token, used instead of password
+	Token string
+}
+
+// CredentialsFromEnv returns credentials from DOCKERHUB_USERNAME, DOCKERHUB_PASSWORD and DOCKERHUB_TOKEN environment variables
+func CredentialsFromEnv() *Credentials {
+	return &Credentials{
+		Username: os.Getenv("DOCKERHUB_USERNAME"),
+		Password: os.Getenv("DOCKERHUB_PASSWORD"),
+		Token:    os.Getenv("DOCKERHUB_TOKEN"),
+	}
+}
+
+// GetAuthToken returns JWT Token from docker hub login page
+/* curl --silent \
+   -H "Content-Type: application/json" \
+   -X POST \
+   -d '{"username": "'${DOCKERHUB_USERNAME}'", "password": "'${DOCKERHUB_PASSWORD}'"}' \
+   https://hub.docker.com/v2/users/login/ | jq -r .token
+*/
+func (c *Client) GetAuthToken() (string, error) {
+	return c.GetAuthTokenContext(context.Background())
+}
+
+// GetAuthTokenContext returns JWT Token, exchanging access token or logging in with password, using provided context
+/* curl --silent \
+   -H "Content-Type: application/json" \
+   -X POST \
+   -d '{"identifier": "'${DOCKERHUB_USERNAME}'", "secret": "'${DOCKERHUB_TOKEN}'"}' \
+   https://hub.docker.com/v2/auth/token | jq -r .access_token
+*/
+func (c *Client) GetAuthTokenContext(ctx context.Context) (string, error) {
+	c.authMu.Lock()
+	defer c.authMu.Unlock()
+
+	return c.authenticate(ctx, true)
+}
+
+// token returns JWT Token for docker hub requests: static AuthToken, or one obtained by client, renewed before it expires
+func (c *Client) token(ctx context.Context) (string, error) {
+	c.authMu.Lock()
+	defer c.authMu.Unlock()
+
+	if c.AuthToken != "" && (c.AuthToken != c.issuedToken || !tokenExpired(c.tokenExpiry, time.Now())) {
+		return c.AuthToken, nil
+	}
+
+	return c.authenticate(ctx, true)
+}
+
+// refreshToken obtains new JWT Token instead of one rejected by docker hub, reporting whether request can be retried with it,
+// concurrent requests rejected with the same JWT Token share single login, static AuthToken is never refreshed
+func (c *Client) refreshToken(ctx context.Context, rejected string) (bool, error) {
+	c.authMu.Lock()
+	defer c.authMu.Unlock()
+
+	if c.AuthToken != rejected {
+		// already refreshed by concurrent request
+		return c.AuthToken != "", nil
+	}
+	if rejected != c.issuedToken {
+		return false, nil
+	}
+
+	if _, err := c.authenticate(ctx, false); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// authenticate obtains JWT Token from token cache (when allowed) or docker hub, callers should hold authMu
+func (c *Client) authenticate(ctx context.Context, cached bool) (string, error) {
+	credentials := c.Credentials
+	if credentials == nil {
+		var err error
+		if credentials, err = ResolveCredentials(ctx); err != nil {
+			return "", err
+		}
+	}
+
+	key := tokenCacheKey(c.URL, credentials)
+	if c.TokenCache != nil {
+		if !cached {
+			_ = c.TokenCache.Delete(key)
+		} else if token, expiresAt, ok := c.TokenCache.Load(key); ok {
+			c.setToken(token, expiresAt)
+			return token, nil
+		}
+	}
+
+	var token string
+	var err error
+	if credentials.Token != "" {
+		token, err = c.exchangeAccessToken(ctx, credentials)
+	} else {
+		token, err = c.login(ctx, credentials)
+	}
+	if err != nil {
+		color.Red("failed to log into the registry")
+		return "", err
+	}
+
+	expiresAt, _ := TokenExpiry(token)
+	c.setToken(token, expiresAt)
+
+	// tokens without expiry aren't cached, as there is no way to know when they should be renewed
+	if c.TokenCache != nil && !expiresAt.IsZero() {
+		if err := c.TokenCache.Store(key, token, expiresAt); err != nil {
+			color.Yellow("Warning: failed to cache docker hub token: %v", err)
+		}
+	}
+
+	return token, nil
+}
+
+func (c *Client) setToken(token string, expiresAt time.Time) {
+	c.AuthToken = token
+	c.issuedToken = token
+	c.tokenExpiry = expiresAt
+}
+
+// exchangeAccessToken returns JWT Token for personal or organization access token
+func (c *Client) exchangeAccessToken(ctx context.Context, credentials *Credentials) (string, error) {
+	identifier := credentials.Username
+	if identifier == "" {
+		// organization access tokens are identified by organization name
+		identifier = c.ORG
+	}
+
+	resp, err := c.postAuth(ctx, "auth/token", map[string]string{"identifier": identifier, "secret": credentials.Token})
+	if err != nil {
+		return "", err
+	}
+	if resp.AccessToken == "" {
+		return "", fmt.Errorf("empty token received")
+	}
+
+	return resp.AccessToken, nil
+}
+
+// login returns JWT Token for username and password, requesting two-factor authentication code when account requires it
+func (c *Client) login(ctx context.Context, credentials *Credentials) (string, error) {
+	resp, err := c.postAuth(ctx, "users/login", map[string]string{"username": credentials.Username, "password": credentials.Password})
+	if err != nil && resp != nil && resp.Login2FAToken != "" {
+		return c.loginTwoFactor(ctx, resp.Login2FAToken)
+	}
+	if err != nil {
+		return "", err
+	}
+	if resp.Token == "" {
+		return "", fmt.Errorf("empty token received")
+	}
+
+	return resp.Token, nil
+}
+
+// loginTwoFactor completes login into account with two-factor authentication enabled
+func (c *Client) loginTwoFactor(ctx context.Context, login2FAToken string) (string, error) {
+	if c.TwoFactorCode == nil {
+		return "", ErrTwoFactorRequired
+	}
+
+	code, err := c.TwoFactorCode(ctx)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrTwoFactorRequired, err)
+	}
+
+	resp, err := c.postAuth(ctx, "users/2fa-login", map[string]string{"login_2fa_token": login2FAToken, "code": strings.TrimSpace(code)})
+	if err != nil {
+		return "", err
+	}
+	if resp.Token == "" {
+		return "", fmt.Errorf("empty token received")
+	}
+
+	return resp.Token, nil
+}
+
+// postAuth posts JSON payload to docker hub authentication endpoint, decoding its response also on failure
+func (c *Client) postAuth(ctx context.Context, path string, payload map[string]string) (*AuthResponse, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.apiURL("%s", path), bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if closeErr := resp.Body.Close(); closeErr != nil {
+			color.Yellow("Warning: failed to close response body: %v", closeErr)
+		}
+	}()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	auth := &AuthResponse{}
+	if len(body) > 0 {
+		if err := json.Unmarshal(body, auth); err != nil && resp.StatusCode == http.StatusOK {
+			return nil, err
+		}
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusOK:
+		return auth, nil
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return auth, authError(auth)
+	}
+
+	return auth, &HTTPError{Method: req.Method, URL: req.URL.String(), StatusCode: resp.StatusCode, Body: string(body)}
+}
+
+// authError distinguishes expired access tokens from invalid credentials by docker hub response details
+func authError(auth *AuthResponse) error {
+	detail := auth.Detail
+	if detail == "" {
+		detail = auth.Message
+	}
+	if auth.Login2FAToken != "" {
+		return fmt.Errorf("%w: %s", ErrTwoFactorRequired, detail)
+	}
+	if strings.Contains(strings.ToLower(detail), "expired") || strings.Contains(strings.ToLower(detail), "revoked") {
+		return fmt.Errorf("%w: %s", ErrExpiredToken, detail)
+	}
+	if detail == "" {
+		return ErrInvalidCredentials
+	}
+
+	return fmt.Errorf("%w: %s", ErrInvalidCredentials, detail)
+}
@@ -0,0 +1,171 @@
+/*
+Copyright © 2020 Yevhen Lebid ealebed@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dockerhub
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// authServer responds to docker hub authentication endpoints, recording decoded request payloads
+func authServer(t *testing.T, handle func(path string, payload map[string]string) (int, string)) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		payload := map[string]string{}
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Errorf("failed to decode request payload: %v", err)
+		}
+		status, body := handle(r.URL.Path, payload)
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestGetAuthTokenAccessToken(t *testing.T) {
+	server := authServer(t, func(path string, payload map[string]string) (int, string) {
+		if path != "/v2/auth/token" {
+			return http.StatusNotFound, ""
+		}
+		if payload["identifier"] != "testorg" || payload["secret"] != "dckr_oat_secret" {
+			return http.StatusUnauthorized, `{"detail": "Incorrect authentication credentials"}`
+		}
+		return http.StatusOK, `{"access_token": "jwt-from-token"}`
+	})
+
+	client := NewClient("testorg", server.URL)
+	client.Credentials = &Credentials{Token: "dckr_oat_secret"}
+
+	token, err := client.GetAuthTokenContext(context.Background())
+	if err != nil {
+		t.Fatalf("GetAuthToken() error = %v", err)
+	}
+	if token != "jwt-from-token" || client.AuthToken != token {
+		t.Errorf("GetAuthToken() = %v, AuthToken = %v, want jwt-from-token", token, client.AuthToken)
+	}
+}
+
+func TestGetAuthTokenErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		credentials *Credentials
+		status      int
+		body        string
+		wantErr     error
+	}{
+		{
+			name:        "bad password",
+			credentials: &Credentials{Username: "user", Password: "wrong"},
+			status:      http.StatusUnauthorized,
+			body:        `{"detail": "Incorrect authentication credentials"}`,
+			wantErr:     ErrInvalidCredentials,
+		},
+		{
+			name:        "unknown token",
+			credentials: &Credentials{Username: "user", Token: "dckr_pat_unknown"},
+			status:      http.StatusUnauthorized,
+			body:        `{"message": "invalid credentials"}`,
+			wantErr:     ErrInvalidCredentials,
+		},
+		{
+			name:        "expired token",
+			credentials: &Credentials{Username: "user", Token: "dckr_pat_expired"},
+			status:      http.StatusUnauthorized,
+			body:        `{"detail": "Token has expired"}`,
+			wantErr:     ErrExpiredToken,
+		},
+		{
+			name:        "two-factor authentication without prompt",
+			credentials: &Credentials{Username: "user", Password: "secret"},
+			status:      http.StatusUnauthorized,
+			body:        `{"detail": "Require secondary authentication on MFA enabled account", "login_2fa_token": "2fa"}`,
+			wantErr:     ErrTwoFactorRequired,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := authServer(t, func(string, map[string]string) (int, string) {
+				return tt.status, tt.body
+			})
+
+			client := NewClient("testorg", server.URL)
+			client.Credentials = tt.credentials
+
+			_, err := client.GetAuthTokenContext(context.Background())
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("GetAuthToken() error = %v, want %v", err, tt.wantErr)
+			}
+			if errors.Is(err, ErrInvalidCredentials) && errors.Is(err, ErrExpiredToken) {
+				t.Errorf("GetAuthToken() error = %v should be either invalid credentials or expired token", err)
+			}
+		})
+	}
+}
+
+func TestGetAuthTokenTwoFactor(t *testing.T) {
+	server := authServer(t, func(path string, payload map[string]string) (int, string) {
+		switch path {
+		case "/v2/users/login":
+			return http.StatusUnauthorized, `{"detail": "Require secondary authentication on MFA enabled account", "login_2fa_token": "2fa"}`
+		case "/v2/users/2fa-login":
+			if payload["login_2fa_token"] != "2fa" || payload["code"] != "123456" {
+				return http.StatusUnauthorized, `{"detail": "Incorrect authentication code"}`
+			}
+			return http.StatusOK, `{"token": "jwt-after-2fa"}`
+		}
+		return http.StatusNotFound, ""
+	})
+
+	client := NewClient("testorg", server.URL)
+	client.Credentials = &Credentials{Username: "user", Password: "secret"}
+	client.TwoFactorCode = func(context.Context) (string, error) {
+		return "123456\n", nil
+	}
+
+	token, err := client.GetAuthTokenContext(context.Background())
+	if err != nil {
+		t.Fatalf("GetAuthToken() error = %v", err)
+	}
+	if token != "jwt-after-2fa" {
+		t.Errorf("GetAuthToken() = %v, want jwt-after-2fa", token)
+	}
+
+	client.TwoFactorCode = func(context.Context) (string, error) {
+		return "000000", nil
+	}
+	if _, err := client.GetAuthTokenContext(context.Background()); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("GetAuthToken() with wrong code error = %v, want ErrInvalidCredentials", err)
+	}
+}
+
+func TestCredentialsFromEnv(t *testing.T) {
+	t.Setenv("DOCKERHUB_USERNAME", "user")
+	t.Setenv("DOCKERHUB_PASSWORD", "secret")
+	t.Setenv("DOCKERHUB_TOKEN", "dckr_pat_token")
+
+	credentials := CredentialsFromEnv()
+	if credentials.Username != "user" || credentials.Password != "secret" || credentials.Token != "dckr_pat_token" {
+		t.Errorf("CredentialsFromEnv() = %+v", credentials)
+	}
+}
@@ -18,26 +18,44 @@ package dockerhub
 
 import (
 	"bytes"
-	"encoding/json"
+	"context"
 	"fmt"
 	"io"
 	"net/http"
-	"os"
+	"regexp"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/fatih/color"
 	"github.com/spf13/pflag"
 )
 
+// DefaultURL represents Docker Hub address, used by clients without custom one
+const DefaultURL = "https://hub.docker.com"
+
 // BaseURL represents Docker Hub endpoint
-const BaseURL = "https://hub.docker.com/v2/"
+//
+// Deprecated: BaseURL ignores custom client URL ('--hub-url'), client methods build endpoints from Client.URL instead.
+const BaseURL = DefaultURL + "/v2/"
 
 // RepositoriesURL represents Docker Hub repositories endpoint
+//
+// Deprecated: RepositoriesURL ignores custom client URL ('--hub-url'), client methods build endpoints from Client.URL instead.
 var RepositoriesURL = BaseURL + "repositories"
 
+// DefaultProtectedTags represents docker image tags, which are never deleted
+var DefaultProtectedTags = []string{"latest"}
+
 // AuthResponse represents auth response
 type AuthResponse struct {
 	Token string `json:"token"`
+	// AccessToken is returned on access token exchange
+	AccessToken string `json:"access_token,omitempty"`
+	// Login2FAToken is returned on login into account with two-factor authentication enabled
+	Login2FAToken string `json:"login_2fa_token,omitempty"`
+	Detail        string `json:"detail,omitempty"`
+	Message       string `json:"message,omitempty"`
 }
 
 // Client represents new HTTP client
@@ -47,6 +65,40 @@ type Client struct {
 	AuthToken string
 	URL       string
 	ORG       string
+	// Protect lists regular expressions of docker image tags (and tags sharing digest with them), which are never deleted
+	Protect []string
+	// Progress counts deletions completed, failed and skipped by client
+	Progress *Progress
+	// Retry retries rate limited and failed requests, requests are made once when nil
+	Retry *RetryPolicy
+	// Credentials are used for login, resolved from environment variables and docker config when nil
+	Credentials *Credentials
+	// TwoFactorCode returns two-factor authentication code, when account requires it
+	TwoFactorCode func(ctx context.Context) (string, error)
+	// TokenCache keeps JWT Tokens between runs, tokens are cached in memory only when nil
+	TokenCache *TokenCache
+
+	// authMu guards AuthToken, obtained JWT Token and its expiry, shared by concurrent requests
+	authMu      sync.Mutex
+	issuedToken string
+	tokenExpiry time.Time
+
+	// protectMu guards Protect patterns compiled once for all deletions, and Protect they were compiled from
+	protectMu     sync.Mutex
+	protected     []*regexp.Regexp
+	protectSource []string
+}
+
+// HTTPError represents unsuccessful docker hub response
+type HTTPError struct {
+	Method     string
+	URL        string
+	StatusCode int
+	Body       string
+}
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("HTTP %d: %s", e.StatusCode, e.Body)
 }
 
 // GetFlags returns variables from provided commandline flags
@@ -70,91 +122,94 @@ func NewClient(org, url string) *Client {
 		Timeout: time.Second * 30,
 	}
 	if url == "" {
-		url = "https://hub.docker.com"
+		url = DefaultURL
 	}
 
 	h := http.Header{}
 	h.Set("Content-Type", "application/json")
 
 	return &Client{
-		Client: c,
-		Header: h,
-		URL:    url,
-		ORG:    org,
+		Client:   c,
+		Header:   h,
+		URL:      url,
+		ORG:      org,
+		Protect:  append([]string{}, DefaultProtectedTags...),
+		Progress: &Progress{},
+		Retry:    NewRetryPolicy(),
 	}
 }
 
-// GetAuthToken returns JWT Token from docker hub login page
-/* curl --silent \
-   -H "Content-Type: application/json" \
-   -X POST \
-   -d '{"username": "'${DOCKERHUB_USERNAME}'", "password": "'${DOCKERHUB_PASSWORD}'"}' \
-   https://hub.docker.com/v2/users/login/ | jq -r .token
-*/
-func (c *Client) GetAuthToken() (string, error) {
-	payload := fmt.Sprintf(`{"username": %q, "password": %q}`, os.Getenv("DOCKERHUB_USERNAME"), os.Getenv("DOCKERHUB_PASSWORD"))
-
-	req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("%s/users/login", BaseURL), bytes.NewBuffer([]byte(payload)))
-	if err != nil {
-		return "", err
+// apiURL returns URL of docker hub API v2 endpoint, relative to client base URL
+func (c *Client) apiURL(format string, args ...any) string {
+	base := strings.TrimSuffix(c.URL, "/")
+	if base == "" {
+		base = DefaultURL
 	}
-	req.Header.Set("Content-Type", "application/json")
-
-	// URL is fixed (BaseURL + path), not user-controlled
-	resp, err := c.Do(req) // #nosec G704
-	if err != nil {
-		return "", err
-	}
-	defer func() {
-		if closeErr := resp.Body.Close(); closeErr != nil {
-			color.Yellow("Warning: failed to close response body: %v", closeErr)
-		}
-	}()
-
-	accessToken := &AuthResponse{}
-	if err := json.NewDecoder(resp.Body).Decode(accessToken); err != nil {
-		return "", err
+	if !strings.HasSuffix(base, "/v2") {
+		base += "/v2"
 	}
 
-	c.AuthToken = accessToken.Token
-	if accessToken.Token == "" {
-		color.Red("failed to log into the registry")
-		return "", fmt.Errorf("empty token received")
-	}
-
-	return accessToken.Token, nil
+	return base + "/" + fmt.Sprintf(format, args...)
 }
 
 // NewRequest prepare request to docker hub
 func (c *Client) NewRequest(method, url string, payload io.Reader) (*http.Request, error) {
-	if c.AuthToken == "" {
-		token, err := c.GetAuthToken()
-		if err != nil {
-			return nil, err
-		}
-		c.AuthToken = token
+	return c.NewRequestContext(context.Background(), method, url, payload)
+}
+
+// NewRequestContext prepare request to docker hub, bound to provided context
+func (c *Client) NewRequestContext(ctx context.Context, method, url string, payload io.Reader) (*http.Request, error) {
+	token, err := c.token(ctx)
+	if err != nil {
+		return nil, err
 	}
 
-	req, err := http.NewRequest(method, url, payload)
+	req, err := http.NewRequestWithContext(ctx, method, url, payload)
 	if err != nil {
 		return nil, err
 	}
-	req.Header.Set("Authorization", fmt.Sprintf("JWT %s", c.AuthToken))
+	req.Header.Set("Authorization", fmt.Sprintf("JWT %s", token))
 
 	return req, nil
 }
 
-func (c *Client) doRequest(method, url string, _ io.Reader) (data []byte, err error) {
-	request, err := c.NewRequest(method, url, nil)
+func (c *Client) doRequest(ctx context.Context, method, url string, payload io.Reader) (data []byte, err error) {
+	// payload is buffered, so request body can be sent again on retry and after JWT Token refresh
+	var requestBody []byte
+	if payload != nil {
+		if requestBody, err = io.ReadAll(payload); err != nil {
+			return nil, fmt.Errorf("failed to read request body: %w", err)
+		}
+	}
+
+	request, err := c.newBodyRequest(ctx, method, url, requestBody)
 	if err != nil {
 		return nil, err
 	}
 
-	response, err := c.Do(request)
+	response, err := c.do(request)
 	if err != nil {
 		return nil, err
 	}
 
+	if response.StatusCode == http.StatusUnauthorized {
+		// JWT Token may expire or be revoked during long running jobs, so log in again and retry once
+		refreshed, err := c.refreshToken(ctx, strings.TrimPrefix(request.Header.Get("Authorization"), "JWT "))
+		if err != nil {
+			drainBody(response)
+			return nil, err
+		}
+		if refreshed {
+			drainBody(response)
+			if request, err = c.newBodyRequest(ctx, method, url, requestBody); err != nil {
+				return nil, err
+			}
+			if response, err = c.do(request); err != nil {
+				return nil, err
+			}
+		}
+	}
+
 	body, err := io.ReadAll(response.Body)
 	if err != nil {
 		if closeErr := response.Body.Close(); closeErr != nil {
@@ -169,10 +224,38 @@ func (c *Client) doRequest(method, url string, _ io.Reader) (data []byte, err er
 		}
 	}()
 
-	if (method == http.MethodGet) && (response.StatusCode != http.StatusOK) {
+	if response.StatusCode < http.StatusOK || response.StatusCode >= http.StatusMultipleChoices {
 		color.Red("HTTP error!\nURL: %s\nstatus code: %d\nbody:\n%s\n", url, response.StatusCode, string(body))
-		return nil, fmt.Errorf("HTTP %d: %s", response.StatusCode, string(body))
+		return nil, &HTTPError{Method: method, URL: url, StatusCode: response.StatusCode, Body: string(body)}
 	}
 
 	return body, nil
 }
+
+// newBodyRequest prepares request to docker hub with JSON body, which can be read again through GetBody, or without body when it's nil
+func (c *Client) newBodyRequest(ctx context.Context, method, url string, body []byte) (*http.Request, error) {
+	var payload io.Reader
+	if body != nil {
+		payload = bytes.NewReader(body)
+	}
+
+	request, err := c.NewRequestContext(ctx, method, url, payload)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		request.Header.Set("Content-Type", "application/json")
+	}
+
+	return request, nil
+}
+
+// do sends request to docker hub, retrying it according to client retry policy
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+	if c.Retry == nil {
+		// request URL is built by client from docker hub base URL
+		return c.Do(req) // #nosec G704
+	}
+
+	return c.Retry.Do(c.Client, req)
+}
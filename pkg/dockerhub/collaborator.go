@@ -0,0 +1,87 @@
+/*
+Copyright © 2020 Yevhen Lebid ealebed@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dockerhub
+
+import (
+	"context"
+	"net/http"
+)
+
+// Collaborator represents individual user permission on docker repository returned from hub.docker.com
+type Collaborator struct {
+	User       string `json:"user"`
+	Permission string `json:"permission"`
+}
+
+// ListCollaborators returns individual collaborators of docker repository from docker hub
+func (c *Client) ListCollaborators(image string) ([]*Collaborator, error) {
+	return c.ListCollaboratorsContext(context.Background(), image)
+}
+
+// ListCollaboratorsContext returns individual collaborators of docker repository from docker hub, using provided context
+func (c *Client) ListCollaboratorsContext(ctx context.Context, image string) ([]*Collaborator, error) {
+	return listPages[*Collaborator](ctx, c, c.apiURL("repositories/%s/%s/collaborators/?page_size=100", c.ORG, image))
+}
+
+// SetCollaboratorPermission grants user permission on docker repository, replacing permission user already has
+/* curl \
+   -H "Authorization: JWT ${TOKEN}" \
+   -H "Content-Type: application/json" \
+   -X POST \
+   -d '{"user": "${USER}", "permission": "write"}' \
+   https://hub.docker.com/v2/repositories/${ORG}/${IMAGE}/collaborators/
+*/
+func (c *Client) SetCollaboratorPermission(image, user, permission string) error {
+	return c.SetCollaboratorPermissionContext(context.Background(), image, user, permission)
+}
+
+// SetCollaboratorPermissionContext grants user permission on docker repository, using provided context,
+// existing permission of user is changed in place
+func (c *Client) SetCollaboratorPermissionContext(ctx context.Context, image, user, permission string) error {
+	if err := ValidatePermission(permission); err != nil {
+		return err
+	}
+
+	collaborators, err := c.ListCollaboratorsContext(ctx, image)
+	if err != nil {
+		return err
+	}
+	for _, collaborator := range collaborators {
+		if collaborator.User == user {
+			return c.sendJSON(ctx, http.MethodPut, c.apiURL("repositories/%s/%s/collaborators/%s/", c.ORG, image, user),
+				map[string]string{"permission": permission})
+		}
+	}
+
+	return c.sendJSON(ctx, http.MethodPost, c.apiURL("repositories/%s/%s/collaborators/", c.ORG, image),
+		&Collaborator{User: user, Permission: permission})
+}
+
+// RemoveCollaborator revokes permission of user on docker repository
+/* curl \
+   -H "Authorization: JWT ${TOKEN}" \
+   -X DELETE \
+   https://hub.docker.com/v2/repositories/${ORG}/${IMAGE}/collaborators/${USER}/
+*/
+func (c *Client) RemoveCollaborator(image, user string) error {
+	return c.RemoveCollaboratorContext(context.Background(), image, user)
+}
+
+// RemoveCollaboratorContext revokes permission of user on docker repository, unless context is already cancelled
+func (c *Client) RemoveCollaboratorContext(ctx context.Context, image, user string) error {
+	return c.deleteResource(ctx, c.apiURL("repositories/%s/%s/collaborators/%s/", c.ORG, image, user))
+}
@@ -0,0 +1,118 @@
+/*
+Copyright © 2020 Yevhen Lebid ealebed@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dockerhub
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadConfig(t *testing.T) {
+	tests := []struct {
+		name        string
+		content     string
+		missing     bool
+		wantProtect []string
+		wantErr     bool
+	}{
+		{
+			name:        "protect list",
+			content:     "protect:\n  - stable\n  - 'v\\d+\\.\\d+\\.\\d+'\n",
+			wantProtect: []string{"stable", `v\d+\.\d+\.\d+`},
+		},
+		{
+			name:        "empty file",
+			content:     "",
+			wantProtect: nil,
+		},
+		{
+			name:        "missing file",
+			missing:     true,
+			wantProtect: nil,
+		},
+		{
+			name:    "invalid pattern",
+			content: "protect: ['(']\n",
+			wantErr: true,
+		},
+		{
+			name:    "invalid yaml",
+			content: "protect: [unclosed",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			if !tt.missing {
+				if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			config, err := LoadConfig(path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !equalNames(config.Protect, tt.wantProtect) {
+				t.Errorf("LoadConfig() Protect = %v, want %v", config.Protect, tt.wantProtect)
+			}
+		})
+	}
+}
+
+func TestDefaultConfigPath(t *testing.T) {
+	path := DefaultConfigPath()
+	if path != "" && !strings.HasSuffix(path, filepath.Join("dha", "config.yaml")) {
+		t.Errorf("DefaultConfigPath() = %v, should end with dha/config.yaml", path)
+	}
+}
+
+func TestDeleteProtectedTag(t *testing.T) {
+	client := NewClient("testorg", "https://test.com")
+	client.AuthToken = "test-token"
+	client.Protect = append(client.Protect, "stable", `v\d+`)
+
+	for _, tag := range []string{"latest", "stable", "v1"} {
+		err := client.deleteDockerImageTag(context.Background(), "image", &Tag{Name: tag}, nil)
+		if !errors.Is(err, ErrProtectedTag) {
+			t.Errorf("deleteDockerImageTag(%q) error = %v, want ErrProtectedTag", tag, err)
+		}
+	}
+	// tags sharing digest with protected tags are protected too
+	err := client.deleteDockerImageTag(context.Background(), "image", &Tag{Name: "build-1", Digest: "sha256:latest"}, map[string]bool{"sha256:latest": true})
+	if !errors.Is(err, ErrProtectedTag) {
+		t.Errorf("deleteDockerImageTag(build-1) sharing protected digest error = %v, want ErrProtectedTag", err)
+	}
+	// patterns are compiled once, and again only after Protect changes
+	compiled, _ := client.protectedPatterns()
+	if again, _ := client.protectedPatterns(); &again[0] != &compiled[0] {
+		t.Error("protectedPatterns() should reuse compiled patterns")
+	}
+	client.Protect = append(client.Protect, "dev")
+	if err := client.deleteDockerImageTag(context.Background(), "image", &Tag{Name: "dev"}, nil); !errors.Is(err, ErrProtectedTag) {
+		t.Errorf("deleteDockerImageTag(dev) after Protect change error = %v, want ErrProtectedTag", err)
+	}
+}
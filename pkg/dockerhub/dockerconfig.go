@@ -0,0 +1,226 @@
+/*
+Copyright © 2020 Yevhen Lebid ealebed@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dockerhub
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// DockerHubServer represents docker hub server address, used by docker CLI for credentials
+const DockerHubServer = "https://index.docker.io/v1/"
+
+// dockerHubHosts represents host names, docker CLI may store docker hub credentials under
+var dockerHubHosts = map[string]bool{
+	"index.docker.io":      true,
+	"docker.io":            true,
+	"registry-1.docker.io": true,
+	"hub.docker.com":       true,
+}
+
+// DockerConfig represents docker CLI configuration file (config.json) credentials settings
+type DockerConfig struct {
+	Auths       map[string]DockerAuth `json:"auths"`
+	CredsStore  string                `json:"credsStore"`
+	CredHelpers map[string]string     `json:"credHelpers"`
+}
+
+// DockerAuth represents credentials, stored by docker CLI in configuration file
+type DockerAuth struct {
+	Auth          string `json:"auth"`
+	IdentityToken string `json:"identitytoken"`
+}
+
+// dockerHelperCredentials represents docker credential helper "get" response
+type dockerHelperCredentials struct {
+	ServerURL string `json:"ServerURL"`
+	Username  string `json:"Username"`
+	Secret    string `json:"Secret"`
+}
+
+// DockerConfigPath returns path to docker CLI configuration file, respecting DOCKER_CONFIG environment variable
+func DockerConfigPath() string {
+	if dir := os.Getenv("DOCKER_CONFIG"); dir != "" {
+		return filepath.Join(dir, "config.json")
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+
+	return filepath.Join(home, ".docker", "config.json")
+}
+
+// LoadDockerConfig reads docker CLI configuration file, missing file results in empty configuration
+func LoadDockerConfig(path string) (*DockerConfig, error) {
+	config := &DockerConfig{}
+	if path == "" {
+		return config, nil
+	}
+
+	data, err := os.ReadFile(path) // #nosec G304 -- docker config path is provided by the user or environment
+	if errors.Is(err, fs.ErrNotExist) {
+		return config, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, config); err != nil {
+		return nil, fmt.Errorf("failed to decode docker config %s: %w", path, err)
+	}
+
+	return config, nil
+}
+
+// Credentials returns docker hub credentials the way docker CLI resolves them: from credential helper
+// configured for docker hub in credHelpers, from credsStore, then from base64 encoded auths entry, or nil when there are none
+func (d *DockerConfig) Credentials(ctx context.Context) (*Credentials, error) {
+	if servers := dockerHubServers(d.CredHelpers); len(servers) > 0 {
+		return helperCredentials(ctx, d.CredHelpers[servers[0]], servers[0])
+	}
+
+	if d.CredsStore != "" {
+		credentials, err := helperCredentials(ctx, d.CredsStore, DockerHubServer)
+		if credentials != nil || err != nil {
+			return credentials, err
+		}
+	}
+
+	for _, server := range dockerHubServers(d.Auths) {
+		auth := d.Auths[server]
+		if auth.Auth == "" {
+			continue
+		}
+		decoded, err := base64.StdEncoding.DecodeString(auth.Auth)
+		if err != nil {
+			return nil, fmt.Errorf("invalid auth for %s in docker config: %w", server, err)
+		}
+		username, secret, ok := strings.Cut(string(decoded), ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid auth for %s in docker config: missing password", server)
+		}
+		return newSecretCredentials(username, secret), nil
+	}
+
+	return nil, nil
+}
+
+// helperCredentials returns credentials from docker-credential-<helper> binary, or nil when helper has none for server
+func helperCredentials(ctx context.Context, helper, server string) (*Credentials, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "docker-credential-"+helper, "get") // #nosec G204 -- helper name comes from docker config
+	cmd.Stdin = strings.NewReader(server)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		output := strings.TrimSpace(stdout.String() + stderr.String())
+		if strings.Contains(output, "credentials not found") {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("docker credential helper %s failed: %w: %s", helper, err, output)
+	}
+
+	response := &dockerHelperCredentials{}
+	if err := json.Unmarshal(stdout.Bytes(), response); err != nil {
+		return nil, fmt.Errorf("failed to decode docker credential helper %s output: %w", helper, err)
+	}
+	if response.Secret == "" || response.Username == "<token>" {
+		// identity tokens of docker desktop sign in can't be used with docker hub API
+		return nil, nil
+	}
+
+	return newSecretCredentials(response.Username, response.Secret), nil
+}
+
+// newSecretCredentials returns credentials with secret used as access token when it looks like one, or as password
+func newSecretCredentials(username, secret string) *Credentials {
+	if strings.HasPrefix(secret, "dckr_pat_") || strings.HasPrefix(secret, "dckr_oat_") {
+		return &Credentials{Username: username, Token: secret}
+	}
+
+	return &Credentials{Username: username, Password: secret}
+}
+
+// dockerHubServers returns docker hub server addresses among map keys, DockerHubServer first and others sorted
+func dockerHubServers[T any](entries map[string]T) []string {
+	servers := []string{}
+	for _, server := range slices.Sorted(maps.Keys(entries)) {
+		if isDockerHubServer(server) {
+			servers = append(servers, server)
+		}
+	}
+	slices.SortStableFunc(servers, func(a, b string) int {
+		switch {
+		case a == DockerHubServer:
+			return -1
+		case b == DockerHubServer:
+			return 1
+		}
+		return 0
+	})
+
+	return servers
+}
+
+// isDockerHubServer reports whether docker config server address belongs to docker hub
+func isDockerHubServer(server string) bool {
+	host := server
+	if _, rest, ok := strings.Cut(host, "://"); ok {
+		host = rest
+	}
+	host, _, _ = strings.Cut(host, "/")
+
+	return dockerHubHosts[host]
+}
+
+// ResolveCredentials returns docker hub credentials from DOCKERHUB_TOKEN or DOCKERHUB_USERNAME/DOCKERHUB_PASSWORD
+// environment variables, falling back to docker CLI configuration file and credential helpers
+func ResolveCredentials(ctx context.Context) (*Credentials, error) {
+	env := CredentialsFromEnv()
+	if env.Token != "" || env.Password != "" {
+		return env, nil
+	}
+
+	config, err := LoadDockerConfig(DockerConfigPath())
+	if err != nil {
+		return nil, err
+	}
+
+	credentials, err := config.Credentials(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if credentials == nil {
+		return env, nil
+	}
+
+	return credentials, nil
+}
@@ -0,0 +1,155 @@
+/*
+Copyright © 2020 Yevhen Lebid ealebed@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dockerhub
+
+import (
+	"context"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// writeDockerConfig writes docker config.json into temporary DOCKER_CONFIG directory
+func writeDockerConfig(t *testing.T, content string) {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv("DOCKER_CONFIG", dir)
+	t.Setenv("DOCKERHUB_USERNAME", "")
+	t.Setenv("DOCKERHUB_PASSWORD", "")
+	t.Setenv("DOCKERHUB_TOKEN", "")
+	if err := os.WriteFile(filepath.Join(dir, "config.json"), []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// installCredentialHelper puts fake docker-credential-<name> script, printing provided output, into PATH
+func installCredentialHelper(t *testing.T, name, script string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("credential helper scripts require unix shell")
+	}
+	dir := t.TempDir()
+	path := filepath.Join(dir, "docker-credential-"+name)
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+script+"\n"), 0o700); err != nil { // #nosec G306 -- helper script should be executable
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+func basicAuth(username, secret string) string {
+	return base64.StdEncoding.EncodeToString([]byte(username + ":" + secret))
+}
+
+func TestResolveCredentialsFromDockerConfig(t *testing.T) {
+	tests := []struct {
+		name         string
+		config       string
+		helper       string
+		helperScript string
+		want         Credentials
+		wantNone     bool
+		wantErr      bool
+	}{
+		{
+			name:   "auths password",
+			config: `{"auths": {"https://index.docker.io/v1/": {"auth": "` + basicAuth("user", "secret") + `"}}}`,
+			want:   Credentials{Username: "user", Password: "secret"},
+		},
+		{
+			name:   "auths access token",
+			config: `{"auths": {"docker.io": {"auth": "` + basicAuth("user", "dckr_pat_abc") + `"}}}`,
+			want:   Credentials{Username: "user", Token: "dckr_pat_abc"},
+		},
+		{
+			name:     "auths of other registry",
+			config:   `{"auths": {"ghcr.io": {"auth": "` + basicAuth("user", "secret") + `"}}}`,
+			wantNone: true,
+		},
+		{
+			name:         "credential helper for docker hub",
+			config:       `{"credHelpers": {"https://index.docker.io/v1/": "fake"}, "auths": {"docker.io": {"auth": "` + basicAuth("old", "old") + `"}}}`,
+			helper:       "fake",
+			helperScript: `read server; echo "{\"ServerURL\": \"$server\", \"Username\": \"helper-user\", \"Secret\": \"helper-secret\"}"`,
+			want:         Credentials{Username: "helper-user", Password: "helper-secret"},
+		},
+		{
+			name:         "credentials store without docker hub entry falls back to auths",
+			config:       `{"credsStore": "fake", "auths": {"https://index.docker.io/v1/": {"auth": "` + basicAuth("user", "secret") + `"}}}`,
+			helper:       "fake",
+			helperScript: `echo "credentials not found in native keychain"; exit 1`,
+			want:         Credentials{Username: "user", Password: "secret"},
+		},
+		{
+			name:         "failing credentials store",
+			config:       `{"credsStore": "fake"}`,
+			helper:       "fake",
+			helperScript: `echo "keychain is locked" >&2; exit 1`,
+			wantErr:      true,
+		},
+		{
+			name:    "invalid auth",
+			config:  `{"auths": {"https://index.docker.io/v1/": {"auth": "not base64"}}}`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			writeDockerConfig(t, tt.config)
+			if tt.helper != "" {
+				installCredentialHelper(t, tt.helper, tt.helperScript)
+			}
+
+			got, err := ResolveCredentials(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ResolveCredentials() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if tt.wantNone {
+				tt.want = Credentials{}
+			}
+			if *got != tt.want {
+				t.Errorf("ResolveCredentials() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveCredentialsPrefersEnv(t *testing.T) {
+	writeDockerConfig(t, `{"auths": {"https://index.docker.io/v1/": {"auth": "`+basicAuth("user", "secret")+`"}}}`)
+	t.Setenv("DOCKERHUB_USERNAME", "env-user")
+	t.Setenv("DOCKERHUB_TOKEN", "dckr_pat_env")
+
+	got, err := ResolveCredentials(context.Background())
+	if err != nil {
+		t.Fatalf("ResolveCredentials() error = %v", err)
+	}
+	if got.Username != "env-user" || got.Token != "dckr_pat_env" {
+		t.Errorf("ResolveCredentials() = %+v, want environment credentials", got)
+	}
+}
+
+func TestDockerConfigPath(t *testing.T) {
+	t.Setenv("DOCKER_CONFIG", "/etc/docker-config")
+	if got := DockerConfigPath(); got != filepath.Join("/etc/docker-config", "config.json") {
+		t.Errorf("DockerConfigPath() = %v", got)
+	}
+}
@@ -0,0 +1,650 @@
+/*
+Copyright © 2020 Yevhen Lebid ealebed@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dockerhub
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+const testToken = "test-token"
+
+// testHub represents in-memory docker hub stand-in for client tests
+type testHub struct {
+	*httptest.Server
+	mu    sync.Mutex
+	repos []*Repository
+	tags  map[string][]*Tag
+	teams []*Team
+	// members maps team names to their members, organization members are under empty name
+	members map[string][]*Member
+	// permissions maps repository names to permissions of teams on them
+	permissions   map[string][]*TeamPermission
+	collaborators map[string][]*Collaborator
+	requests      []string
+}
+
+// newTestHub starts docker hub stand-in with provided repositories and their tags
+func newTestHub(t *testing.T, repos []*Repository, tags map[string][]*Tag) *testHub {
+	t.Helper()
+	// keep docker config of the user out of tests
+	t.Setenv("DOCKER_CONFIG", t.TempDir())
+	hub := &testHub{repos: repos, tags: tags, permissions: map[string][]*TeamPermission{}, collaborators: map[string][]*Collaborator{}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v2/users/login", func(w http.ResponseWriter, r *http.Request) {
+		hub.writeJSON(w, &AuthResponse{Token: testToken})
+	})
+	mux.HandleFunc("GET /v2/repositories/{org}/{$}", func(w http.ResponseWriter, r *http.Request) {
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		hub.mu.Lock()
+		list := &RepositoryList{Count: len(hub.repos), Results: paginate(hub.repos, page, 2)}
+		if page*2 < len(hub.repos) {
+			list.Next = fmt.Sprintf("%s%s?page=%d&page_size=2", hub.URL, r.URL.Path, page+1)
+		}
+		hub.mu.Unlock()
+		hub.writeJSON(w, list)
+	})
+	mux.HandleFunc("POST /v2/repositories/{$}", func(w http.ResponseWriter, r *http.Request) {
+		request := &createRepositoryRequest{}
+		if err := json.NewDecoder(r.Body).Decode(request); err != nil || request.Name == "" {
+			http.Error(w, `{"message": "invalid repository"}`, http.StatusBadRequest)
+			return
+		}
+		hub.mu.Lock()
+		defer hub.mu.Unlock()
+		for _, repo := range hub.repos {
+			if repo.Name == request.Name {
+				http.Error(w, `{"message": "Repository with this Name and Namespace already exists."}`, http.StatusBadRequest)
+				return
+			}
+		}
+		repo := &Repository{Namespace: request.Namespace, Name: request.Name, Description: request.Description,
+			FullDescription: request.FullDescription, IsPrivate: request.IsPrivate, Status: 1}
+		hub.repos = append(hub.repos, repo)
+		w.WriteHeader(http.StatusCreated)
+		hub.writeJSON(w, repo)
+	})
+	mux.HandleFunc("PATCH /v2/repositories/{org}/{repo}/{$}", func(w http.ResponseWriter, r *http.Request) {
+		hub.updateRepository(w, r, func(repo *Repository) error {
+			update := &RepositoryUpdate{}
+			if err := json.NewDecoder(r.Body).Decode(update); err != nil {
+				return err
+			}
+			if update.Description != nil {
+				repo.Description = *update.Description
+			}
+			if update.FullDescription != nil {
+				repo.FullDescription = *update.FullDescription
+			}
+			return nil
+		})
+	})
+	mux.HandleFunc("POST /v2/repositories/{org}/{repo}/privacy/{$}", func(w http.ResponseWriter, r *http.Request) {
+		hub.updateRepository(w, r, func(repo *Repository) error {
+			return json.NewDecoder(r.Body).Decode(&struct {
+				IsPrivate *bool `json:"is_private"`
+			}{IsPrivate: &repo.IsPrivate})
+		})
+	})
+	mux.HandleFunc("GET /v2/repositories/{org}/{repo}", func(w http.ResponseWriter, r *http.Request) {
+		hub.mu.Lock()
+		defer hub.mu.Unlock()
+		for _, repo := range hub.repos {
+			if repo.Name == r.PathValue("repo") {
+				hub.writeJSON(w, repo)
+				return
+			}
+		}
+		http.NotFound(w, r)
+	})
+	mux.HandleFunc("GET /v2/repositories/{org}/{repo}/tags/{$}", func(w http.ResponseWriter, r *http.Request) {
+		hub.mu.Lock()
+		tags := hub.tags[r.PathValue("repo")]
+		hub.mu.Unlock()
+		hub.writeJSON(w, &TagList{Count: len(tags), Results: tags})
+	})
+	mux.HandleFunc("GET /v2/repositories/{org}/{repo}/tags/{tag}/{$}", func(w http.ResponseWriter, r *http.Request) {
+		hub.mu.Lock()
+		defer hub.mu.Unlock()
+		for _, tag := range hub.tags[r.PathValue("repo")] {
+			if tag.Name == r.PathValue("tag") {
+				hub.writeJSON(w, tag)
+				return
+			}
+		}
+		http.NotFound(w, r)
+	})
+	mux.HandleFunc("DELETE /v2/repositories/{org}/{repo}/tags/{tag}/{$}", func(w http.ResponseWriter, r *http.Request) {
+		hub.mu.Lock()
+		defer hub.mu.Unlock()
+		tags := hub.tags[r.PathValue("repo")]
+		for i, tag := range tags {
+			if tag.Name == r.PathValue("tag") {
+				hub.tags[r.PathValue("repo")] = append(tags[:i:i], tags[i+1:]...)
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+		}
+		http.NotFound(w, r)
+	})
+	mux.HandleFunc("DELETE /v2/repositories/{org}/{repo}/{$}", func(w http.ResponseWriter, r *http.Request) {
+		hub.mu.Lock()
+		defer hub.mu.Unlock()
+		for i, repo := range hub.repos {
+			if repo.Name == r.PathValue("repo") {
+				hub.repos = append(hub.repos[:i:i], hub.repos[i+1:]...)
+				w.WriteHeader(http.StatusAccepted)
+				return
+			}
+		}
+		http.NotFound(w, r)
+	})
+
+	mux.HandleFunc("GET /v2/orgs/{org}/groups/{$}", func(w http.ResponseWriter, r *http.Request) {
+		number, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		hub.mu.Lock()
+		list := &page[*Team]{Count: len(hub.teams), Results: paginate(hub.teams, number, 2)}
+		if max(number, 1)*2 < len(hub.teams) {
+			list.Next = fmt.Sprintf("%s%s?page=%d", hub.URL, r.URL.Path, max(number, 1)+1)
+		}
+		hub.mu.Unlock()
+		hub.writeJSON(w, list)
+	})
+	mux.HandleFunc("GET /v2/orgs/{org}/members/{$}", func(w http.ResponseWriter, r *http.Request) {
+		hub.mu.Lock()
+		members := hub.members[""]
+		hub.mu.Unlock()
+		hub.writeJSON(w, &page[*Member]{Count: len(members), Results: members})
+	})
+	mux.HandleFunc("GET /v2/orgs/{org}/groups/{team}/members/{$}", func(w http.ResponseWriter, r *http.Request) {
+		hub.mu.Lock()
+		members, ok := hub.members[r.PathValue("team")]
+		hub.mu.Unlock()
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		hub.writeJSON(w, &page[*Member]{Count: len(members), Results: members})
+	})
+	mux.HandleFunc("GET /v2/repositories/{org}/{repo}/groups/{$}", func(w http.ResponseWriter, r *http.Request) {
+		hub.mu.Lock()
+		permissions := hub.permissions[r.PathValue("repo")]
+		hub.mu.Unlock()
+		hub.writeJSON(w, &page[*TeamPermission]{Count: len(permissions), Results: permissions})
+	})
+	mux.HandleFunc("POST /v2/repositories/{org}/{repo}/groups/{$}", func(w http.ResponseWriter, r *http.Request) {
+		permission := &TeamPermission{}
+		if err := json.NewDecoder(r.Body).Decode(permission); err != nil || permission.TeamID == 0 {
+			http.Error(w, `{"message": "invalid group"}`, http.StatusBadRequest)
+			return
+		}
+		hub.mu.Lock()
+		defer hub.mu.Unlock()
+		for _, team := range hub.teams {
+			if team.ID == permission.TeamID {
+				permission.TeamName = team.Name
+			}
+		}
+		hub.permissions[r.PathValue("repo")] = append(hub.permissions[r.PathValue("repo")], permission)
+		hub.writeJSON(w, permission)
+	})
+	mux.HandleFunc("PUT /v2/repositories/{org}/{repo}/groups/{id}/{$}", func(w http.ResponseWriter, r *http.Request) {
+		hub.mu.Lock()
+		defer hub.mu.Unlock()
+		for _, permission := range hub.permissions[r.PathValue("repo")] {
+			if strconv.FormatInt(permission.TeamID, 10) == r.PathValue("id") {
+				if err := json.NewDecoder(r.Body).Decode(permission); err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+				hub.writeJSON(w, permission)
+				return
+			}
+		}
+		http.NotFound(w, r)
+	})
+	mux.HandleFunc("DELETE /v2/repositories/{org}/{repo}/groups/{id}/{$}", func(w http.ResponseWriter, r *http.Request) {
+		hub.mu.Lock()
+		defer hub.mu.Unlock()
+		permissions := hub.permissions[r.PathValue("repo")]
+		for i, permission := range permissions {
+			if strconv.FormatInt(permission.TeamID, 10) == r.PathValue("id") {
+				hub.permissions[r.PathValue("repo")] = append(permissions[:i:i], permissions[i+1:]...)
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+		}
+		http.NotFound(w, r)
+	})
+	mux.HandleFunc("GET /v2/repositories/{org}/{repo}/collaborators/{$}", func(w http.ResponseWriter, r *http.Request) {
+		hub.mu.Lock()
+		collaborators := hub.collaborators[r.PathValue("repo")]
+		hub.mu.Unlock()
+		hub.writeJSON(w, &page[*Collaborator]{Count: len(collaborators), Results: collaborators})
+	})
+	mux.HandleFunc("POST /v2/repositories/{org}/{repo}/collaborators/{$}", func(w http.ResponseWriter, r *http.Request) {
+		collaborator := &Collaborator{}
+		if err := json.NewDecoder(r.Body).Decode(collaborator); err != nil || collaborator.User == "" {
+			http.Error(w, `{"message": "invalid collaborator"}`, http.StatusBadRequest)
+			return
+		}
+		hub.mu.Lock()
+		defer hub.mu.Unlock()
+		hub.collaborators[r.PathValue("repo")] = append(hub.collaborators[r.PathValue("repo")], collaborator)
+		hub.writeJSON(w, collaborator)
+	})
+	mux.HandleFunc("PUT /v2/repositories/{org}/{repo}/collaborators/{user}/{$}", func(w http.ResponseWriter, r *http.Request) {
+		hub.mu.Lock()
+		defer hub.mu.Unlock()
+		for _, collaborator := range hub.collaborators[r.PathValue("repo")] {
+			if collaborator.User == r.PathValue("user") {
+				if err := json.NewDecoder(r.Body).Decode(collaborator); err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+				hub.writeJSON(w, collaborator)
+				return
+			}
+		}
+		http.NotFound(w, r)
+	})
+	mux.HandleFunc("DELETE /v2/repositories/{org}/{repo}/collaborators/{user}/{$}", func(w http.ResponseWriter, r *http.Request) {
+		hub.mu.Lock()
+		defer hub.mu.Unlock()
+		collaborators := hub.collaborators[r.PathValue("repo")]
+		for i, collaborator := range collaborators {
+			if collaborator.User == r.PathValue("user") {
+				hub.collaborators[r.PathValue("repo")] = append(collaborators[:i:i], collaborators[i+1:]...)
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+		}
+		http.NotFound(w, r)
+	})
+
+	hub.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hub.mu.Lock()
+		hub.requests = append(hub.requests, r.Method+" "+r.URL.Path)
+		hub.mu.Unlock()
+		if r.URL.Path != "/v2/users/login" && r.Header.Get("Authorization") != "JWT "+testToken {
+			http.Error(w, `{"detail": "authentication required"}`, http.StatusUnauthorized)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	}))
+	t.Cleanup(hub.Close)
+
+	return hub
+}
+
+func (h *testHub) writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// updateRepository applies update to repository from request path, responding with updated repository
+func (h *testHub) updateRepository(w http.ResponseWriter, r *http.Request, update func(repo *Repository) error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, repo := range h.repos {
+		if repo.Name == r.PathValue("repo") {
+			if err := update(repo); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			h.writeJSON(w, repo)
+			return
+		}
+	}
+	http.NotFound(w, r)
+}
+
+// requested returns requests received by hub as "METHOD path"
+func (h *testHub) requested() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return append([]string{}, h.requests...)
+}
+
+func paginate[T any](items []T, page, size int) []T {
+	start := max(page-1, 0) * size
+	if start >= len(items) {
+		return []T{}
+	}
+
+	return items[start:min(start+size, len(items))]
+}
+
+func TestClientUsesConfiguredURL(t *testing.T) {
+	hub := newTestHub(t,
+		[]*Repository{{Name: "api"}, {Name: "web"}, {Name: "worker"}},
+		map[string][]*Tag{"api": {{Name: "latest"}, {Name: "dev-1"}, {Name: "dev-2"}}},
+	)
+
+	client := NewClient("testorg", hub.URL)
+	client.Retry = nil
+	ctx := context.Background()
+
+	repos, err := client.ListRepositoriesContext(ctx)
+	if err != nil {
+		t.Fatalf("ListRepositories() error = %v", err)
+	}
+	if len(repos) != 3 {
+		t.Errorf("ListRepositories() returned %d repositories, want 3", len(repos))
+	}
+
+	repo, err := client.DescribeRepositoryContext(ctx, "web")
+	if err != nil || repo.Name != "web" {
+		t.Errorf("DescribeRepository() = %v, %v", repo, err)
+	}
+
+	if err := client.TruncateTagsContext(ctx, "api", &TagSelector{TagRegex: "^dev-"}); err != nil {
+		t.Fatalf("TruncateTags() error = %v", err)
+	}
+	tags, err := client.ListTagsContext(ctx, "api")
+	if err != nil {
+		t.Fatalf("ListTags() error = %v", err)
+	}
+	if got := tagNames(tags); !equalNames(got, []string{"latest"}) {
+		t.Errorf("tags after TruncateTags() = %v, want [latest]", got)
+	}
+
+	tag, err := client.GetTagContext(ctx, "api", "latest")
+	if err != nil || tag.Name != "latest" {
+		t.Errorf("GetTag() = %v, %v", tag, err)
+	}
+	if _, err := client.GetTagContext(ctx, "api", "dev-1"); err == nil {
+		t.Error("GetTag() of deleted tag should return error")
+	}
+
+	if err := client.DeleteRepositoryContext(ctx, "worker"); err != nil {
+		t.Fatalf("DeleteRepository() error = %v", err)
+	}
+	if _, err := client.DescribeRepositoryContext(ctx, "worker"); err == nil {
+		t.Error("DescribeRepository() of deleted repository should return error")
+	}
+
+	requests := hub.requested()
+	if requests[0] != "POST /v2/users/login" {
+		t.Errorf("first request = %v, want login", requests[0])
+	}
+	if client.AuthToken != testToken {
+		t.Errorf("AuthToken = %v, want %v", client.AuthToken, testToken)
+	}
+}
+
+func TestClientAPIURL(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{url: "", want: "https://hub.docker.com/v2/repositories/org/"},
+		{url: "https://hub.docker.com", want: "https://hub.docker.com/v2/repositories/org/"},
+		{url: "http://localhost:8080/", want: "http://localhost:8080/v2/repositories/org/"},
+		{url: "https://mirror.example.com/hub/v2", want: "https://mirror.example.com/hub/v2/repositories/org/"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			client := &Client{URL: tt.url}
+			if got := client.apiURL("repositories/%s/", "org"); got != tt.want {
+				t.Errorf("apiURL() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClientCreateRepository(t *testing.T) {
+	hub := newTestHub(t, []*Repository{{Name: "api"}}, nil)
+
+	client := NewClient("testorg", hub.URL)
+	client.Retry = nil
+	ctx := context.Background()
+
+	created, err := client.CreateRepositoryContext(ctx, &Repository{Name: "web", Description: "web app", FullDescription: "# Web", IsPrivate: true})
+	if err != nil {
+		t.Fatalf("CreateRepository() error = %v", err)
+	}
+	if created.Namespace != "testorg" || created.Name != "web" || !created.IsPrivate || created.FullDescription != "# Web" {
+		t.Errorf("CreateRepository() = %+v", created)
+	}
+
+	repo, err := client.DescribeRepositoryContext(ctx, "web")
+	if err != nil || repo.Description != "web app" || !repo.IsPrivate {
+		t.Errorf("DescribeRepository() of created repository = %+v, %v", repo, err)
+	}
+
+	var httpErr *HTTPError
+	if _, err := client.CreateRepositoryContext(ctx, &Repository{Name: "api"}); !errors.As(err, &httpErr) || httpErr.StatusCode != http.StatusBadRequest {
+		t.Errorf("CreateRepository() of existing repository error = %v, want HTTP 400", err)
+	}
+}
+
+func TestClientResendsRequestBody(t *testing.T) {
+	var mu sync.Mutex
+	var logins int
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.URL.Path == "/v2/users/login" {
+			logins++
+			_, _ = fmt.Fprintf(w, `{"token": "token-%d"}`, logins)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, r.Header.Get("Content-Type")+" "+string(body))
+		switch len(bodies) {
+		case 1:
+			// token is revoked, so client should log in again and resend request
+			http.Error(w, `{"detail": "Token is invalid or expired"}`, http.StatusUnauthorized)
+		case 2:
+			// rate limited request is retried by retry policy with the same body
+			http.Error(w, `{"detail": "too many requests"}`, http.StatusTooManyRequests)
+		default:
+			_, _ = w.Write(body)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	client := NewClient("testorg", server.URL)
+	client.Credentials = &Credentials{Username: "user", Password: "secret"}
+	client.Retry = newTestRetryPolicy(&[]time.Duration{})
+
+	created, err := client.CreateRepositoryContext(context.Background(), &Repository{Name: "web"})
+	if err != nil {
+		t.Fatalf("CreateRepository() error = %v", err)
+	}
+	if created.Name != "web" {
+		t.Errorf("CreateRepository() = %+v", created)
+	}
+
+	want := `application/json {"namespace":"testorg","name":"web","description":"","full_description":"","is_private":false}`
+	if len(bodies) != 3 || logins != 2 {
+		t.Fatalf("got %d requests and %d logins, want 3 requests and 2 logins", len(bodies), logins)
+	}
+	for i, body := range bodies {
+		if body != want {
+			t.Errorf("request %d body = %s, want %s", i+1, body, want)
+		}
+	}
+}
+
+func TestClientUpdateRepository(t *testing.T) {
+	hub := newTestHub(t, []*Repository{{Name: "api", Description: "old", FullDescription: "# Old"}}, nil)
+
+	client := NewClient("testorg", hub.URL)
+	client.Retry = nil
+	ctx := context.Background()
+
+	description, private := "API service", true
+	if err := client.UpdateRepositoryContext(ctx, "api", &RepositoryUpdate{Description: &description, IsPrivate: &private}); err != nil {
+		t.Fatalf("UpdateRepository() error = %v", err)
+	}
+	repo, err := client.DescribeRepositoryContext(ctx, "api")
+	if err != nil || repo.Description != description || repo.FullDescription != "# Old" || !repo.IsPrivate {
+		t.Errorf("repository after UpdateRepository() = %+v, %v", repo, err)
+	}
+
+	// visibility only update doesn't touch descriptions
+	public := false
+	if err := client.UpdateRepositoryContext(ctx, "api", &RepositoryUpdate{IsPrivate: &public}); err != nil {
+		t.Fatalf("UpdateRepository() error = %v", err)
+	}
+	requests := hub.requested()
+	if got := requests[len(requests)-1]; got != "POST /v2/repositories/testorg/api/privacy/" {
+		t.Errorf("last request = %s, want privacy change only", got)
+	}
+
+	if err := client.UpdateRepositoryContext(ctx, "missing", &RepositoryUpdate{Description: &description}); err == nil {
+		t.Error("UpdateRepository() of missing repository should fail")
+	}
+}
+
+func TestClientTeamPermissions(t *testing.T) {
+	hub := newTestHub(t, []*Repository{{Name: "api"}}, nil)
+	hub.teams = []*Team{{ID: 1, Name: "backend"}, {ID: 2, Name: "frontend"}, {ID: 3, Name: "ops"}}
+	hub.permissions["api"] = []*TeamPermission{{TeamID: 1, TeamName: "backend", Permission: PermissionRead}}
+
+	client := NewClient("testorg", hub.URL)
+	client.Retry = nil
+	ctx := context.Background()
+
+	teams, err := client.ListTeamsContext(ctx)
+	if err != nil || len(teams) != 3 || teams[2].Name != "ops" {
+		t.Fatalf("ListTeams() = %v, %v, want teams of both pages", teams, err)
+	}
+	ops, err := client.GetTeamContext(ctx, "ops")
+	if err != nil || ops.ID != 3 {
+		t.Fatalf("GetTeam() = %+v, %v", ops, err)
+	}
+	if _, err := client.GetTeamContext(ctx, "missing"); err == nil {
+		t.Error("GetTeam() of missing team should fail")
+	}
+
+	if err := client.SetTeamPermissionContext(ctx, "api", teams[0], PermissionWrite); err != nil {
+		t.Fatalf("SetTeamPermission() of existing team error = %v", err)
+	}
+	if err := client.SetTeamPermissionContext(ctx, "api", ops, PermissionAdmin); err != nil {
+		t.Fatalf("SetTeamPermission() of new team error = %v", err)
+	}
+	if err := client.SetTeamPermissionContext(ctx, "api", ops, "owner"); err == nil {
+		t.Error("SetTeamPermission() with invalid permission should fail")
+	}
+
+	permissions, err := client.ListRepositoryTeamsContext(ctx, "api")
+	if err != nil {
+		t.Fatalf("ListRepositoryTeams() error = %v", err)
+	}
+	got := map[string]string{}
+	for _, p := range permissions {
+		got[p.TeamName] = p.Permission
+	}
+	if want := map[string]string{"backend": PermissionWrite, "ops": PermissionAdmin}; !maps.Equal(got, want) {
+		t.Errorf("team permissions = %v, want %v", got, want)
+	}
+
+	if err := client.RemoveTeamPermissionContext(ctx, "api", teams[0]); err != nil {
+		t.Fatalf("RemoveTeamPermission() error = %v", err)
+	}
+	if permissions, _ := client.ListRepositoryTeamsContext(ctx, "api"); len(permissions) != 1 || permissions[0].TeamName != "ops" {
+		t.Errorf("team permissions after RemoveTeamPermission() = %v", permissions)
+	}
+}
+
+func TestClientMembers(t *testing.T) {
+	hub := newTestHub(t, nil, nil)
+	hub.members = map[string][]*Member{
+		"":        {{ID: "1", Username: "alice"}, {ID: "2", Username: "bob"}},
+		"backend": {{ID: "2", Username: "bob"}},
+	}
+
+	client := NewClient("testorg", hub.URL)
+	client.Retry = nil
+	ctx := context.Background()
+
+	members, err := client.ListMembersContext(ctx)
+	if err != nil || len(members) != 2 || members[0].Username != "alice" {
+		t.Errorf("ListMembers() = %v, %v", members, err)
+	}
+	members, err = client.ListTeamMembersContext(ctx, "backend")
+	if err != nil || len(members) != 1 || members[0].Username != "bob" {
+		t.Errorf("ListTeamMembers() = %v, %v", members, err)
+	}
+	if _, err := client.ListTeamMembersContext(ctx, "missing"); err == nil {
+		t.Error("ListTeamMembers() of missing team should fail")
+	}
+}
+
+func TestClientCollaborators(t *testing.T) {
+	hub := newTestHub(t, []*Repository{{Name: "api"}}, nil)
+	hub.collaborators["api"] = []*Collaborator{{User: "alice", Permission: PermissionRead}, {User: "bob", Permission: PermissionAdmin}}
+
+	client := NewClient("testorg", hub.URL)
+	client.Retry = nil
+	ctx := context.Background()
+
+	if err := client.SetCollaboratorPermissionContext(ctx, "api", "alice", PermissionWrite); err != nil {
+		t.Fatalf("SetCollaboratorPermission() of existing collaborator error = %v", err)
+	}
+	if err := client.SetCollaboratorPermissionContext(ctx, "api", "carol", PermissionRead); err != nil {
+		t.Fatalf("SetCollaboratorPermission() of new collaborator error = %v", err)
+	}
+	if err := client.SetCollaboratorPermissionContext(ctx, "api", "carol", "owner"); err == nil {
+		t.Error("SetCollaboratorPermission() with invalid permission should fail")
+	}
+	if err := client.RemoveCollaboratorContext(ctx, "api", "bob"); err != nil {
+		t.Fatalf("RemoveCollaborator() error = %v", err)
+	}
+
+	collaborators, err := client.ListCollaboratorsContext(ctx, "api")
+	if err != nil {
+		t.Fatalf("ListCollaborators() error = %v", err)
+	}
+	got := map[string]string{}
+	for _, collaborator := range collaborators {
+		got[collaborator.User] = collaborator.Permission
+	}
+	if want := map[string]string{"alice": PermissionWrite, "carol": PermissionRead}; !maps.Equal(got, want) {
+		t.Errorf("collaborators = %v, want %v", got, want)
+	}
+
+	if err := client.RemoveCollaboratorContext(ctx, "api", "bob"); err == nil {
+		t.Error("RemoveCollaborator() of missing collaborator should fail")
+	}
+	if client.Progress.Completed() != 1 || client.Progress.Failed() != 1 {
+		t.Errorf("Progress = completed %d, failed %d, want 1, 1", client.Progress.Completed(), client.Progress.Failed())
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := client.RemoveCollaboratorContext(cancelled, "api", "alice"); !errors.Is(err, context.Canceled) {
+		t.Errorf("RemoveCollaborator() with cancelled context error = %v, want context.Canceled", err)
+	}
+}
@@ -17,6 +17,7 @@ limitations under the License.
 package dockerhub
 
 import (
+	"context"
 	"os/exec"
 	"regexp"
 	"time"
@@ -24,9 +25,18 @@ import (
 	"github.com/fatih/color"
 )
 
+// dateTagRegexp matches date docker image tags like "20.11.15-12.30"
+var dateTagRegexp = regexp.MustCompile(`^\d{2}\.\d{2}\.\d{2}\-\d{2}\.\d{2}$`)
+
 // RenewDockerImage renew docker image tags older than 20 days from docker hub
 func (c *Client) RenewDockerImage(image string) error {
-	tags, err := c.ListTags(image)
+	return c.RenewDockerImageContext(context.Background(), image)
+}
+
+// RenewDockerImageContext renew docker image tags older than 20 days from docker hub,
+// after context is cancelled remaining tags are skipped, while started renewal is finished
+func (c *Client) RenewDockerImageContext(ctx context.Context, image string) error {
+	tags, err := c.ListTagsContext(ctx, image)
 	if err != nil {
 		return err
 	}
@@ -34,11 +44,13 @@ func (c *Client) RenewDockerImage(image string) error {
 	loc, _ := time.LoadLocation("UTC")
 	currentTime := time.Now().In(loc)
 	expiredRange := (time.Hour * 24 * 20)
-	validTag := regexp.MustCompile(`^\d{2}\.\d{2}\.\d{2}\-\d{2}\.\d{2}$`)
 
 	for _, tag := range tags {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		imageReference := c.ORG + "/" + image + ":" + tag.Name
-		if !validTag.MatchString(tag.Name) {
+		if !dateTagRegexp.MatchString(tag.Name) {
 			color.Yellow("	Skip %s ", BW(imageReference))
 		} else {
 			lastUpdatedAt := tag.LastUpdated.In(loc)
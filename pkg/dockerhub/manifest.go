@@ -0,0 +1,319 @@
+/*
+Copyright © 2020 Yevhen Lebid ealebed@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dockerhub
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Actions of repository diff
+const (
+	ActionNone      = ""
+	ActionCreate    = "create"
+	ActionUpdate    = "update"
+	ActionUnmanaged = "unmanaged"
+)
+
+// Fields of repository diff changes, team and collaborator permissions are "team/NAME" and "collaborator/NAME" fields
+const (
+	FieldVisibility         = "visibility"
+	FieldDescription        = "description"
+	FieldFullDescription    = "full_description"
+	FieldTeamPrefix         = "team/"
+	FieldCollaboratorPrefix = "collaborator/"
+)
+
+// Visibilities of docker repository
+const (
+	VisibilityPrivate = "private"
+	VisibilityPublic  = "public"
+)
+
+// Manifest represents desired state of organization repositories, loaded from YAML or JSON file
+type Manifest struct {
+	// Organization owns repositories, defaults to '--org' when omitted
+	Organization string                `yaml:"organization"`
+	Repositories []*ManifestRepository `yaml:"repositories"`
+}
+
+// ManifestRepository represents desired state of docker repository, omitted fields aren't managed
+type ManifestRepository struct {
+	Name string `yaml:"name"`
+	// Visibility is private or public
+	Visibility  string  `yaml:"visibility"`
+	Description *string `yaml:"description"`
+	// Readme is path to full description file, relative to manifest file
+	Readme string `yaml:"readme"`
+	// Teams maps team names to their permissions, teams not listed lose access to repository
+	Teams map[string]string `yaml:"teams"`
+	// Collaborators maps user names to their permissions, users not listed lose access to repository
+	Collaborators map[string]string `yaml:"collaborators"`
+	// Retention is tags retention rule of repository, its repositories expression is ignored
+	Retention *RetentionRule `yaml:"retention"`
+
+	fullDescription *string
+}
+
+// RepositoryState represents live state of docker repository, compared with manifest
+type RepositoryState struct {
+	// Repository is nil, when repository doesn't exist
+	Repository *Repository
+	// Teams is nil, when team permissions aren't managed
+	Teams []*TeamPermission
+	// Collaborators is nil, when collaborators aren't managed
+	Collaborators []*Collaborator
+}
+
+// Change represents docker repository field, which differs from manifest
+type Change struct {
+	Field   string `json:"field"`
+	Live    string `json:"live"`
+	Desired string `json:"desired"`
+}
+
+// RepositoryDiff represents changes bringing docker repository to manifest state
+type RepositoryDiff struct {
+	Repository string    `json:"repository"`
+	Action     string    `json:"action"`
+	Changes    []*Change `json:"changes"`
+}
+
+// LoadManifest reads manifest from YAML or JSON file, unknown fields are rejected to catch typos
+func LoadManifest(path string) (*Manifest, error) {
+	data, err := os.ReadFile(path) // #nosec G304 -- manifest file path is provided by the user
+	if err != nil {
+		return nil, err
+	}
+
+	manifest := &Manifest{}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(manifest); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to decode manifest %s: %w", path, err)
+	}
+
+	if err := manifest.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid manifest %s: %w", path, err)
+	}
+
+	for _, repo := range manifest.Repositories {
+		if repo.Readme == "" {
+			continue
+		}
+		readme := repo.Readme
+		if !filepath.IsAbs(readme) {
+			readme = filepath.Join(filepath.Dir(path), readme)
+		}
+		data, err := os.ReadFile(readme) // #nosec G304 -- readme path is provided by the user in manifest
+		if err != nil {
+			return nil, fmt.Errorf("repository %s: failed to read readme: %w", repo.Name, err)
+		}
+		repo.SetFullDescription(string(data))
+	}
+
+	return manifest, nil
+}
+
+// Validate checks that repository names are unique, and visibilities, permissions and retention rules are valid
+func (m *Manifest) Validate() error {
+	names := map[string]bool{}
+	for i, repo := range m.Repositories {
+		if repo.Name == "" {
+			return fmt.Errorf("repository %d: name is required", i+1)
+		}
+		if names[repo.Name] {
+			return fmt.Errorf("repository %s: declared more than once", repo.Name)
+		}
+		names[repo.Name] = true
+
+		if repo.Visibility != "" && repo.Visibility != VisibilityPrivate && repo.Visibility != VisibilityPublic {
+			return fmt.Errorf("repository %s: visibility should be private or public", repo.Name)
+		}
+		for team, permission := range repo.Teams {
+			if err := ValidatePermission(permission); err != nil {
+				return fmt.Errorf("repository %s: team %s: %w", repo.Name, team, err)
+			}
+		}
+		for user, permission := range repo.Collaborators {
+			if err := ValidatePermission(permission); err != nil {
+				return fmt.Errorf("repository %s: collaborator %s: %w", repo.Name, user, err)
+			}
+		}
+		if repo.Retention != nil {
+			if err := (&RetentionPolicy{Rules: []*RetentionRule{repo.Retention}}).Validate(); err != nil {
+				return fmt.Errorf("repository %s: retention: %w", repo.Name, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Repository returns manifest repository with provided name, or nil
+func (m *Manifest) Repository(name string) *ManifestRepository {
+	for _, repo := range m.Repositories {
+		if repo.Name == name {
+			return repo
+		}
+	}
+
+	return nil
+}
+
+// SetFullDescription sets desired full description of repository, normally read from readme file
+func (r *ManifestRepository) SetFullDescription(fullDescription string) {
+	r.fullDescription = &fullDescription
+}
+
+// RetentionPolicy returns retention policy applying repository retention rule, or nil when it's not provided
+func (r *ManifestRepository) RetentionPolicy() *RetentionPolicy {
+	if r.Retention == nil {
+		return nil
+	}
+
+	rule := *r.Retention
+	rule.Repositories = ""
+
+	return &RetentionPolicy{Rules: []*RetentionRule{&rule}}
+}
+
+// Diff returns changes of docker repository live state, bringing it to manifest state
+func (r *ManifestRepository) Diff(live *RepositoryState) *RepositoryDiff {
+	diff := &RepositoryDiff{Repository: r.Name, Action: ActionUpdate, Changes: []*Change{}}
+
+	repo := live.Repository
+	if repo == nil {
+		diff.Action = ActionCreate
+		repo = &Repository{}
+	}
+
+	add := func(field, liveValue, desired string) {
+		if liveValue != desired {
+			diff.Changes = append(diff.Changes, &Change{Field: field, Live: liveValue, Desired: desired})
+		}
+	}
+
+	if r.Visibility != "" {
+		liveVisibility := Visibility(repo.IsPrivate)
+		if live.Repository == nil {
+			liveVisibility = ""
+		}
+		add(FieldVisibility, liveVisibility, r.Visibility)
+	}
+	if r.Description != nil {
+		add(FieldDescription, repo.Description, *r.Description)
+	}
+	if r.fullDescription != nil {
+		add(FieldFullDescription, repo.FullDescription, *r.fullDescription)
+	}
+
+	if r.Teams != nil {
+		liveTeams := map[string]string{}
+		for _, team := range live.Teams {
+			liveTeams[team.TeamName] = team.Permission
+		}
+		diff.addPermissions(FieldTeamPrefix, liveTeams, r.Teams)
+	}
+	if r.Collaborators != nil {
+		liveCollaborators := map[string]string{}
+		for _, collaborator := range live.Collaborators {
+			liveCollaborators[collaborator.User] = collaborator.Permission
+		}
+		diff.addPermissions(FieldCollaboratorPrefix, liveCollaborators, r.Collaborators)
+	}
+
+	if diff.Action == ActionUpdate && len(diff.Changes) == 0 {
+		diff.Action = ActionNone
+	}
+
+	return diff
+}
+
+// addPermissions adds changes of permissions, which differ from desired ones, sorted by name,
+// permissions missing in desired ones are revoked
+func (d *RepositoryDiff) addPermissions(prefix string, live, desired map[string]string) {
+	for _, name := range slices.Sorted(maps.Keys(desired)) {
+		if live[name] != desired[name] {
+			d.Changes = append(d.Changes, &Change{Field: prefix + name, Live: live[name], Desired: desired[name]})
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(live)) {
+		if _, ok := desired[name]; !ok {
+			d.Changes = append(d.Changes, &Change{Field: prefix + name, Live: live[name]})
+		}
+	}
+}
+
+// RepositoryUpdate returns update of docker repository descriptions and visibility changes
+func (d *RepositoryDiff) RepositoryUpdate() *RepositoryUpdate {
+	update := &RepositoryUpdate{}
+	for _, change := range d.Changes {
+		desired := change.Desired
+		switch change.Field {
+		case FieldVisibility:
+			private := desired == VisibilityPrivate
+			update.IsPrivate = &private
+		case FieldDescription:
+			update.Description = &desired
+		case FieldFullDescription:
+			update.FullDescription = &desired
+		}
+	}
+
+	return update
+}
+
+// TeamChanges returns desired permissions of teams, which differ from live ones, empty permission revokes access
+func (d *RepositoryDiff) TeamChanges() map[string]string {
+	return d.permissionChanges(FieldTeamPrefix)
+}
+
+// CollaboratorChanges returns desired permissions of collaborators, which differ from live ones, empty permission revokes access
+func (d *RepositoryDiff) CollaboratorChanges() map[string]string {
+	return d.permissionChanges(FieldCollaboratorPrefix)
+}
+
+// permissionChanges returns desired permissions of changes with fields starting with prefix, by name following prefix
+func (d *RepositoryDiff) permissionChanges(prefix string) map[string]string {
+	permissions := map[string]string{}
+	for _, change := range d.Changes {
+		if name, ok := strings.CutPrefix(change.Field, prefix); ok {
+			permissions[name] = change.Desired
+		}
+	}
+
+	return permissions
+}
+
+// Visibility returns docker repository visibility name
+func Visibility(private bool) string {
+	if private {
+		return VisibilityPrivate
+	}
+
+	return VisibilityPublic
+}
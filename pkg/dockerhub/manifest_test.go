@@ -0,0 +1,215 @@
+/*
+Copyright © 2020 Yevhen Lebid ealebed@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dockerhub
+
+import (
+	"maps"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestLoadManifest(t *testing.T) {
+	path := writePolicy(t, "repos.yaml", `
+organization: acme
+repositories:
+  - name: api
+    visibility: private
+    description: API service
+    readme: docs/api.md
+    teams:
+      backend: write
+      ops: admin
+    retention:
+      repositories: ^never$
+      keep_last: 10
+      max_age: 30d
+  - name: web
+`)
+	if err := os.MkdirAll(filepath.Join(filepath.Dir(path), "docs"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(filepath.Dir(path), "docs", "api.md"), []byte("# API\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	manifest, err := LoadManifest(path)
+	if err != nil {
+		t.Fatalf("LoadManifest() error = %v", err)
+	}
+	if manifest.Organization != "acme" || len(manifest.Repositories) != 2 {
+		t.Fatalf("LoadManifest() = %+v", manifest)
+	}
+
+	api := manifest.Repository("api")
+	if api == nil || api.Visibility != VisibilityPrivate || *api.Description != "API service" || *api.fullDescription != "# API\n" {
+		t.Errorf("Repository(api) = %+v", api)
+	}
+	if want := map[string]string{"backend": PermissionWrite, "ops": PermissionAdmin}; !maps.Equal(api.Teams, want) {
+		t.Errorf("Repository(api) teams = %v, want %v", api.Teams, want)
+	}
+	// repositories expression of rule is ignored, as rule belongs to repository
+	if rule := api.RetentionPolicy().Rule("api"); rule == nil || rule.KeepLast != 10 || time.Duration(rule.MaxAge) != 30*24*time.Hour {
+		t.Errorf("RetentionPolicy().Rule(api) = %+v", rule)
+	}
+
+	web := manifest.Repository("web")
+	if web == nil || web.Description != nil || web.fullDescription != nil || web.Teams != nil || web.RetentionPolicy() != nil {
+		t.Errorf("Repository(web) = %+v, want unmanaged fields", web)
+	}
+	if manifest.Repository("missing") != nil {
+		t.Error("Repository(missing) should be nil")
+	}
+}
+
+func TestLoadManifestErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{name: "invalid yaml", content: "repositories: [unclosed"},
+		{name: "unknown field", content: "repositories:\n  - name: api\n    visibilty: private\n"},
+		{name: "missing name", content: "repositories:\n  - visibility: private\n"},
+		{name: "duplicate name", content: "repositories:\n  - name: api\n  - name: api\n"},
+		{name: "invalid visibility", content: "repositories:\n  - name: api\n    visibility: internal\n"},
+		{name: "invalid permission", content: "repositories:\n  - name: api\n    teams: {backend: owner}\n"},
+		{name: "invalid collaborator permission", content: "repositories:\n  - name: api\n    collaborators: {alice: pull}\n"},
+		{name: "invalid retention", content: "repositories:\n  - name: api\n    retention: {tags: '['}\n"},
+		{name: "missing readme", content: "repositories:\n  - name: api\n    readme: missing.md\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := LoadManifest(writePolicy(t, "repos.yaml", tt.content)); err == nil {
+				t.Error("LoadManifest() should return error")
+			}
+		})
+	}
+}
+
+func TestManifestRepositoryDiff(t *testing.T) {
+	description := "API service"
+	desired := &ManifestRepository{
+		Name:        "api",
+		Visibility:  VisibilityPrivate,
+		Description: &description,
+		Teams:       map[string]string{"backend": PermissionWrite, "ops": PermissionAdmin},
+		// no collaborators besides teams
+		Collaborators: map[string]string{},
+	}
+	desired.SetFullDescription("# API")
+
+	tests := []struct {
+		name       string
+		live       *RepositoryState
+		wantAction string
+		want       []*Change
+	}{
+		{
+			name:       "missing repository",
+			live:       &RepositoryState{},
+			wantAction: ActionCreate,
+			want: []*Change{
+				{Field: FieldVisibility, Desired: VisibilityPrivate},
+				{Field: FieldDescription, Desired: description},
+				{Field: FieldFullDescription, Desired: "# API"},
+				{Field: "team/backend", Desired: PermissionWrite},
+				{Field: "team/ops", Desired: PermissionAdmin},
+			},
+		},
+		{
+			name: "drifted repository",
+			live: &RepositoryState{
+				Repository: &Repository{Name: "api", Description: "old", FullDescription: "# API"},
+				Teams: []*TeamPermission{
+					{TeamName: "qa", Permission: PermissionRead},
+					{TeamName: "backend", Permission: PermissionRead},
+					{TeamName: "ops", Permission: PermissionAdmin},
+				},
+				Collaborators: []*Collaborator{{User: "alice", Permission: PermissionAdmin}},
+			},
+			wantAction: ActionUpdate,
+			want: []*Change{
+				{Field: FieldVisibility, Live: VisibilityPublic, Desired: VisibilityPrivate},
+				{Field: FieldDescription, Live: "old", Desired: description},
+				{Field: "team/backend", Live: PermissionRead, Desired: PermissionWrite},
+				{Field: "team/qa", Live: PermissionRead},
+				{Field: "collaborator/alice", Live: PermissionAdmin},
+			},
+		},
+		{
+			name: "repository in sync",
+			live: &RepositoryState{
+				Repository: &Repository{Name: "api", Description: description, FullDescription: "# API", IsPrivate: true},
+				Teams: []*TeamPermission{
+					{TeamName: "ops", Permission: PermissionAdmin},
+					{TeamName: "backend", Permission: PermissionWrite},
+				},
+			},
+			wantAction: ActionNone,
+			want:       []*Change{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diff := desired.Diff(tt.live)
+			if diff.Repository != "api" || diff.Action != tt.wantAction {
+				t.Errorf("Diff() repository = %s, action = %q, want api, %q", diff.Repository, diff.Action, tt.wantAction)
+			}
+			if !reflect.DeepEqual(diff.Changes, tt.want) {
+				t.Errorf("Diff() changes:")
+				for _, change := range diff.Changes {
+					t.Errorf("\tgot %+v", change)
+				}
+				for _, change := range tt.want {
+					t.Errorf("\twant %+v", change)
+				}
+			}
+		})
+	}
+
+	// fields omitted in manifest aren't managed
+	unmanaged := (&ManifestRepository{Name: "web"}).Diff(&RepositoryState{Repository: &Repository{Name: "web", Description: "web", IsPrivate: true}})
+	if unmanaged.Action != ActionNone || len(unmanaged.Changes) != 0 {
+		t.Errorf("Diff() of repository without managed fields = %+v", unmanaged)
+	}
+}
+
+func TestRepositoryDiffUpdates(t *testing.T) {
+	diff := &RepositoryDiff{Repository: "api", Action: ActionUpdate, Changes: []*Change{
+		{Field: FieldVisibility, Live: VisibilityPrivate, Desired: VisibilityPublic},
+		{Field: FieldDescription, Live: "old", Desired: ""},
+		{Field: "team/backend", Live: PermissionRead, Desired: PermissionWrite},
+		{Field: "team/qa", Live: PermissionRead},
+		{Field: "collaborator/alice", Live: PermissionRead, Desired: PermissionAdmin},
+	}}
+
+	update := diff.RepositoryUpdate()
+	if update.IsPrivate == nil || *update.IsPrivate || update.Description == nil || *update.Description != "" || update.FullDescription != nil {
+		t.Errorf("RepositoryUpdate() = %+v", update)
+	}
+
+	if want := map[string]string{"backend": PermissionWrite, "qa": ""}; !maps.Equal(diff.TeamChanges(), want) {
+		t.Errorf("TeamChanges() = %v, want %v", diff.TeamChanges(), want)
+	}
+	if want := map[string]string{"alice": PermissionAdmin}; !maps.Equal(diff.CollaboratorChanges(), want) {
+		t.Errorf("CollaboratorChanges() = %v, want %v", diff.CollaboratorChanges(), want)
+	}
+}
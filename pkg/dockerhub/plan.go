@@ -0,0 +1,309 @@
+/*
+Copyright © 2020 Yevhen Lebid ealebed@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dockerhub
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// PlanVersion is version of plan file format written by Save, LoadPlan refuses plans of other versions
+const PlanVersion = 1
+
+// Plan represents docker image tags and repositories deletions, which can be reviewed and applied later
+type Plan struct {
+	Version      int                  `json:"version"`
+	Organization string               `json:"organization"`
+	CreatedAt    time.Time            `json:"created_at"`
+	Tags         []*PlannedTag        `json:"tags"`
+	Repositories []*PlannedRepository `json:"repositories"`
+}
+
+// PlannedTag represents docker image tag deletion recorded in a plan
+type PlannedTag struct {
+	Repository  string    `json:"repository"`
+	Name        string    `json:"name"`
+	Digest      string    `json:"digest"`
+	LastUpdated time.Time `json:"last_updated"`
+	FullSize    int       `json:"full_size"`
+}
+
+// PlannedRepository represents docker repository deletion recorded in a plan
+type PlannedRepository struct {
+	Name        string    `json:"name"`
+	LastUpdated time.Time `json:"last_updated"`
+}
+
+// TruncatePlan represents docker image tags which TruncateTags would delete and keep in a single repository
+type TruncatePlan struct {
+	Repository string `json:"repository"`
+	Delete     []*Tag `json:"delete"`
+	Keep       []*Tag `json:"keep"`
+	// Protected lists tags, which would be deleted, but are protected
+	Protected []*Tag `json:"protected"`
+	// Shared lists tags, which would be deleted, but are kept in digest safe mode as they share digest with kept tags
+	Shared []*Tag `json:"shared"`
+}
+
+// DeleteSize returns total size (in bytes) of docker image tags selected for deletion
+func (p *TruncatePlan) DeleteSize() int {
+	var size int
+	for _, tag := range p.Delete {
+		size += tag.FullSize
+	}
+
+	return size
+}
+
+// SharedDigests returns names of kept docker image tags by digest, for digests shared with tags selected for deletion
+func (p *TruncatePlan) SharedDigests() map[string][]string {
+	deleted := map[string]bool{}
+	for _, tag := range p.Delete {
+		if digest := TagDigest(tag); digest != "" {
+			deleted[digest] = true
+		}
+	}
+
+	shared := map[string][]string{}
+	for _, kept := range [][]*Tag{p.Keep, p.Protected, p.Shared} {
+		for _, tag := range kept {
+			if digest := TagDigest(tag); deleted[digest] {
+				shared[digest] = append(shared[digest], tag.Name)
+			}
+		}
+	}
+
+	return shared
+}
+
+// PlanTruncateTags returns docker image tags that TruncateTags would delete, without deleting anything
+func (c *Client) PlanTruncateTags(image string, selector *TagSelector) (*TruncatePlan, error) {
+	return c.PlanTruncateTagsContext(context.Background(), image, selector)
+}
+
+// PlanTruncateTagsContext returns docker image tags that TruncateTags would delete, using provided context
+func (c *Client) PlanTruncateTagsContext(ctx context.Context, image string, selector *TagSelector) (*TruncatePlan, error) {
+	tags, err := c.ListTagsContext(ctx, image)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.protectedSelector(selector).Plan(image, tags, time.Now())
+}
+
+// protectedSelector returns copy of tag selector, additionally keeping tags protected by client
+func (c *Client) protectedSelector(selector *TagSelector) *TagSelector {
+	protected := *selector
+	protected.Protect = append(append([]string{}, c.Protect...), selector.Protect...)
+
+	return &protected
+}
+
+// NewPlan returns empty plan for provided organization
+func NewPlan(org string) *Plan {
+	return &Plan{
+		Version:      PlanVersion,
+		Organization: org,
+		CreatedAt:    time.Now().UTC(),
+		Tags:         []*PlannedTag{},
+		Repositories: []*PlannedRepository{},
+	}
+}
+
+// LoadPlan reads plan from JSON file, refusing plans of other format version or without organization
+func LoadPlan(path string) (*Plan, error) {
+	data, err := os.ReadFile(path) // #nosec G304 -- plan file path is provided by the user
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &Plan{}
+	if err := json.Unmarshal(data, plan); err != nil {
+		return nil, fmt.Errorf("failed to decode plan %s: %w", path, err)
+	}
+	if plan.Version != PlanVersion {
+		return nil, fmt.Errorf("unsupported plan %s version %d, should be %d", path, plan.Version, PlanVersion)
+	}
+	if plan.Organization == "" {
+		return nil, fmt.Errorf("plan %s has no organization", path)
+	}
+
+	return plan, nil
+}
+
+// Save writes plan to JSON file
+func (p *Plan) Save(path string) error {
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, append(data, '\n'), 0o600)
+}
+
+// AddTruncatePlan records docker image tags selected for deletion in truncate plan
+func (p *Plan) AddTruncatePlan(plan *TruncatePlan) {
+	for _, tag := range plan.Delete {
+		p.Tags = append(p.Tags, &PlannedTag{
+			Repository:  plan.Repository,
+			Name:        tag.Name,
+			Digest:      tag.Digest,
+			LastUpdated: tag.LastUpdated,
+			FullSize:    tag.FullSize,
+		})
+	}
+}
+
+// AddRepository records docker repository deletion
+func (p *Plan) AddRepository(repo *Repository) {
+	p.Repositories = append(p.Repositories, &PlannedRepository{
+		Name:        repo.Name,
+		LastUpdated: repo.LastUpdated,
+	})
+}
+
+// DeleteSize returns total size (in bytes) of docker image tags recorded in plan
+func (p *Plan) DeleteSize() int {
+	var size int
+	for _, tag := range p.Tags {
+		size += tag.FullSize
+	}
+
+	return size
+}
+
+// VerifyPlan checks that docker image tags and repositories recorded in plan weren't changed since the plan was made
+func (c *Client) VerifyPlan(plan *Plan) error {
+	return c.VerifyPlanContext(context.Background(), plan)
+}
+
+// VerifyPlanContext checks that docker image tags and repositories recorded in plan weren't changed, using provided context
+func (c *Client) VerifyPlanContext(ctx context.Context, plan *Plan) error {
+	_, _, err := c.verifyPlan(ctx, plan)
+
+	return err
+}
+
+// verifyPlan checks that plan wasn't changed, returning current docker image tags and protected digests of plan repositories
+func (c *Client) verifyPlan(ctx context.Context, plan *Plan) (map[string]map[string]*Tag, map[string]map[string]bool, error) {
+	var changes []error
+	current := map[string]map[string]*Tag{}
+	protectedDigests := map[string]map[string]bool{}
+
+	protected, err := c.protectedPatterns()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, planned := range plan.Tags {
+		tags, ok := current[planned.Repository]
+		if !ok {
+			list, err := c.ListTagsContext(ctx, planned.Repository)
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to list tags for %s: %w", planned.Repository, err)
+			}
+			tags = map[string]*Tag{}
+			for _, tag := range list {
+				tags[tag.Name] = tag
+			}
+			current[planned.Repository] = tags
+			protectedDigests[planned.Repository] = digestsMatching(protected, list)
+		}
+
+		tag, ok := tags[planned.Name]
+		switch {
+		case !ok:
+			changes = append(changes, fmt.Errorf("tag %s:%s no longer exists", planned.Repository, planned.Name))
+		case tag.Digest != planned.Digest:
+			changes = append(changes, fmt.Errorf("tag %s:%s digest changed from %q to %q", planned.Repository, planned.Name, planned.Digest, tag.Digest))
+		case !tag.LastUpdated.Equal(planned.LastUpdated):
+			changes = append(changes, fmt.Errorf("tag %s:%s was updated at %s", planned.Repository, planned.Name, tag.LastUpdated))
+		case protectedDigests[planned.Repository][TagDigest(tag)]:
+			changes = append(changes, fmt.Errorf("tag %s:%s shares digest with protected tag", planned.Repository, planned.Name))
+		}
+	}
+
+	for _, planned := range plan.Repositories {
+		repo, err := c.DescribeRepositoryContext(ctx, planned.Name)
+		if err != nil {
+			changes = append(changes, fmt.Errorf("repository %s: %w", planned.Name, err))
+			continue
+		}
+		if !repo.LastUpdated.Equal(planned.LastUpdated) {
+			changes = append(changes, fmt.Errorf("repository %s was updated at %s", planned.Name, repo.LastUpdated))
+		}
+	}
+
+	if len(changes) > 0 {
+		return nil, nil, fmt.Errorf("plan is outdated, %d change(s) since %s:\n%w", len(changes), plan.CreatedAt, errors.Join(changes...))
+	}
+
+	return current, protectedDigests, nil
+}
+
+// ApplyPlan deletes exactly the docker image tags and repositories recorded in plan, refusing if any of them changed
+func (c *Client) ApplyPlan(plan *Plan) error {
+	return c.ApplyPlanContext(context.Background(), plan)
+}
+
+// ApplyPlanContext deletes exactly the docker image tags and repositories recorded in plan, refusing if any of them changed,
+// after context is cancelled remaining deletions are skipped, while started deletion is finished
+func (c *Client) ApplyPlanContext(ctx context.Context, plan *Plan) error {
+	current, protectedDigests, err := c.verifyPlan(ctx, plan)
+	if err != nil {
+		return err
+	}
+
+	var failed int
+	for _, tag := range plan.Tags {
+		if ctx.Err() != nil {
+			c.Progress.skip(1)
+			continue
+		}
+		color.Green("\u2714  Delete tag %s", BW(tag.Repository+":"+tag.Name))
+		err := c.deleteDockerImageTag(ctx, tag.Repository, current[tag.Repository][tag.Name], protectedDigests[tag.Repository])
+		if err != nil && !errors.Is(err, ErrProtectedTag) {
+			failed++
+		}
+	}
+
+	for _, repo := range plan.Repositories {
+		if ctx.Err() != nil {
+			c.Progress.skip(1)
+			continue
+		}
+		color.Green("\u2714  Delete repository %s", BW(repo.Name))
+		if err := c.DeleteRepositoryContext(ctx, repo.Name); err != nil {
+			failed++
+		}
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("failed to apply %d of %d deletions", failed, len(plan.Tags)+len(plan.Repositories))
+	}
+
+	return nil
+}
@@ -0,0 +1,94 @@
+/*
+Copyright © 2020 Yevhen Lebid ealebed@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dockerhub
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPlanAddAndSave(t *testing.T) {
+	updated := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	plan := NewPlan("testorg")
+	plan.AddTruncatePlan(&TruncatePlan{
+		Repository: "image",
+		Delete: []*Tag{
+			{Name: "dev-1", Digest: "sha256:aaa", LastUpdated: updated, FullSize: 100},
+			{Name: "dev-2", Digest: "sha256:bbb", LastUpdated: updated, FullSize: 200},
+		},
+		Keep: []*Tag{{Name: "latest"}},
+	})
+	plan.AddRepository(&Repository{Name: "old-image", LastUpdated: updated})
+
+	if len(plan.Tags) != 2 {
+		t.Fatalf("AddTruncatePlan() recorded %d tags, want 2", len(plan.Tags))
+	}
+	if plan.Tags[0].Repository != "image" || plan.Tags[0].Digest != "sha256:aaa" {
+		t.Errorf("AddTruncatePlan() recorded %+v", plan.Tags[0])
+	}
+	if got := plan.DeleteSize(); got != 300 {
+		t.Errorf("DeleteSize() = %v, want 300", got)
+	}
+
+	path := filepath.Join(t.TempDir(), "plan.json")
+	if err := plan.Save(path); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	loaded, err := LoadPlan(path)
+	if err != nil {
+		t.Fatalf("LoadPlan() error = %v", err)
+	}
+	if loaded.Organization != "testorg" {
+		t.Errorf("LoadPlan() Organization = %v, want testorg", loaded.Organization)
+	}
+	if len(loaded.Tags) != 2 || !loaded.Tags[1].LastUpdated.Equal(updated) {
+		t.Errorf("LoadPlan() Tags = %+v", loaded.Tags)
+	}
+	if len(loaded.Repositories) != 1 || loaded.Repositories[0].Name != "old-image" {
+		t.Errorf("LoadPlan() Repositories = %+v", loaded.Repositories)
+	}
+}
+
+func TestLoadPlanErrors(t *testing.T) {
+	if _, err := LoadPlan(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("LoadPlan() with missing file should return error")
+	}
+
+	path := filepath.Join(t.TempDir(), "invalid.json")
+	if err := os.WriteFile(path, []byte("{invalid"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadPlan(path); err == nil {
+		t.Error("LoadPlan() with invalid JSON should return error")
+	}
+
+	for name, content := range map[string]string{
+		"no version":      `{"organization": "testorg", "tags": []}`,
+		"other version":   `{"version": 2, "organization": "testorg", "tags": []}`,
+		"no organization": `{"version": 1, "organization": "", "tags": []}`,
+	} {
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadPlan(path); err == nil {
+			t.Errorf("LoadPlan() of plan with %s should return error", name)
+		}
+	}
+}
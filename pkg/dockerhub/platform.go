@@ -0,0 +1,83 @@
+/*
+Copyright © 2020 Yevhen Lebid ealebed@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dockerhub
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Platform represents docker image platform like "linux/amd64" or "linux/arm/v7"
+type Platform struct {
+	OS           string
+	Architecture string
+	Variant      string
+}
+
+// ParsePlatform parses platform in "os/architecture[/variant]" format
+func ParsePlatform(s string) (Platform, error) {
+	parts := strings.Split(strings.ToLower(strings.TrimSpace(s)), "/")
+	if len(parts) < 2 || len(parts) > 3 || parts[0] == "" || parts[1] == "" {
+		return Platform{}, fmt.Errorf("invalid platform %q, should be os/architecture[/variant]", s)
+	}
+
+	p := Platform{OS: parts[0], Architecture: parts[1]}
+	if len(parts) == 3 {
+		p.Variant = parts[2]
+	}
+
+	return p, nil
+}
+
+// String returns platform in "os/architecture[/variant]" format
+func (p Platform) String() string {
+	if p.Variant == "" {
+		return p.OS + "/" + p.Architecture
+	}
+
+	return p.OS + "/" + p.Architecture + "/" + p.Variant
+}
+
+// Matches reports whether docker image is built for platform, platform without variant matches images of any variant
+func (p Platform) Matches(image *Image) bool {
+	return strings.EqualFold(image.OS, p.OS) && strings.EqualFold(image.Architecture, p.Architecture) &&
+		(p.Variant == "" || strings.EqualFold(image.Variant, p.Variant))
+}
+
+// ImagePlatform returns platform of docker image
+func ImagePlatform(image *Image) Platform {
+	return Platform{OS: image.OS, Architecture: image.Architecture, Variant: image.Variant}
+}
+
+// MissingPlatforms returns required platforms, docker image tag has no images for
+func MissingPlatforms(tag *Tag, required []Platform) []Platform {
+	missing := []Platform{}
+	for _, platform := range required {
+		found := false
+		for _, image := range tag.Images {
+			if platform.Matches(image) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			missing = append(missing, platform)
+		}
+	}
+
+	return missing
+}
@@ -0,0 +1,70 @@
+/*
+Copyright © 2020 Yevhen Lebid ealebed@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dockerhub
+
+import (
+	"testing"
+)
+
+func TestParsePlatform(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    Platform
+		wantErr bool
+	}{
+		{input: "linux/amd64", want: Platform{OS: "linux", Architecture: "amd64"}},
+		{input: " Linux/ARM/v7 ", want: Platform{OS: "linux", Architecture: "arm", Variant: "v7"}},
+		{input: "amd64", wantErr: true},
+		{input: "linux/", wantErr: true},
+		{input: "linux/arm/v7/extra", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParsePlatform(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParsePlatform() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParsePlatform() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMissingPlatforms(t *testing.T) {
+	tag := &Tag{Images: []*Image{
+		{OS: "linux", Architecture: "amd64"},
+		{OS: "linux", Architecture: "arm", Variant: "v6"},
+	}}
+
+	required := []Platform{
+		{OS: "linux", Architecture: "amd64"},
+		{OS: "linux", Architecture: "arm"},
+		{OS: "linux", Architecture: "arm", Variant: "v7"},
+		{OS: "linux", Architecture: "arm64"},
+	}
+
+	missing := MissingPlatforms(tag, required)
+	if len(missing) != 2 || missing[0].String() != "linux/arm/v7" || missing[1].String() != "linux/arm64" {
+		t.Errorf("MissingPlatforms() = %v, want [linux/arm/v7 linux/arm64]", missing)
+	}
+
+	if missing := MissingPlatforms(&Tag{}, required[:1]); len(missing) != 1 {
+		t.Errorf("MissingPlatforms() of tag without images = %v, want all required", missing)
+	}
+}
@@ -0,0 +1,87 @@
+/*
+Copyright © 2020 Yevhen Lebid ealebed@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dockerhub
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+)
+
+// Progress counts deletions of docker image tags, repositories and their permissions, safe for concurrent use
+type Progress struct {
+	completed atomic.Int64
+	failed    atomic.Int64
+	skipped   atomic.Int64
+}
+
+// Completed returns count of finished deletions
+func (p *Progress) Completed() int {
+	if p == nil {
+		return 0
+	}
+
+	return int(p.completed.Load())
+}
+
+// Failed returns count of deletions finished with error
+func (p *Progress) Failed() int {
+	if p == nil {
+		return 0
+	}
+
+	return int(p.failed.Load())
+}
+
+// Skipped returns count of deletions not started because of cancellation
+func (p *Progress) Skipped() int {
+	if p == nil {
+		return 0
+	}
+
+	return int(p.skipped.Load())
+}
+
+func (p *Progress) skip(n int) {
+	if p != nil {
+		p.skipped.Add(int64(n))
+	}
+}
+
+func (p *Progress) done(err error) {
+	switch {
+	case p == nil:
+	case err != nil:
+		p.failed.Add(1)
+	default:
+		p.completed.Add(1)
+	}
+}
+
+// deleteResource deletes docker hub resource unless context is already cancelled,
+// once started deletion isn't interrupted by context cancellation
+func (c *Client) deleteResource(ctx context.Context, url string) error {
+	if err := ctx.Err(); err != nil {
+		c.Progress.skip(1)
+		return err
+	}
+
+	_, err := c.doRequest(context.WithoutCancel(ctx), http.MethodDelete, url, nil)
+	c.Progress.done(err)
+
+	return err
+}
@@ -0,0 +1,108 @@
+/*
+Copyright © 2020 Yevhen Lebid ealebed@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dockerhub
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestDeleteResource(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if r.Method != http.MethodDelete {
+			t.Errorf("request method = %v, want DELETE", r.Method)
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	client := NewClient("testorg", "")
+	client.AuthToken = "token"
+
+	if err := client.deleteResource(context.Background(), server.URL+"/tag/"); err != nil {
+		t.Fatalf("deleteResource() error = %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := client.deleteResource(ctx, server.URL+"/tag/"); !errors.Is(err, context.Canceled) {
+		t.Errorf("deleteResource() with cancelled context error = %v, want context.Canceled", err)
+	}
+
+	if requests.Load() != 1 {
+		t.Errorf("server received %d requests, want 1", requests.Load())
+	}
+	if client.Progress.Completed() != 1 || client.Progress.Skipped() != 1 || client.Progress.Failed() != 0 {
+		t.Errorf("Progress = completed %d, failed %d, skipped %d, want 1, 0, 1",
+			client.Progress.Completed(), client.Progress.Failed(), client.Progress.Skipped())
+	}
+}
+
+func TestApplyTruncatePlanContextCancelled(t *testing.T) {
+	client := NewClient("testorg", "")
+	client.AuthToken = "token"
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	plan := &TruncatePlan{Repository: "image", Delete: []*Tag{{Name: "dev-1"}, {Name: "dev-2"}}}
+	if err := client.ApplyTruncatePlanContext(ctx, plan); !errors.Is(err, context.Canceled) {
+		t.Errorf("ApplyTruncatePlanContext() error = %v, want context.Canceled", err)
+	}
+	if client.Progress.Skipped() != 2 || client.Progress.Completed() != 0 {
+		t.Errorf("Progress = completed %d, skipped %d, want 0, 2", client.Progress.Completed(), client.Progress.Skipped())
+	}
+}
+
+func TestApplyTruncatePlanContextFailed(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.URL.Path, "/dev-2/") {
+			http.Error(w, `{"detail": "forbidden"}`, http.StatusForbidden)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	client := NewClient("testorg", server.URL)
+	client.AuthToken = "token"
+
+	plan := &TruncatePlan{Repository: "image", Delete: []*Tag{{Name: "dev-1"}, {Name: "dev-2"}, {Name: "dev-3"}}}
+	err := client.ApplyTruncatePlanContext(context.Background(), plan)
+	if err == nil || !strings.Contains(err.Error(), "failed to delete 1 of 3 tags") || !strings.Contains(err.Error(), "image:dev-2") {
+		t.Errorf("ApplyTruncatePlanContext() error = %v, want failure of image:dev-2", err)
+	}
+	if client.Progress.Completed() != 2 || client.Progress.Failed() != 1 {
+		t.Errorf("Progress = completed %d, failed %d, want 2, 1", client.Progress.Completed(), client.Progress.Failed())
+	}
+}
+
+func TestProgressNil(t *testing.T) {
+	var progress *Progress
+	progress.skip(1)
+	progress.done(nil)
+	if progress.Completed() != 0 || progress.Failed() != 0 || progress.Skipped() != 0 {
+		t.Error("nil Progress should count nothing")
+	}
+}
@@ -0,0 +1,104 @@
+/*
+Copyright © 2020 Yevhen Lebid ealebed@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dockerhub
+
+import (
+	"time"
+)
+
+// RepositoryReport represents storage and usage statistics of docker repository tags, sizes are in bytes
+type RepositoryReport struct {
+	Repository string `json:"repository"`
+	Tags       int    `json:"tags"`
+	// Size is total size of tags, as reported by docker hub (full_size)
+	Size int `json:"size"`
+	// ImagesSize is total size of all platform images of tags
+	ImagesSize   int       `json:"images_size"`
+	AvgSize      int       `json:"avg_size"`
+	PullCount    int       `json:"pull_count"`
+	InactiveTags int       `json:"inactive_tags"`
+	InactiveSize int       `json:"inactive_size"`
+	OldestPush   time.Time `json:"oldest_push,omitzero"`
+	NewestPush   time.Time `json:"newest_push,omitzero"`
+	LastPull     time.Time `json:"last_pull,omitzero"`
+}
+
+// NewRepositoryReport returns statistics of docker repository, computed from its already listed tags
+func NewRepositoryReport(repo *Repository, tags []*Tag) *RepositoryReport {
+	r := &RepositoryReport{Repository: repo.Name, PullCount: repo.PullCount}
+	for _, tag := range tags {
+		r.Tags++
+		r.Size += tag.FullSize
+		for _, image := range tag.Images {
+			r.ImagesSize += image.Size
+		}
+		if tag.TagStatus == "inactive" {
+			r.InactiveTags++
+			r.InactiveSize += tag.FullSize
+		}
+		r.addTimes(TagPushedAt(tag), TagPushedAt(tag), tag.TagLastPulled)
+	}
+	r.setAvgSize()
+
+	return r
+}
+
+// SumReports returns organization-wide totals of docker repositories statistics, named as provided
+func SumReports(name string, reports []*RepositoryReport) *RepositoryReport {
+	total := &RepositoryReport{Repository: name}
+	for _, r := range reports {
+		total.Tags += r.Tags
+		total.Size += r.Size
+		total.ImagesSize += r.ImagesSize
+		total.PullCount += r.PullCount
+		total.InactiveTags += r.InactiveTags
+		total.InactiveSize += r.InactiveSize
+		total.addTimes(r.OldestPush, r.NewestPush, r.LastPull)
+	}
+	total.setAvgSize()
+
+	return total
+}
+
+// addTimes extends push range and last pull time of report, zero times are ignored
+func (r *RepositoryReport) addTimes(oldestPush, newestPush, lastPull time.Time) {
+	if !oldestPush.IsZero() && (r.OldestPush.IsZero() || oldestPush.Before(r.OldestPush)) {
+		r.OldestPush = oldestPush
+	}
+	if newestPush.After(r.NewestPush) {
+		r.NewestPush = newestPush
+	}
+	if lastPull.After(r.LastPull) {
+		r.LastPull = lastPull
+	}
+}
+
+// AvgSizeMB returns average size of tags in megabytes
+func (r *RepositoryReport) AvgSizeMB() float64 {
+	if r.Tags == 0 {
+		return 0
+	}
+
+	return float64(r.Size) / float64(r.Tags) / 1024 / 1024
+}
+
+func (r *RepositoryReport) setAvgSize() {
+	r.AvgSize = 0
+	if r.Tags > 0 {
+		r.AvgSize = r.Size / r.Tags
+	}
+}
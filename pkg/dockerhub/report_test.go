@@ -0,0 +1,52 @@
+/*
+Copyright © 2020 Yevhen Lebid ealebed@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dockerhub
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNewRepositoryReport(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	tags := []*Tag{
+		{Name: "latest", FullSize: 300, TagStatus: "active", TagLastPushed: now, TagLastPulled: now.Add(time.Hour),
+			Images: []*Image{{Size: 300}, {Size: 280}}},
+		{Name: "1.0.0", FullSize: 100, TagStatus: "inactive", TagLastPushed: now.AddDate(0, -3, 0)},
+		{Name: "0.9.0", FullSize: 200, TagStatus: "inactive", LastUpdated: now.AddDate(-1, 0, 0), Images: []*Image{{Size: 200}}},
+	}
+
+	r := NewRepositoryReport(&Repository{Name: "api", PullCount: 42}, tags)
+	want := RepositoryReport{
+		Repository: "api", Tags: 3, Size: 600, ImagesSize: 780, AvgSize: 200, PullCount: 42, InactiveTags: 2, InactiveSize: 300,
+		OldestPush: now.AddDate(-1, 0, 0), NewestPush: now, LastPull: now.Add(time.Hour),
+	}
+	if *r != want {
+		t.Errorf("NewRepositoryReport() = %+v, want %+v", *r, want)
+	}
+
+	empty := NewRepositoryReport(&Repository{Name: "web"}, nil)
+	if empty.Tags != 0 || empty.AvgSize != 0 || !empty.OldestPush.IsZero() {
+		t.Errorf("NewRepositoryReport() of repository without tags = %+v", *empty)
+	}
+
+	total := SumReports("total", []*RepositoryReport{r, empty, {Repository: "db", Tags: 1, Size: 1000, PullCount: 8, NewestPush: now.AddDate(0, 0, 1)}})
+	if total.Repository != "total" || total.Tags != 4 || total.Size != 1600 || total.AvgSize != 400 || total.PullCount != 50 ||
+		total.InactiveSize != 300 || !total.OldestPush.Equal(want.OldestPush) || !total.NewestPush.Equal(now.AddDate(0, 0, 1)) {
+		t.Errorf("SumReports() = %+v", *total)
+	}
+}
@@ -18,6 +18,7 @@ package dockerhub
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -27,8 +28,13 @@ import (
 
 // ListRepositories returns list of docker images from docker hub
 func (c *Client) ListRepositories() (repos []*Repository, err error) {
+	return c.ListRepositoriesContext(context.Background())
+}
+
+// ListRepositoriesContext returns list of docker images from docker hub, using provided context
+func (c *Client) ListRepositoriesContext(ctx context.Context) (repos []*Repository, err error) {
 	repos = []*Repository{}
-	output, err := c.listRepositoriesRequest("")
+	output, err := c.listRepositoriesRequest(ctx, "")
 	if err != nil {
 		return nil, err
 	}
@@ -40,7 +46,7 @@ func (c *Client) ListRepositories() (repos []*Repository, err error) {
 		if next == "" {
 			return repos, nil
 		}
-		output, err := c.listRepositoriesRequest(next)
+		output, err := c.listRepositoriesRequest(ctx, next)
 		if err != nil {
 			return nil, err
 		}
@@ -49,15 +55,15 @@ func (c *Client) ListRepositories() (repos []*Repository, err error) {
 	}
 }
 
-func (c *Client) listRepositoriesRequest(next string) (*RepositoryList, error) {
+func (c *Client) listRepositoriesRequest(ctx context.Context, next string) (*RepositoryList, error) {
 	var url string
 	if next != "" {
 		url = next
 	} else {
-		url = fmt.Sprintf("%s/%s/?page=1&page_size=100", RepositoriesURL, c.ORG)
+		url = c.apiURL("repositories/%s/?page=1&page_size=100", c.ORG)
 	}
 
-	data, err := c.doRequest(http.MethodGet, url, nil)
+	data, err := c.doRequest(ctx, http.MethodGet, url, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -71,9 +77,118 @@ func (c *Client) listRepositoriesRequest(next string) (*RepositoryList, error) {
 	return output, nil
 }
 
+// createRepositoryRequest represents docker repository fields sent on repository creation
+type createRepositoryRequest struct {
+	Namespace       string `json:"namespace"`
+	Name            string `json:"name"`
+	Description     string `json:"description"`
+	FullDescription string `json:"full_description"`
+	IsPrivate       bool   `json:"is_private"`
+}
+
+// CreateRepository creates docker repository on docker hub with provided name, visibility and descriptions
+/* curl \
+   -H "Authorization: JWT ${TOKEN}" \
+   -H "Content-Type: application/json" \
+   -X POST \
+   -d '{"namespace": "${ORG}", "name": "${IMAGE}", "description": "...", "full_description": "...", "is_private": true}' \
+   https://hub.docker.com/v2/repositories/
+*/
+func (c *Client) CreateRepository(repo *Repository) (*Repository, error) {
+	return c.CreateRepositoryContext(context.Background(), repo)
+}
+
+// CreateRepositoryContext creates docker repository on docker hub, using provided context,
+// repository is created in client organization, unless its namespace is provided
+func (c *Client) CreateRepositoryContext(ctx context.Context, repo *Repository) (*Repository, error) {
+	request := &createRepositoryRequest{
+		Namespace:       repo.Namespace,
+		Name:            repo.Name,
+		Description:     repo.Description,
+		FullDescription: repo.FullDescription,
+		IsPrivate:       repo.IsPrivate,
+	}
+	if request.Namespace == "" {
+		request.Namespace = c.ORG
+	}
+
+	payload, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := c.doRequest(ctx, http.MethodPost, c.apiURL("repositories/"), bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+
+	created := &Repository{}
+	if err := json.NewDecoder(bytes.NewReader(data)).Decode(created); err != nil {
+		return nil, err
+	}
+
+	return created, nil
+}
+
+// RepositoryUpdate represents docker repository fields to update, nil fields are left as is
+type RepositoryUpdate struct {
+	Description     *string `json:"description,omitempty"`
+	FullDescription *string `json:"full_description,omitempty"`
+	IsPrivate       *bool   `json:"-"`
+}
+
+// UpdateRepository updates descriptions and visibility of docker repository on docker hub
+/* curl \
+   -H "Authorization: JWT ${TOKEN}" \
+   -H "Content-Type: application/json" \
+   -X PATCH \
+   -d '{"description": "...", "full_description": "..."}' \
+   https://hub.docker.com/v2/repositories/${ORG}/${IMAGE}/
+   curl \
+   -H "Authorization: JWT ${TOKEN}" \
+   -H "Content-Type: application/json" \
+   -X POST \
+   -d '{"is_private": true}' \
+   https://hub.docker.com/v2/repositories/${ORG}/${IMAGE}/privacy/
+*/
+func (c *Client) UpdateRepository(image string, update *RepositoryUpdate) error {
+	return c.UpdateRepositoryContext(context.Background(), image, update)
+}
+
+// UpdateRepositoryContext updates descriptions and visibility of docker repository on docker hub, using provided context,
+// visibility is changed by separate request, after descriptions
+func (c *Client) UpdateRepositoryContext(ctx context.Context, image string, update *RepositoryUpdate) error {
+	if update.Description != nil || update.FullDescription != nil {
+		payload, err := json.Marshal(update)
+		if err != nil {
+			return err
+		}
+		if _, err := c.doRequest(ctx, http.MethodPatch, c.apiURL("repositories/%s/%s/", c.ORG, image), bytes.NewReader(payload)); err != nil {
+			return err
+		}
+	}
+
+	if update.IsPrivate != nil {
+		payload, err := json.Marshal(map[string]bool{"is_private": *update.IsPrivate})
+		if err != nil {
+			return err
+		}
+		if _, err := c.doRequest(ctx, http.MethodPost, c.apiURL("repositories/%s/%s/privacy/", c.ORG, image), bytes.NewReader(payload)); err != nil {
+			return fmt.Errorf("failed to change visibility: %w", err)
+		}
+	}
+
+	return nil
+}
+
 // DescribeRepository print details about docker repository from docker hub
 func (c *Client) DescribeRepository(image string) (*Repository, error) {
-	data, err := c.doRequest(http.MethodGet, fmt.Sprintf("%s/%s/%s", RepositoriesURL, c.ORG, image), nil)
+	return c.DescribeRepositoryContext(context.Background(), image)
+}
+
+// DescribeRepositoryContext returns details about docker repository from docker hub, using provided context
+func (c *Client) DescribeRepositoryContext(ctx context.Context, image string) (*Repository, error) {
+	data, err := c.doRequest(ctx, http.MethodGet, c.apiURL("repositories/%s/%s", c.ORG, image), nil)
 	if err != nil {
 		return nil, err
 	}
@@ -94,8 +209,14 @@ func (c *Client) DescribeRepository(image string) (*Repository, error) {
    https://hub.docker.com/v2/repositories/${ORG}/${IMAGE}/
 */
 func (c *Client) DeleteRepository(image string) error {
-	if _, err := c.doRequest(http.MethodDelete, fmt.Sprintf("%s/%s/%s/", RepositoriesURL, c.ORG, image), nil); err != nil {
+	return c.DeleteRepositoryContext(context.Background(), image)
+}
+
+// DeleteRepositoryContext delete docker repository from docker hub, unless context is already cancelled
+func (c *Client) DeleteRepositoryContext(ctx context.Context, image string) error {
+	if err := c.deleteResource(ctx, c.apiURL("repositories/%s/%s/", c.ORG, image)); err != nil {
 		color.Red("Error while deleting docker image: %s", err)
+		return err
 	}
 
 	return nil
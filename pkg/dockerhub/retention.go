@@ -0,0 +1,185 @@
+/*
+Copyright © 2020 Yevhen Lebid ealebed@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dockerhub
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// RetentionPolicy represents declarative retention rules for docker image tags, loaded from YAML or JSON file
+type RetentionPolicy struct {
+	Rules []*RetentionRule `yaml:"rules"`
+}
+
+// RetentionRule represents tags retention for repositories, matching name regular expression
+type RetentionRule struct {
+	Name               string   `yaml:"name"`
+	Repositories       string   `yaml:"repositories"`
+	Tags               string   `yaml:"tags"`
+	KeepLast           int      `yaml:"keep_last"`
+	MaxAge             Duration `yaml:"max_age"`
+	KeepIfPulledWithin Duration `yaml:"keep_if_pulled_within"`
+	Protect            []string `yaml:"protect"`
+	DigestSafe         bool     `yaml:"digest_safe"`
+	Versions           string   `yaml:"versions"`
+	Prereleases        bool     `yaml:"prereleases"`
+	KeepPatches        int      `yaml:"keep_patches"`
+}
+
+// Duration represents time duration, which additionally accepts days ("30d") and weeks ("2w") units
+type Duration time.Duration
+
+// UnmarshalYAML decodes duration from string like "30d" or "12h"
+func (d *Duration) UnmarshalYAML(value *yaml.Node) error {
+	duration, err := ParseDuration(value.Value)
+	if err != nil {
+		return err
+	}
+	*d = Duration(duration)
+
+	return nil
+}
+
+// ParseDuration parses duration string, additionally accepting days ("30d") and weeks ("2w") units
+func ParseDuration(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if s == "" || s == "0" {
+		return 0, nil
+	}
+
+	units := map[string]time.Duration{
+		"d": 24 * time.Hour,
+		"w": 7 * 24 * time.Hour,
+	}
+	if unit, ok := units[s[len(s)-1:]]; ok {
+		n, err := strconv.Atoi(s[:len(s)-1])
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		return time.Duration(n) * unit, nil
+	}
+
+	duration, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+
+	return duration, nil
+}
+
+// LoadRetentionPolicy reads retention policy from YAML or JSON file, unknown fields are rejected to catch typos
+func LoadRetentionPolicy(path string) (*RetentionPolicy, error) {
+	data, err := os.ReadFile(path) // #nosec G304 -- policy file path is provided by the user
+	if err != nil {
+		return nil, err
+	}
+
+	policy := &RetentionPolicy{}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(policy); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to decode retention policy %s: %w", path, err)
+	}
+
+	if err := policy.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid retention policy %s: %w", path, err)
+	}
+
+	return policy, nil
+}
+
+// Validate checks that all regular expressions in retention policy rules compile, and every rule selects tags,
+// as rule without selection criteria would delete all unprotected tags
+func (p *RetentionPolicy) Validate() error {
+	for i, rule := range p.Rules {
+		if rule.Tags == "" && rule.MaxAge == 0 && rule.KeepIfPulledWithin == 0 && rule.KeepLast == 0 &&
+			rule.Versions == "" && !rule.Prereleases && rule.KeepPatches == 0 {
+			return fmt.Errorf("rule %d: should set tags, max_age, keep_if_pulled_within, keep_last, versions, prereleases or keep_patches", i+1)
+		}
+		if _, err := regexp.Compile(rule.Repositories); err != nil {
+			return fmt.Errorf("rule %d: invalid repositories regular expression: %w", i+1, err)
+		}
+		if _, err := rule.Selector().Plan("", nil, time.Time{}); err != nil {
+			return fmt.Errorf("rule %d: %w", i+1, err)
+		}
+		if rule.KeepLast < 0 {
+			return fmt.Errorf("rule %d: keep_last should not be negative", i+1)
+		}
+		if rule.KeepPatches < 0 {
+			return fmt.Errorf("rule %d: keep_patches should not be negative", i+1)
+		}
+	}
+
+	return nil
+}
+
+// Rule returns first retention rule, matching provided repository name, or nil
+func (p *RetentionPolicy) Rule(repository string) *RetentionRule {
+	for _, rule := range p.Rules {
+		if matched, _ := regexp.MatchString(rule.Repositories, repository); matched {
+			return rule
+		}
+	}
+
+	return nil
+}
+
+// Selector returns tag selector, which implements retention rule
+func (r *RetentionRule) Selector() *TagSelector {
+	return &TagSelector{
+		TagRegex:     r.Tags,
+		KeepLast:     r.KeepLast,
+		OlderThan:    time.Duration(r.MaxAge),
+		PulledBefore: time.Duration(r.KeepIfPulledWithin),
+		Protect:      r.Protect,
+		DigestSafe:   r.DigestSafe,
+		Versions:     r.Versions,
+		Prereleases:  r.Prereleases,
+		KeepPatches:  r.KeepPatches,
+	}
+}
+
+// PlanRetention returns docker image tags that retention policy would delete, without deleting anything
+func (c *Client) PlanRetention(image string, policy *RetentionPolicy) (*TruncatePlan, error) {
+	return c.PlanRetentionContext(context.Background(), image, policy)
+}
+
+// PlanRetentionContext returns docker image tags that retention policy would delete, using provided context
+func (c *Client) PlanRetentionContext(ctx context.Context, image string, policy *RetentionPolicy) (*TruncatePlan, error) {
+	tags, err := c.ListTagsContext(ctx, image)
+	if err != nil {
+		return nil, err
+	}
+
+	rule := policy.Rule(image)
+	if rule == nil {
+		return &TruncatePlan{Repository: image, Keep: tags}, nil
+	}
+
+	return c.protectedSelector(rule.Selector()).Plan(image, tags, time.Now())
+}
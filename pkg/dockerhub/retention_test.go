@@ -0,0 +1,181 @@
+/*
+Copyright © 2020 Yevhen Lebid ealebed@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dockerhub
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{input: "", want: 0},
+		{input: "0", want: 0},
+		{input: "30d", want: 30 * 24 * time.Hour},
+		{input: "2w", want: 14 * 24 * time.Hour},
+		{input: "12h", want: 12 * time.Hour},
+		{input: " 1d ", want: 24 * time.Hour},
+		{input: "d", wantErr: true},
+		{input: "-1d", wantErr: true},
+		{input: "1.5d", wantErr: true},
+		{input: "month", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseDuration(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseDuration(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseDuration(%q) = %v, want %v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func writePolicy(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadRetentionPolicy(t *testing.T) {
+	yamlPolicy := `
+rules:
+  - name: dev builds
+    repositories: ^api-
+    tags: ^dev-
+    keep_last: 5
+    max_age: 2w
+    keep_if_pulled_within: 30d
+    protect: [latest, 'v\d+\.\d+\.\d+']
+    digest_safe: true
+  - repositories: .*
+    keep_last: 30
+  - name: releases
+    repositories: -release$
+    versions: <2.0.0
+    keep_patches: 2
+`
+	jsonPolicy := `{"rules": [{"repositories": "^api-", "tags": "^dev-", "keep_last": 5, "max_age": "14d", "keep_if_pulled_within": "720h", ` +
+		`"protect": ["latest"], "digest_safe": true}]}`
+
+	for name, content := range map[string]string{"retention.yaml": yamlPolicy, "retention.json": jsonPolicy} {
+		t.Run(name, func(t *testing.T) {
+			policy, err := LoadRetentionPolicy(writePolicy(t, name, content))
+			if err != nil {
+				t.Fatalf("LoadRetentionPolicy() error = %v", err)
+			}
+
+			rule := policy.Rule("api-users")
+			if rule == nil {
+				t.Fatal("Rule() should match api-users")
+			}
+			selector := rule.Selector()
+			if selector.TagRegex != "^dev-" || selector.KeepLast != 5 {
+				t.Errorf("Selector() = %+v", selector)
+			}
+			if selector.OlderThan != 14*24*time.Hour {
+				t.Errorf("Selector() OlderThan = %v, want 336h", selector.OlderThan)
+			}
+			if selector.PulledBefore != 30*24*time.Hour {
+				t.Errorf("Selector() PulledBefore = %v, want 720h", selector.PulledBefore)
+			}
+			if len(selector.Protect) == 0 || selector.Protect[0] != "latest" {
+				t.Errorf("Selector() Protect = %v", selector.Protect)
+			}
+			if !selector.DigestSafe {
+				t.Error("Selector() DigestSafe should be true")
+			}
+		})
+	}
+
+	policy, err := LoadRetentionPolicy(writePolicy(t, "retention.yaml", yamlPolicy))
+	if err != nil {
+		t.Fatalf("LoadRetentionPolicy() error = %v", err)
+	}
+	if selector := policy.Rules[2].Selector(); selector.Versions != "<2.0.0" || selector.KeepPatches != 2 {
+		t.Errorf("Selector() = %+v", selector)
+	}
+}
+
+func TestRetentionPolicyRule(t *testing.T) {
+	policy := &RetentionPolicy{Rules: []*RetentionRule{
+		{Name: "api", Repositories: "^api-"},
+		{Name: "workers", Repositories: "-worker$"},
+	}}
+
+	tests := []struct {
+		repository string
+		want       string
+	}{
+		{repository: "api-users", want: "api"},
+		{repository: "api-worker", want: "api"},
+		{repository: "billing-worker", want: "workers"},
+		{repository: "frontend", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.repository, func(t *testing.T) {
+			rule := policy.Rule(tt.repository)
+			got := ""
+			if rule != nil {
+				got = rule.Name
+			}
+			if got != tt.want {
+				t.Errorf("Rule(%q) = %q, want %q", tt.repository, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadRetentionPolicyErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{name: "invalid yaml", content: "rules: [unclosed"},
+		{name: "invalid duration", content: "rules:\n  - repositories: .*\n    max_age: month\n"},
+		{name: "invalid repositories regex", content: "rules:\n  - repositories: '('\n"},
+		{name: "invalid tags regex", content: "rules:\n  - repositories: .*\n    tags: '['\n"},
+		{name: "negative keep_last", content: "rules:\n  - repositories: .*\n    keep_last: -1\n"},
+		{name: "misspelled keys", content: "rules:\n  - repositories: .*\n    keep-last: 5\n    max-age: 30d\n"},
+		{name: "no selection criteria", content: "rules:\n  - name: everything\n    repositories: .*\n    protect: [latest]\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := LoadRetentionPolicy(writePolicy(t, "policy.yaml", tt.content)); err == nil {
+				t.Error("LoadRetentionPolicy() should return error")
+			}
+		})
+	}
+
+	if _, err := LoadRetentionPolicy(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Error("LoadRetentionPolicy() with missing file should return error")
+	}
+}
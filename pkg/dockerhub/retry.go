@@ -0,0 +1,255 @@
+/*
+Copyright © 2020 Yevhen Lebid ealebed@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dockerhub
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// Default retry settings of docker hub client
+const (
+	DefaultMaxRetries = 5
+	DefaultMinBackoff = 500 * time.Millisecond
+	DefaultMaxBackoff = 30 * time.Second
+	DefaultMaxWait    = 10 * time.Minute
+)
+
+// RetryPolicy represents retries of rate limited (429) requests, server errors (5xx) and connection resets
+// with jittered exponential backoff, honouring Retry-After and X-RateLimit-* headers,
+// every attempt is separate HTTP client call, so client timeout limits single attempt only
+type RetryPolicy struct {
+	// MaxRetries limits retries of single request
+	MaxRetries int
+	// MinBackoff and MaxBackoff bound exponential backoff between retries
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	// MaxWait limits waiting for rate limit reset, longer waits fail request instead
+	MaxWait time.Duration
+	// Verbose prints every retry
+	Verbose bool
+
+	retries atomic.Int64
+	mu      sync.Mutex
+	resetAt time.Time
+	sleep   func(ctx context.Context, d time.Duration) error
+}
+
+// NewRetryPolicy returns retry policy with default settings
+func NewRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{
+		MaxRetries: DefaultMaxRetries,
+		MinBackoff: DefaultMinBackoff,
+		MaxBackoff: DefaultMaxBackoff,
+		MaxWait:    DefaultMaxWait,
+	}
+}
+
+// Retries returns total count of retried requests
+func (p *RetryPolicy) Retries() int {
+	return int(p.retries.Load())
+}
+
+// Do executes HTTP request with provided client, retrying it when docker hub is rate limiting or temporarily unavailable
+func (p *RetryPolicy) Do(client *http.Client, req *http.Request) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		if err := p.waitRateLimit(req.Context()); err != nil {
+			return nil, err
+		}
+
+		if attempt > 0 && req.Body != nil {
+			if req.GetBody == nil {
+				return nil, errors.New("request body can't be replayed for retry")
+			}
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+
+		// request URL is built by client from docker hub base URL
+		resp, err := client.Do(req) // #nosec G704
+		if resp != nil {
+			p.updateRateLimit(resp)
+		}
+
+		wait, retry := p.retryAfter(req, resp, err, attempt)
+		if !retry || attempt >= p.MaxRetries || req.Context().Err() != nil {
+			return resp, err
+		}
+
+		var reason string
+		if err != nil {
+			reason = err.Error()
+		} else {
+			reason = resp.Status
+			drainBody(resp)
+		}
+
+		p.retries.Add(1)
+		if p.Verbose {
+			color.Yellow("Retry %d/%d %s %s: %s, waiting %s", attempt+1, p.MaxRetries, req.Method, req.URL, reason, wait.Round(time.Millisecond))
+		}
+
+		if err := p.wait(req.Context(), wait); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// retryAfter reports whether request should be retried and how long to wait before that
+func (p *RetryPolicy) retryAfter(req *http.Request, resp *http.Response, err error, attempt int) (time.Duration, bool) {
+	switch {
+	case err != nil:
+		return p.backoff(attempt), idempotent(req.Method) && retryableError(err)
+	case resp.StatusCode == http.StatusTooManyRequests:
+		wait, ok := rateLimitWait(resp.Header, time.Now())
+		if !ok {
+			wait = p.backoff(attempt)
+		}
+		return wait, wait <= p.MaxWait
+	case resp.StatusCode >= http.StatusInternalServerError && resp.StatusCode != http.StatusNotImplemented:
+		if wait, ok := rateLimitWait(resp.Header, time.Now()); ok && wait <= p.MaxWait {
+			return wait, idempotent(req.Method)
+		}
+		return p.backoff(attempt), idempotent(req.Method)
+	}
+
+	return 0, false
+}
+
+// backoff returns jittered exponential delay for provided attempt
+func (p *RetryPolicy) backoff(attempt int) time.Duration {
+	delay := p.MaxBackoff
+	if attempt < 32 {
+		if d := p.MinBackoff << attempt; d > 0 && d < p.MaxBackoff {
+			delay = d
+		}
+	}
+	if delay <= 0 {
+		return 0
+	}
+
+	return delay/2 + rand.N(delay/2+1) // #nosec G404 -- jitter doesn't need cryptographic randomness
+}
+
+// updateRateLimit remembers rate limit reset time, when docker hub reports no remaining requests
+func (p *RetryPolicy) updateRateLimit(resp *http.Response) {
+	if resp.Header.Get("X-RateLimit-Remaining") != "0" {
+		return
+	}
+
+	reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64)
+	if err != nil {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if resetAt := time.Unix(reset, 0); resetAt.After(p.resetAt) {
+		p.resetAt = resetAt
+	}
+}
+
+// waitRateLimit waits until rate limit reset, when previous response reported no remaining requests
+func (p *RetryPolicy) waitRateLimit(ctx context.Context) error {
+	p.mu.Lock()
+	wait := time.Until(p.resetAt)
+	p.mu.Unlock()
+
+	if wait <= 0 || wait > p.MaxWait {
+		return nil
+	}
+	if p.Verbose {
+		color.Yellow("Rate limit exhausted, waiting %s for reset", wait.Round(time.Second))
+	}
+
+	return p.wait(ctx, wait)
+}
+
+func (p *RetryPolicy) wait(ctx context.Context, d time.Duration) error {
+	if p.sleep != nil {
+		return p.sleep(ctx, d)
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// rateLimitWait returns delay requested by Retry-After (seconds or HTTP date) or X-RateLimit-Reset (unix time) headers
+func rateLimitWait(header http.Header, now time.Time) (time.Duration, bool) {
+	if value := header.Get("Retry-After"); value != "" {
+		if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+			return time.Duration(seconds) * time.Second, true
+		}
+		if date, err := http.ParseTime(value); err == nil {
+			return max(date.Sub(now), 0), true
+		}
+	}
+
+	if value := header.Get("X-RateLimit-Reset"); value != "" {
+		if reset, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return max(time.Unix(reset, 0).Sub(now), 0), true
+		}
+	}
+
+	return 0, false
+}
+
+// retryableError reports whether request error is transient connection failure
+func retryableError(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	return errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.EPIPE) ||
+		errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// idempotent reports whether request with provided method can be safely repeated
+func idempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+
+	return false
+}
+
+func drainBody(resp *http.Response) {
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+	_ = resp.Body.Close()
+}
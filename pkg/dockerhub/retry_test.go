@@ -0,0 +1,274 @@
+/*
+Copyright © 2020 Yevhen Lebid ealebed@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dockerhub
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestRetryPolicy returns retry policy recording waits instead of sleeping
+func newTestRetryPolicy(waits *[]time.Duration) *RetryPolicy {
+	policy := NewRetryPolicy()
+	policy.sleep = func(_ context.Context, d time.Duration) error {
+		*waits = append(*waits, d)
+		return nil
+	}
+
+	return policy
+}
+
+// sequenceServer responds with provided status codes in order, then with 200
+func sequenceServer(t *testing.T, header http.Header, statuses ...int) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(requests.Add(1))
+		if n <= len(statuses) {
+			for key, values := range header {
+				w.Header()[key] = values
+			}
+			w.WriteHeader(statuses[n-1])
+			return
+		}
+		_, _ = w.Write([]byte(`{"count": 1}`))
+	}))
+	t.Cleanup(server.Close)
+
+	return server, &requests
+}
+
+func TestRetryPolicyDo(t *testing.T) {
+	tests := []struct {
+		name         string
+		method       string
+		header       http.Header
+		statuses     []int
+		maxRetries   int
+		wantStatus   int
+		wantRequests int32
+		wantWaits    []time.Duration
+	}{
+		{
+			name:         "rate limited with Retry-After",
+			method:       http.MethodGet,
+			header:       http.Header{"Retry-After": {"7"}},
+			statuses:     []int{http.StatusTooManyRequests},
+			maxRetries:   DefaultMaxRetries,
+			wantStatus:   http.StatusOK,
+			wantRequests: 2,
+			wantWaits:    []time.Duration{7 * time.Second},
+		},
+		{
+			name:         "server errors",
+			method:       http.MethodDelete,
+			statuses:     []int{http.StatusBadGateway, http.StatusServiceUnavailable},
+			maxRetries:   DefaultMaxRetries,
+			wantStatus:   http.StatusOK,
+			wantRequests: 3,
+		},
+		{
+			name:         "retries exhausted",
+			method:       http.MethodGet,
+			statuses:     []int{http.StatusInternalServerError, http.StatusInternalServerError, http.StatusInternalServerError},
+			maxRetries:   2,
+			wantStatus:   http.StatusInternalServerError,
+			wantRequests: 3,
+		},
+		{
+			name:         "not idempotent request isn't retried on server error",
+			method:       http.MethodPost,
+			statuses:     []int{http.StatusInternalServerError},
+			maxRetries:   DefaultMaxRetries,
+			wantStatus:   http.StatusInternalServerError,
+			wantRequests: 1,
+		},
+		{
+			name:         "client error isn't retried",
+			method:       http.MethodGet,
+			statuses:     []int{http.StatusNotFound},
+			maxRetries:   DefaultMaxRetries,
+			wantStatus:   http.StatusNotFound,
+			wantRequests: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, requests := sequenceServer(t, tt.header, tt.statuses...)
+
+			var waits []time.Duration
+			policy := newTestRetryPolicy(&waits)
+			policy.MaxRetries = tt.maxRetries
+
+			req, err := http.NewRequest(tt.method, server.URL, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp, err := policy.Do(server.Client(), req)
+			if err != nil {
+				t.Fatalf("Do() error = %v", err)
+			}
+			_ = resp.Body.Close()
+
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("Do() status = %v, want %v", resp.StatusCode, tt.wantStatus)
+			}
+			if requests.Load() != tt.wantRequests {
+				t.Errorf("server received %d requests, want %d", requests.Load(), tt.wantRequests)
+			}
+			if policy.Retries() != int(tt.wantRequests)-1 {
+				t.Errorf("Retries() = %d, want %d", policy.Retries(), tt.wantRequests-1)
+			}
+			if tt.wantWaits != nil && (len(waits) != len(tt.wantWaits) || waits[0] != tt.wantWaits[0]) {
+				t.Errorf("waits = %v, want %v", waits, tt.wantWaits)
+			}
+		})
+	}
+}
+
+func TestRetryPolicyReplaysBody(t *testing.T) {
+	var bodies []string
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := make([]byte, r.ContentLength)
+		_, _ = r.Body.Read(body)
+		bodies = append(bodies, string(body))
+		if requests.Add(1) == 1 {
+			w.WriteHeader(http.StatusTooManyRequests)
+		}
+	}))
+	defer server.Close()
+
+	var waits []time.Duration
+	policy := newTestRetryPolicy(&waits)
+
+	req, err := http.NewRequest(http.MethodPost, server.URL, strings.NewReader(`{"name": "image"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := policy.Do(server.Client(), req)
+	if err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+	_ = resp.Body.Close()
+
+	if len(bodies) != 2 || bodies[1] != `{"name": "image"}` {
+		t.Errorf("server received bodies %q", bodies)
+	}
+}
+
+func TestRetryPolicyWaitsForRateLimitReset(t *testing.T) {
+	reset := time.Now().Add(time.Minute).Unix()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-RateLimit-Remaining", "0")
+		w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(reset, 10))
+	}))
+	defer server.Close()
+
+	var waits []time.Duration
+	policy := newTestRetryPolicy(&waits)
+
+	for range 2 {
+		req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+		resp, err := policy.Do(server.Client(), req)
+		if err != nil {
+			t.Fatalf("Do() error = %v", err)
+		}
+		_ = resp.Body.Close()
+	}
+
+	if len(waits) != 1 || waits[0] <= 0 || waits[0] > time.Minute {
+		t.Errorf("waits = %v, want single wait until rate limit reset", waits)
+	}
+}
+
+func TestRetryPolicyCancelled(t *testing.T) {
+	server, requests := sequenceServer(t, http.Header{"Retry-After": {"1"}}, http.StatusTooManyRequests, http.StatusTooManyRequests)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	policy := NewRetryPolicy()
+	policy.sleep = func(ctx context.Context, _ time.Duration) error {
+		cancel()
+		return ctx.Err()
+	}
+
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	if _, err := policy.Do(server.Client(), req); !errors.Is(err, context.Canceled) {
+		t.Errorf("Do() error = %v, want context.Canceled", err)
+	}
+	if requests.Load() != 1 {
+		t.Errorf("server received %d requests, want 1", requests.Load())
+	}
+}
+
+func TestRateLimitWait(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		header http.Header
+		want   time.Duration
+		wantOK bool
+	}{
+		{name: "seconds", header: http.Header{"Retry-After": {"30"}}, want: 30 * time.Second, wantOK: true},
+		{name: "date", header: http.Header{"Retry-After": {now.Add(time.Minute).Format(http.TimeFormat)}}, want: time.Minute, wantOK: true},
+		{name: "reset", header: http.Header{"X-Ratelimit-Reset": {strconv.FormatInt(now.Add(2*time.Minute).Unix(), 10)}}, want: 2 * time.Minute, wantOK: true},
+		{name: "past reset", header: http.Header{"X-Ratelimit-Reset": {strconv.FormatInt(now.Add(-time.Minute).Unix(), 10)}}, want: 0, wantOK: true},
+		{name: "none", header: http.Header{}, wantOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := rateLimitWait(tt.header, now)
+			if ok != tt.wantOK || got != tt.want {
+				t.Errorf("rateLimitWait() = %v, %v, want %v, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	policy := NewRetryPolicy()
+	for attempt := range 10 {
+		delay := min(DefaultMinBackoff<<attempt, DefaultMaxBackoff)
+		got := policy.backoff(attempt)
+		if got < delay/2 || got > delay {
+			t.Errorf("backoff(%d) = %v, want within [%v, %v]", attempt, got, delay/2, delay)
+		}
+	}
+}
+
+func TestDoRequestHTTPError(t *testing.T) {
+	server, _ := sequenceServer(t, nil, http.StatusNotFound)
+
+	client := NewClient("testorg", "")
+	client.AuthToken = "token"
+
+	_, err := client.doRequest(context.Background(), http.MethodDelete, server.URL, nil)
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) || httpErr.StatusCode != http.StatusNotFound || httpErr.Method != http.MethodDelete {
+		t.Errorf("doRequest() error = %v, want HTTP 404 error", err)
+	}
+}
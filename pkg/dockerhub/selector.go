@@ -0,0 +1,237 @@
+/*
+Copyright © 2020 Yevhen Lebid ealebed@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dockerhub
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"time"
+)
+
+// TagSelector represents criteria selecting docker image tags for deletion
+type TagSelector struct {
+	// Inactive selects only tags with inactive status
+	Inactive bool
+	// TagRegex selects only tags matching regular expression (case insensitive)
+	TagRegex string
+	// KeepLast always keeps newest (by push time) N tags among selected ones
+	KeepLast int
+	// OlderThan selects only tags pushed earlier than that
+	OlderThan time.Duration
+	// PulledBefore keeps tags pulled within that
+	PulledBefore time.Duration
+	// Protect keeps tags fully matching any of these regular expressions, and tags sharing digest with them
+	Protect []string
+	// DigestSafe keeps selected tags sharing digest with any kept tag, so digest is deleted only with all its tags
+	DigestSafe bool
+	// Versions selects only semantic version tags within range like "<2.0.0"
+	Versions string
+	// Prereleases selects only pre-release semantic version tags like "1.2.0-rc.1"
+	Prereleases bool
+	// KeepPatches always keeps newest N patch releases per major.minor line among selected ones, and selects only
+	// semantic version tags, pre-releases only with Versions or Prereleases
+	KeepPatches int
+	// Active selects only tags with active status
+	Active bool
+	// NewerThan selects only tags pushed within that
+	NewerThan time.Duration
+	// MinSize selects only tags with size (in bytes) at least that
+	MinSize int
+}
+
+// Plan splits provided docker image tags to delete and keep
+func (s *TagSelector) Plan(image string, tags []*Tag, now time.Time) (*TruncatePlan, error) {
+	plan := &TruncatePlan{Repository: image}
+
+	match, err := s.matcher(now)
+	if err != nil {
+		return nil, err
+	}
+
+	protected, err := compilePatterns(s.Protect)
+	if err != nil {
+		return nil, err
+	}
+	protectedDigests := digestsMatching(protected, tags)
+
+	var selected []*Tag
+	for _, tag := range tags {
+		if !match(tag) {
+			plan.Keep = append(plan.Keep, tag)
+		} else {
+			selected = append(selected, tag)
+		}
+	}
+
+	sort.SliceStable(selected, func(i, j int) bool {
+		return TagPushedAt(selected[i]).After(TagPushedAt(selected[j]))
+	})
+
+	keptPatches := latestPatches(selected, s.KeepPatches)
+	for i, tag := range selected {
+		switch {
+		case i < s.KeepLast || keptPatches[tag.Name] || !s.expired(tag, now):
+			plan.Keep = append(plan.Keep, tag)
+		case matchAny(protected, tag.Name) || protectedDigests[TagDigest(tag)]:
+			plan.Protected = append(plan.Protected, tag)
+		default:
+			plan.Delete = append(plan.Delete, tag)
+		}
+	}
+
+	if s.DigestSafe {
+		shared := plan.SharedDigests()
+		deletions := []*Tag{}
+		for _, tag := range plan.Delete {
+			if _, ok := shared[TagDigest(tag)]; ok {
+				plan.Shared = append(plan.Shared, tag)
+			} else {
+				deletions = append(deletions, tag)
+			}
+		}
+		plan.Delete = deletions
+	}
+
+	return plan, nil
+}
+
+// Select returns docker image tags matching selector criteria and old enough for deletion, in original order,
+// i.e. tags Plan would select before keeping and protecting some of them
+func (s *TagSelector) Select(tags []*Tag, now time.Time) ([]*Tag, error) {
+	match, err := s.matcher(now)
+	if err != nil {
+		return nil, err
+	}
+
+	selected := []*Tag{}
+	for _, tag := range tags {
+		if match(tag) && s.expired(tag, now) {
+			selected = append(selected, tag)
+		}
+	}
+
+	return selected, nil
+}
+
+// matcher returns function reporting whether docker image tag matches selector criteria, regardless of its age
+func (s *TagSelector) matcher(now time.Time) (func(tag *Tag) bool, error) {
+	var validTag *regexp.Regexp
+	if s.TagRegex != "" {
+		var err error
+		if validTag, err = regexp.Compile(fmt.Sprintf(`(?i)%s`, s.TagRegex)); err != nil {
+			return nil, fmt.Errorf("invalid tag regular expression: %w", err)
+		}
+	}
+
+	var versions *VersionRange
+	if s.Versions != "" {
+		var err error
+		if versions, err = ParseVersionRange(s.Versions); err != nil {
+			return nil, err
+		}
+	}
+
+	return func(tag *Tag) bool {
+		return (validTag == nil || validTag.MatchString(tag.Name)) && (!s.Inactive || tag.TagStatus == "inactive") &&
+			(!s.Active || tag.TagStatus == "active") && tag.FullSize >= s.MinSize &&
+			(s.NewerThan == 0 || now.Sub(TagPushedAt(tag)) <= s.NewerThan) && s.versionSelected(versions, tag)
+	}, nil
+}
+
+// versionSelected reports whether docker image tag satisfies semantic version criteria
+func (s *TagSelector) versionSelected(versions *VersionRange, tag *Tag) bool {
+	if versions == nil && !s.Prereleases && s.KeepPatches == 0 {
+		return true
+	}
+
+	v, ok := ParseVersion(tag.Name)
+	if !ok {
+		return false
+	}
+	// keep patches alone selects only releases, which it keeps newest patches of
+	if versions == nil && !s.Prereleases && v.IsPrerelease() {
+		return false
+	}
+
+	return (versions == nil || versions.Contains(v)) && (!s.Prereleases || v.IsPrerelease())
+}
+
+// expired reports whether docker image tag is old enough for deletion
+func (s *TagSelector) expired(tag *Tag, now time.Time) bool {
+	if s.OlderThan > 0 && now.Sub(TagPushedAt(tag)) <= s.OlderThan {
+		return false
+	}
+	if s.PulledBefore > 0 && !tag.TagLastPulled.IsZero() && now.Sub(tag.TagLastPulled) <= s.PulledBefore {
+		return false
+	}
+
+	return true
+}
+
+// TagPushedAt returns time when docker image tag was last pushed, falling back to its last update time
+func TagPushedAt(tag *Tag) time.Time {
+	if !tag.TagLastPushed.IsZero() {
+		return tag.TagLastPushed
+	}
+
+	return tag.LastUpdated
+}
+
+// TagDigest returns digest of docker image tag, falling back to digest of its single image
+func TagDigest(tag *Tag) string {
+	if tag.Digest == "" && len(tag.Images) == 1 {
+		return tag.Images[0].Digest
+	}
+
+	return tag.Digest
+}
+
+// digestsMatching returns digests of docker image tags, which names fully match any of patterns
+func digestsMatching(patterns []*regexp.Regexp, tags []*Tag) map[string]bool {
+	digests := map[string]bool{}
+	for _, tag := range tags {
+		if digest := TagDigest(tag); digest != "" && matchAny(patterns, tag.Name) {
+			digests[digest] = true
+		}
+	}
+
+	return digests
+}
+
+func compilePatterns(patterns []string) ([]*regexp.Regexp, error) {
+	compiled := make([]*regexp.Regexp, 0, len(patterns))
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(`^(?:` + pattern + `)$`)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regular expression %q: %w", pattern, err)
+		}
+		compiled = append(compiled, re)
+	}
+
+	return compiled, nil
+}
+
+func matchAny(patterns []*regexp.Regexp, s string) bool {
+	for _, re := range patterns {
+		if re.MatchString(s) {
+			return true
+		}
+	}
+
+	return false
+}
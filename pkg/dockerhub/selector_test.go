@@ -0,0 +1,407 @@
+/*
+Copyright © 2020 Yevhen Lebid ealebed@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dockerhub
+
+import (
+	"testing"
+	"time"
+)
+
+func tagNames(tags []*Tag) []string {
+	names := []string{}
+	for _, tag := range tags {
+		names = append(names, tag.Name)
+	}
+	return names
+}
+
+func equalNames(got, want []string) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestTagSelectorPlan(t *testing.T) {
+	tags := []*Tag{
+		{Name: "dev-3", TagStatus: "inactive", FullSize: 300},
+		{Name: "latest", TagStatus: "active", FullSize: 100},
+		{Name: "DEV-2", TagStatus: "active", FullSize: 200},
+		{Name: "1.0.0", TagStatus: "inactive", FullSize: 400},
+		{Name: "dev-1", TagStatus: "inactive", FullSize: 500},
+	}
+
+	tests := []struct {
+		name          string
+		selector      *TagSelector
+		wantDelete    []string
+		wantKeep      []string
+		wantProtected []string
+		wantSize      int
+		wantErr       bool
+	}{
+		{
+			name:       "regex is case insensitive",
+			selector:   &TagSelector{TagRegex: "dev"},
+			wantDelete: []string{"dev-3", "DEV-2", "dev-1"},
+			wantKeep:   []string{"latest", "1.0.0"},
+			wantSize:   1000,
+		},
+		{
+			name:       "inactive leaving latest inactive tag",
+			selector:   &TagSelector{Inactive: true, KeepLast: 1},
+			wantDelete: []string{"1.0.0", "dev-1"},
+			wantKeep:   []string{"latest", "DEV-2", "dev-3"},
+			wantSize:   900,
+		},
+		{
+			name:       "regex combined with inactive",
+			selector:   &TagSelector{Inactive: true, TagRegex: "^dev"},
+			wantDelete: []string{"dev-3", "dev-1"},
+			wantKeep:   []string{"latest", "DEV-2", "1.0.0"},
+			wantSize:   800,
+		},
+		{
+			name:          "protected tags are reported and kept",
+			selector:      &TagSelector{Protect: []string{"latest", `\d+\.\d+\.\d+`, "dev"}},
+			wantDelete:    []string{"dev-3", "DEV-2", "dev-1"},
+			wantKeep:      []string{},
+			wantProtected: []string{"latest", "1.0.0"},
+			wantSize:      1000,
+		},
+		{
+			name:     "invalid regex",
+			selector: &TagSelector{TagRegex: "dev-["},
+			wantErr:  true,
+		},
+		{
+			name:     "invalid protect pattern",
+			selector: &TagSelector{Protect: []string{"("}},
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, err := tt.selector.Plan("image", tags, time.Now())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Plan() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if plan.Repository != "image" {
+				t.Errorf("Plan() Repository = %v, want image", plan.Repository)
+			}
+			if got := tagNames(plan.Delete); !equalNames(got, tt.wantDelete) {
+				t.Errorf("Plan() Delete = %v, want %v", got, tt.wantDelete)
+			}
+			if got := tagNames(plan.Keep); !equalNames(got, tt.wantKeep) {
+				t.Errorf("Plan() Keep = %v, want %v", got, tt.wantKeep)
+			}
+			if tt.wantProtected == nil {
+				tt.wantProtected = []string{}
+			}
+			if got := tagNames(plan.Protected); !equalNames(got, tt.wantProtected) {
+				t.Errorf("Plan() Protected = %v, want %v", got, tt.wantProtected)
+			}
+			if got := plan.DeleteSize(); got != tt.wantSize {
+				t.Errorf("DeleteSize() = %v, want %v", got, tt.wantSize)
+			}
+		})
+	}
+}
+
+func TestTagSelectorPlanByAge(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+	tags := []*Tag{
+		{Name: "a", TagLastPushed: now.Add(-1 * day)},
+		{Name: "b", TagLastPushed: now.Add(-40 * day), TagLastPulled: now.Add(-2 * day)},
+		{Name: "c", LastUpdated: now.Add(-50 * day)},
+		{Name: "d", TagLastPushed: now.Add(-10 * day)},
+		{Name: "e", TagLastPushed: now.Add(-90 * day), TagLastPulled: now.Add(-80 * day)},
+	}
+
+	tests := []struct {
+		name       string
+		selector   *TagSelector
+		wantDelete []string
+	}{
+		{
+			name:       "keep newest by push time",
+			selector:   &TagSelector{KeepLast: 2},
+			wantDelete: []string{"b", "c", "e"},
+		},
+		{
+			name:       "older than",
+			selector:   &TagSelector{OlderThan: 30 * day},
+			wantDelete: []string{"b", "c", "e"},
+		},
+		{
+			name:       "older than and not pulled recently",
+			selector:   &TagSelector{OlderThan: 30 * day, PulledBefore: 60 * day},
+			wantDelete: []string{"c", "e"},
+		},
+		{
+			name:       "keep last combined with age",
+			selector:   &TagSelector{KeepLast: 3, OlderThan: 30 * day},
+			wantDelete: []string{"c", "e"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, err := tt.selector.Plan("image", tags, now)
+			if err != nil {
+				t.Fatalf("Plan() error = %v", err)
+			}
+			if got := tagNames(plan.Delete); !equalNames(got, tt.wantDelete) {
+				t.Errorf("Plan() Delete = %v, want %v", got, tt.wantDelete)
+			}
+			if len(plan.Delete)+len(plan.Keep) != len(tags) {
+				t.Errorf("Plan() lost tags: %d deleted, %d kept", len(plan.Delete), len(plan.Keep))
+			}
+		})
+	}
+}
+
+func TestTagSelectorSelect(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+	tags := []*Tag{
+		{Name: "latest", TagStatus: "active", FullSize: 100, TagLastPushed: now.Add(-1 * day)},
+		{Name: "1.1.0", TagStatus: "active", FullSize: 300, TagLastPushed: now.Add(-10 * day), TagLastPulled: now.Add(-1 * day)},
+		{Name: "1.0.0", TagStatus: "inactive", FullSize: 200, TagLastPushed: now.Add(-40 * day)},
+		{Name: "dev", TagStatus: "inactive", FullSize: 50, TagLastPushed: now.Add(-90 * day), TagLastPulled: now.Add(-80 * day)},
+	}
+
+	tests := []struct {
+		name     string
+		selector *TagSelector
+		want     []string
+	}{
+		{name: "all", selector: &TagSelector{}, want: []string{"latest", "1.1.0", "1.0.0", "dev"}},
+		{name: "active", selector: &TagSelector{Active: true}, want: []string{"latest", "1.1.0"}},
+		{name: "inactive", selector: &TagSelector{Inactive: true}, want: []string{"1.0.0", "dev"}},
+		{name: "min size", selector: &TagSelector{MinSize: 200}, want: []string{"1.1.0", "1.0.0"}},
+		{name: "pushed within", selector: &TagSelector{NewerThan: 30 * day}, want: []string{"latest", "1.1.0"}},
+		{name: "pushed between", selector: &TagSelector{NewerThan: 60 * day, OlderThan: 5 * day}, want: []string{"1.1.0", "1.0.0"}},
+		{name: "not pulled recently", selector: &TagSelector{PulledBefore: 30 * day}, want: []string{"latest", "1.0.0", "dev"}},
+		{name: "regex and versions", selector: &TagSelector{TagRegex: `^1\.`, Versions: "<1.1.0"}, want: []string{"1.0.0"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selected, err := tt.selector.Select(tags, now)
+			if err != nil {
+				t.Fatalf("Select() error = %v", err)
+			}
+			if got := tagNames(selected); !equalNames(got, tt.want) {
+				t.Errorf("Select() = %v, want %v", got, tt.want)
+			}
+
+			plan, err := tt.selector.Plan("image", tags, now)
+			if err != nil {
+				t.Fatalf("Plan() error = %v", err)
+			}
+			if len(plan.Delete) != len(selected) {
+				t.Errorf("Plan() deletes %v, while Select() selects %v", tagNames(plan.Delete), tagNames(selected))
+			}
+		})
+	}
+
+	if _, err := (&TagSelector{TagRegex: "("}).Select(tags, now); err == nil {
+		t.Error("Select() with invalid regular expression should fail")
+	}
+}
+
+func TestTagSelectorPlanProtectsLatestDigest(t *testing.T) {
+	tags := []*Tag{
+		{Name: "latest", Digest: "sha256:aaa"},
+		{Name: "1.4.2", Digest: "sha256:aaa"},
+		{Name: "1.4.1", Images: []*Image{{Digest: "sha256:bbb"}}},
+		{Name: "1.4.0", Digest: "sha256:ccc"},
+	}
+
+	plan, err := (&TagSelector{TagRegex: ".*", Protect: DefaultProtectedTags}).Plan("image", tags, time.Now())
+	if err != nil {
+		t.Fatalf("Plan() error = %v", err)
+	}
+
+	if got := tagNames(plan.Protected); !equalNames(got, []string{"latest", "1.4.2"}) {
+		t.Errorf("Plan() Protected = %v, want [latest 1.4.2]", got)
+	}
+	if got := tagNames(plan.Delete); !equalNames(got, []string{"1.4.1", "1.4.0"}) {
+		t.Errorf("Plan() Delete = %v, want [1.4.1 1.4.0]", got)
+	}
+}
+
+func TestTagDigest(t *testing.T) {
+	tests := []struct {
+		name string
+		tag  *Tag
+		want string
+	}{
+		{name: "tag digest", tag: &Tag{Digest: "sha256:aaa", Images: []*Image{{Digest: "sha256:bbb"}}}, want: "sha256:aaa"},
+		{name: "single image digest", tag: &Tag{Images: []*Image{{Digest: "sha256:bbb"}}}, want: "sha256:bbb"},
+		{name: "multiple images without tag digest", tag: &Tag{Images: []*Image{{Digest: "sha256:bbb"}, {Digest: "sha256:ccc"}}}, want: ""},
+		{name: "no digest", tag: &Tag{}, want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := TagDigest(tt.tag); got != tt.want {
+				t.Errorf("TagDigest() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTagSelectorPlanDigestSafe(t *testing.T) {
+	tags := []*Tag{
+		{Name: "stable", Digest: "sha256:aaa"},
+		{Name: "1.4", Digest: "sha256:aaa"},
+		{Name: "1.4.2", Digest: "sha256:aaa"},
+		{Name: "1.3", Digest: "sha256:bbb"},
+		{Name: "1.3.9", Digest: "sha256:bbb"},
+		{Name: "1.2.0", Digest: "sha256:ccc"},
+	}
+
+	tests := []struct {
+		name       string
+		digestSafe bool
+		wantDelete []string
+		wantShared []string
+		wantWarned map[string][]string
+	}{
+		{
+			name:       "default mode deletes and reports shared digests",
+			wantDelete: []string{"1.4.2", "1.3.9", "1.2.0"},
+			wantShared: []string{},
+			wantWarned: map[string][]string{"sha256:aaa": {"stable", "1.4"}, "sha256:bbb": {"1.3"}},
+		},
+		{
+			name:       "digest safe mode keeps tags sharing digest with kept tags",
+			digestSafe: true,
+			wantDelete: []string{"1.2.0"},
+			wantShared: []string{"1.4.2", "1.3.9"},
+			wantWarned: map[string][]string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selector := &TagSelector{TagRegex: `^\d+\.\d+\.\d+$`, DigestSafe: tt.digestSafe}
+			plan, err := selector.Plan("image", tags, time.Now())
+			if err != nil {
+				t.Fatalf("Plan() error = %v", err)
+			}
+
+			if got := tagNames(plan.Delete); !equalNames(got, tt.wantDelete) {
+				t.Errorf("Plan() Delete = %v, want %v", got, tt.wantDelete)
+			}
+			if got := tagNames(plan.Shared); !equalNames(got, tt.wantShared) {
+				t.Errorf("Plan() Shared = %v, want %v", got, tt.wantShared)
+			}
+
+			shared := plan.SharedDigests()
+			if len(shared) != len(tt.wantWarned) {
+				t.Errorf("SharedDigests() = %v, want %v", shared, tt.wantWarned)
+			}
+			for digest, names := range tt.wantWarned {
+				if !equalNames(shared[digest], names) {
+					t.Errorf("SharedDigests()[%s] = %v, want %v", digest, shared[digest], names)
+				}
+			}
+		})
+	}
+}
+
+func TestTagSelectorPlanVersions(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	pushed := func(days int) time.Time { return now.Add(-time.Duration(days) * 24 * time.Hour) }
+	tags := []*Tag{
+		{Name: "2.0.0", TagLastPushed: pushed(1)},
+		{Name: "2.0.0-rc.1", TagLastPushed: pushed(5)},
+		{Name: "1.1.1", TagLastPushed: pushed(10)},
+		{Name: "1.1.0", TagLastPushed: pushed(20)},
+		{Name: "1.0.2", TagLastPushed: pushed(30)},
+		{Name: "1.0.1", TagLastPushed: pushed(40)},
+		{Name: "1.1.0-beta.1", TagLastPushed: pushed(50)},
+		{Name: "20.11.15-12.30", TagLastPushed: pushed(60)},
+		{Name: "stable", TagLastPushed: pushed(70)},
+	}
+
+	tests := []struct {
+		name       string
+		selector   *TagSelector
+		wantDelete []string
+		wantErr    bool
+	}{
+		{
+			name:       "range",
+			selector:   &TagSelector{Versions: "<2.0.0"},
+			wantDelete: []string{"2.0.0-rc.1", "1.1.1", "1.1.0", "1.0.2", "1.0.1", "1.1.0-beta.1"},
+		},
+		{
+			name:       "keep latest patch per minor line",
+			selector:   &TagSelector{Versions: "<2.0.0", KeepPatches: 1},
+			wantDelete: []string{"2.0.0-rc.1", "1.1.0", "1.0.1", "1.1.0-beta.1"},
+		},
+		{
+			// non-semver tags and pre-releases are never selected by keep patches alone
+			name:       "keep latest patch per minor line without range",
+			selector:   &TagSelector{KeepPatches: 1},
+			wantDelete: []string{"1.1.0", "1.0.1"},
+		},
+		{
+			name:       "pre-releases older than age",
+			selector:   &TagSelector{Prereleases: true, OlderThan: 7 * 24 * time.Hour},
+			wantDelete: []string{"1.1.0-beta.1"},
+		},
+		{
+			name:     "invalid range",
+			selector: &TagSelector{Versions: "~1.0"},
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, err := tt.selector.Plan("image", tags, now)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Plan() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got := tagNames(plan.Delete); !equalNames(got, tt.wantDelete) {
+				t.Errorf("Plan() Delete = %v, want %v", got, tt.wantDelete)
+			}
+			if len(plan.Delete)+len(plan.Keep) != len(tags) {
+				t.Errorf("Plan() lost tags: delete %v, keep %v", tagNames(plan.Delete), tagNames(plan.Keep))
+			}
+		})
+	}
+}
@@ -0,0 +1,268 @@
+/*
+Copyright © 2020 Yevhen Lebid ealebed@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dockerhub
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var versionRegexp = regexp.MustCompile(`^v?(0|[1-9]\d*)\.(0|[1-9]\d*)\.(0|[1-9]\d*)` +
+	`(?:-([0-9A-Za-z-]+(?:\.[0-9A-Za-z-]+)*))?(?:\+([0-9A-Za-z-]+(?:\.[0-9A-Za-z-]+)*))?$`)
+
+var constraintRegexp = regexp.MustCompile(`^(>=|<=|!=|>|<|=)?v?(\d+)(?:\.(\d+))?(?:\.(\d+))?(?:-([0-9A-Za-z.-]+))?$`)
+
+var operatorSpaceRegexp = regexp.MustCompile(`(>=|<=|!=|>|<|=)\s+`)
+
+// Version represents semantic version of docker image tag
+type Version struct {
+	Major      int
+	Minor      int
+	Patch      int
+	Prerelease string
+	Build      string
+}
+
+// ParseVersion parses semantic version from docker image tag name (with optional "v" prefix),
+// date tags like "20.11.15-12.30" are not versions
+func ParseVersion(tag string) (*Version, bool) {
+	if dateTagRegexp.MatchString(tag) {
+		return nil, false
+	}
+
+	match := versionRegexp.FindStringSubmatch(tag)
+	if match == nil {
+		return nil, false
+	}
+
+	v := &Version{Prerelease: match[4], Build: match[5]}
+	var err error
+	if v.Major, err = strconv.Atoi(match[1]); err != nil {
+		return nil, false
+	}
+	if v.Minor, err = strconv.Atoi(match[2]); err != nil {
+		return nil, false
+	}
+	if v.Patch, err = strconv.Atoi(match[3]); err != nil {
+		return nil, false
+	}
+
+	return v, true
+}
+
+// String returns semantic version string without "v" prefix
+func (v *Version) String() string {
+	s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if v.Prerelease != "" {
+		s += "-" + v.Prerelease
+	}
+	if v.Build != "" {
+		s += "+" + v.Build
+	}
+
+	return s
+}
+
+// IsPrerelease reports whether version is pre-release one
+func (v *Version) IsPrerelease() bool {
+	return v.Prerelease != ""
+}
+
+// Compare returns -1, 0 or 1 when version precedes, equals or follows the other one, ignoring build metadata
+func (v *Version) Compare(o *Version) int {
+	for _, diff := range []int{v.Major - o.Major, v.Minor - o.Minor, v.Patch - o.Patch} {
+		if diff != 0 {
+			return sign(diff)
+		}
+	}
+
+	switch {
+	case v.Prerelease == o.Prerelease:
+		return 0
+	case v.Prerelease == "":
+		return 1
+	case o.Prerelease == "":
+		return -1
+	}
+
+	return comparePrerelease(v.Prerelease, o.Prerelease)
+}
+
+// comparePrerelease compares dot separated pre-release identifiers: numeric ones numerically and lower than alphanumeric ones
+func comparePrerelease(a, b string) int {
+	left, right := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(left) && i < len(right); i++ {
+		l, lErr := strconv.Atoi(left[i])
+		r, rErr := strconv.Atoi(right[i])
+		switch {
+		case lErr == nil && rErr == nil:
+			if l != r {
+				return sign(l - r)
+			}
+		case lErr == nil:
+			return -1
+		case rErr == nil:
+			return 1
+		default:
+			if c := strings.Compare(left[i], right[i]); c != 0 {
+				return c
+			}
+		}
+	}
+
+	return sign(len(left) - len(right))
+}
+
+func sign(n int) int {
+	switch {
+	case n < 0:
+		return -1
+	case n > 0:
+		return 1
+	}
+
+	return 0
+}
+
+// VersionRange represents semantic versions range like "<2.0.0" or ">=1.2.0 <2.0.0 || 3.0.0"
+type VersionRange struct {
+	alternatives [][]versionConstraint
+}
+
+type versionConstraint struct {
+	operator string
+	version  *Version
+}
+
+// ParseVersionRange parses space separated version constraints (all should match), alternatives are separated by "||"
+func ParseVersionRange(s string) (*VersionRange, error) {
+	r := &VersionRange{}
+	for _, alternative := range strings.Split(s, "||") {
+		var constraints []versionConstraint
+		for _, field := range strings.Fields(normalizeConstraints(alternative)) {
+			match := constraintRegexp.FindStringSubmatch(field)
+			if match == nil {
+				return nil, fmt.Errorf("invalid version constraint %q", field)
+			}
+
+			v := &Version{Prerelease: match[5]}
+			v.Major, _ = strconv.Atoi(match[2])
+			v.Minor, _ = strconv.Atoi(match[3])
+			v.Patch, _ = strconv.Atoi(match[4])
+
+			operator := match[1]
+			if operator == "" {
+				operator = "="
+			}
+			constraints = append(constraints, versionConstraint{operator: operator, version: v})
+		}
+		if len(constraints) == 0 {
+			return nil, fmt.Errorf("empty version range %q", s)
+		}
+		r.alternatives = append(r.alternatives, constraints)
+	}
+
+	return r, nil
+}
+
+// normalizeConstraints removes spaces between operators and versions, so ">= 1.2.0" becomes ">=1.2.0"
+func normalizeConstraints(s string) string {
+	return operatorSpaceRegexp.ReplaceAllString(s, "$1")
+}
+
+// Contains reports whether version satisfies range
+func (r *VersionRange) Contains(v *Version) bool {
+	for _, constraints := range r.alternatives {
+		matched := true
+		for _, c := range constraints {
+			if !c.matches(v) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (c versionConstraint) matches(v *Version) bool {
+	cmp := v.Compare(c.version)
+	switch c.operator {
+	case ">=":
+		return cmp >= 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	case "<":
+		return cmp < 0
+	case "!=":
+		return cmp != 0
+	}
+
+	return cmp == 0
+}
+
+// SortTagsByVersion sorts docker image tags by semantic version (newest first), tags which aren't versions go last by name
+func SortTagsByVersion(tags []*Tag) {
+	sort.SliceStable(tags, func(i, j int) bool {
+		left, leftOK := ParseVersion(tags[i].Name)
+		right, rightOK := ParseVersion(tags[j].Name)
+		switch {
+		case leftOK && rightOK:
+			return left.Compare(right) > 0
+		case leftOK != rightOK:
+			return leftOK
+		}
+
+		return tags[i].Name < tags[j].Name
+	})
+}
+
+// latestPatches returns names of newest N release versions per major.minor line among provided docker image tags
+func latestPatches(tags []*Tag, n int) map[string]bool {
+	kept := map[string]bool{}
+	if n <= 0 {
+		return kept
+	}
+
+	releases := []*Tag{}
+	for _, tag := range tags {
+		if v, ok := ParseVersion(tag.Name); ok && !v.IsPrerelease() {
+			releases = append(releases, tag)
+		}
+	}
+	SortTagsByVersion(releases)
+
+	perLine := map[string]int{}
+	for _, tag := range releases {
+		v, _ := ParseVersion(tag.Name)
+		line := fmt.Sprintf("%d.%d", v.Major, v.Minor)
+		if perLine[line] < n {
+			perLine[line]++
+			kept[tag.Name] = true
+		}
+	}
+
+	return kept
+}
@@ -0,0 +1,125 @@
+/*
+Copyright © 2020 Yevhen Lebid ealebed@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dockerhub
+
+import (
+	"testing"
+)
+
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		tag    string
+		want   string
+		wantOK bool
+	}{
+		{tag: "1.2.3", want: "1.2.3", wantOK: true},
+		{tag: "v1.2.3", want: "1.2.3", wantOK: true},
+		{tag: "1.2.3-rc.1+build.5", want: "1.2.3-rc.1+build.5", wantOK: true},
+		{tag: "20.11.15-12.30", wantOK: false},
+		{tag: "1.2", wantOK: false},
+		{tag: "01.2.3", wantOK: false},
+		{tag: "latest", wantOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.tag, func(t *testing.T) {
+			v, ok := ParseVersion(tt.tag)
+			if ok != tt.wantOK {
+				t.Fatalf("ParseVersion(%q) ok = %v, want %v", tt.tag, ok, tt.wantOK)
+			}
+			if ok && v.String() != tt.want {
+				t.Errorf("ParseVersion(%q) = %v, want %v", tt.tag, v, tt.want)
+			}
+		})
+	}
+}
+
+func TestVersionCompare(t *testing.T) {
+	ordered := []string{"1.0.0-alpha", "1.0.0-alpha.1", "1.0.0-alpha.beta", "1.0.0-beta.2", "1.0.0-beta.11", "1.0.0-rc.1", "1.0.0", "1.0.1", "1.10.0", "2.0.0"}
+
+	for i := 0; i < len(ordered)-1; i++ {
+		left, _ := ParseVersion(ordered[i])
+		right, _ := ParseVersion(ordered[i+1])
+		if left.Compare(right) != -1 || right.Compare(left) != 1 {
+			t.Errorf("%s should precede %s", ordered[i], ordered[i+1])
+		}
+	}
+
+	left, _ := ParseVersion("1.0.0+build.1")
+	right, _ := ParseVersion("v1.0.0")
+	if left.Compare(right) != 0 {
+		t.Error("build metadata should be ignored in comparison")
+	}
+}
+
+func TestParseVersionRange(t *testing.T) {
+	tests := []struct {
+		versionRange string
+		contains     []string
+		excludes     []string
+		wantErr      bool
+	}{
+		{versionRange: "<2.0.0", contains: []string{"1.9.9", "2.0.0-rc.1"}, excludes: []string{"2.0.0", "2.1.0"}},
+		{versionRange: ">=1.2.0 <2.0.0 || 3.0.0", contains: []string{"1.2.0", "1.9.0", "3.0.0"}, excludes: []string{"1.1.9", "2.0.0", "3.0.1"}},
+		{versionRange: ">= 1.2 != 1.2.5", contains: []string{"1.2.0", "1.2.6"}, excludes: []string{"1.1.0", "1.2.5"}},
+		{versionRange: "~1.2", wantErr: true},
+		{versionRange: "1.0.0 ||", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.versionRange, func(t *testing.T) {
+			r, err := ParseVersionRange(tt.versionRange)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseVersionRange() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			for _, tag := range tt.contains {
+				if v, _ := ParseVersion(tag); !r.Contains(v) {
+					t.Errorf("range %q should contain %s", tt.versionRange, tag)
+				}
+			}
+			for _, tag := range tt.excludes {
+				if v, _ := ParseVersion(tag); r.Contains(v) {
+					t.Errorf("range %q should not contain %s", tt.versionRange, tag)
+				}
+			}
+		})
+	}
+}
+
+func TestSortTagsByVersion(t *testing.T) {
+	tags := []*Tag{{Name: "latest"}, {Name: "1.2.0"}, {Name: "1.10.0"}, {Name: "20.11.15-12.30"}, {Name: "v1.9.0"}, {Name: "1.10.0-rc.1"}}
+	SortTagsByVersion(tags)
+
+	want := []string{"1.10.0", "1.10.0-rc.1", "v1.9.0", "1.2.0", "20.11.15-12.30", "latest"}
+	if got := tagNames(tags); !equalNames(got, want) {
+		t.Errorf("SortTagsByVersion() = %v, want %v", got, want)
+	}
+}
+
+func TestLatestPatches(t *testing.T) {
+	tags := []*Tag{{Name: "1.0.1"}, {Name: "1.0.2"}, {Name: "1.0.3"}, {Name: "1.1.0"}, {Name: "1.1.1-rc.1"}, {Name: "dev"}}
+
+	kept := latestPatches(tags, 2)
+	for _, name := range []string{"1.0.3", "1.0.2", "1.1.0"} {
+		if !kept[name] {
+			t.Errorf("latestPatches() should keep %s", name)
+		}
+	}
+	if len(kept) != 3 {
+		t.Errorf("latestPatches() = %v, want 3 tags", kept)
+	}
+}
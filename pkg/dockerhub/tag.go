@@ -18,18 +18,30 @@ package dockerhub
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"regexp"
+	"slices"
+	"strings"
 
 	"github.com/fatih/color"
 )
 
+// ErrProtectedTag is returned on attempt to delete protected docker image tag
+var ErrProtectedTag = errors.New("tag is protected")
+
 // ListTags returns list of docker image tags for selected image from docker hub
 func (c *Client) ListTags(image string) ([]*Tag, error) {
+	return c.ListTagsContext(context.Background(), image)
+}
+
+// ListTagsContext returns list of docker image tags for selected image from docker hub, using provided context
+func (c *Client) ListTagsContext(ctx context.Context, image string) ([]*Tag, error) {
 	var tags = []*Tag{}
-	output, err := c.listTagsRequest(image, "")
+	output, err := c.listTagsRequest(ctx, image, "")
 	if err != nil {
 		return nil, err
 	}
@@ -41,7 +53,7 @@ func (c *Client) ListTags(image string) ([]*Tag, error) {
 		if next == "" {
 			return tags, nil
 		}
-		output, err := c.listTagsRequest(image, next)
+		output, err := c.listTagsRequest(ctx, image, next)
 		if err != nil {
 			return nil, err
 		}
@@ -51,15 +63,15 @@ func (c *Client) ListTags(image string) ([]*Tag, error) {
 	}
 }
 
-func (c *Client) listTagsRequest(image, next string) (*TagList, error) {
+func (c *Client) listTagsRequest(ctx context.Context, image, next string) (*TagList, error) {
 	var url string
 	if next != "" {
 		url = next
 	} else {
-		url = fmt.Sprintf("%s/%s/%s/tags/?page_size=100", RepositoriesURL, c.ORG, image)
+		url = c.apiURL("repositories/%s/%s/tags/?page_size=100", c.ORG, image)
 	}
 
-	data, err := c.doRequest(http.MethodGet, url, nil)
+	data, err := c.doRequest(ctx, http.MethodGet, url, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -73,11 +85,40 @@ func (c *Client) listTagsRequest(image, next string) (*TagList, error) {
 	return output, nil
 }
 
+// GetTag returns single docker image tag with its per-platform images from docker hub
+/* curl \
+   -H "Authorization: JWT ${TOKEN}" \
+   https://hub.docker.com/v2/repositories/${ORG}/${IMAGE}/tags/${TAG}/
+*/
+func (c *Client) GetTag(image, tag string) (*Tag, error) {
+	return c.GetTagContext(context.Background(), image, tag)
+}
+
+// GetTagContext returns single docker image tag with its per-platform images from docker hub, using provided context
+func (c *Client) GetTagContext(ctx context.Context, image, tag string) (*Tag, error) {
+	data, err := c.doRequest(ctx, http.MethodGet, c.apiURL("repositories/%s/%s/tags/%s/", c.ORG, image, tag), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	output := &Tag{}
+	if err := json.NewDecoder(bytes.NewReader(data)).Decode(output); err != nil {
+		return nil, err
+	}
+
+	return output, nil
+}
+
 // GetTagsCount returns count docker image tag from docker hub for selected repository
 func (c *Client) GetTagsCount(image string) (int, error) {
-	url := fmt.Sprintf("%s/%s/%s/tags/?page_size=100", RepositoriesURL, c.ORG, image)
+	return c.GetTagsCountContext(context.Background(), image)
+}
 
-	data, err := c.doRequest(http.MethodGet, url, nil)
+// GetTagsCountContext returns count docker image tag from docker hub for selected repository, using provided context
+func (c *Client) GetTagsCountContext(ctx context.Context, image string) (int, error) {
+	url := c.apiURL("repositories/%s/%s/tags/?page_size=100", c.ORG, image)
+
+	data, err := c.doRequest(ctx, http.MethodGet, url, nil)
 	if err != nil {
 		return -1, err
 	}
@@ -95,34 +136,40 @@ func (c *Client) GetTagsCount(image string) (int, error) {
 
 // GetAvgTagsSize returns size docker image tag from docker hub for selected repository
 func (c *Client) GetAvgTagsSize(image string) (float64, error) {
-	var size int
-
-	tagsCount, err := c.GetTagsCount(image)
-	if err != nil {
-		color.Red("Error: %s", err)
-	}
+	return c.GetAvgTagsSizeContext(context.Background(), image)
+}
 
-	tags, err := c.ListTags(image)
+// GetAvgTagsSizeContext returns size docker image tag from docker hub for selected repository, using provided context
+func (c *Client) GetAvgTagsSizeContext(ctx context.Context, image string) (float64, error) {
+	tags, err := c.ListTagsContext(ctx, image)
 	if err != nil {
-		color.Red("Error: %s", err)
-	}
-
-	for _, tag := range tags {
-		size += tag.FullSize
+		return 0, err
 	}
 
-	var avgSize = float64(size) / float64(tagsCount) / 1024 / 1024
-
-	return avgSize, nil
+	return NewRepositoryReport(&Repository{Name: image}, tags).AvgSizeMB(), nil
 }
 
-// deleteDockerImageTag delete docker image tag from docker hub
+// deleteDockerImageTag delete docker image tag from docker hub, unless tag name matches Protect patterns
+// or tag shares digest with protected tags, provided as protected digests of repository
 /* curl \
    -H "Authorization: JWT ${TOKEN}" \
    -X DELETE https://hub.docker.com/v2/repositories/${ORG}/${IMAGE}/tags/${TAG}/
 */
-func (c *Client) deleteDockerImageTag(image, tag string) error {
-	if _, err := c.doRequest(http.MethodDelete, fmt.Sprintf("%s/%s/%s/tags/%s/", RepositoriesURL, c.ORG, image, tag), nil); err != nil {
+func (c *Client) deleteDockerImageTag(ctx context.Context, image string, tag *Tag, protectedDigests map[string]bool) error {
+	protected, err := c.protectedPatterns()
+	if err != nil {
+		return err
+	}
+	if matchAny(protected, tag.Name) {
+		color.Yellow("Skip protected tag %s", BW(image+":"+tag.Name))
+		return fmt.Errorf("%s:%s: %w", image, tag.Name, ErrProtectedTag)
+	}
+	if protectedDigests[TagDigest(tag)] {
+		color.Yellow("Skip tag %s sharing digest with protected tags", BW(image+":"+tag.Name))
+		return fmt.Errorf("%s:%s: %w", image, tag.Name, ErrProtectedTag)
+	}
+
+	if err := c.deleteResource(ctx, c.apiURL("repositories/%s/%s/tags/%s/", c.ORG, image, tag.Name)); err != nil {
 		color.Red("Error while deleting docker image tag: %s", err)
 		return err
 	}
@@ -130,9 +177,30 @@ func (c *Client) deleteDockerImageTag(image, tag string) error {
 	return nil
 }
 
+// protectedPatterns returns compiled Protect patterns of client, which are compiled again only when Protect changes
+func (c *Client) protectedPatterns() ([]*regexp.Regexp, error) {
+	c.protectMu.Lock()
+	defer c.protectMu.Unlock()
+
+	if c.protected == nil || !slices.Equal(c.protectSource, c.Protect) {
+		protected, err := compilePatterns(c.Protect)
+		if err != nil {
+			return nil, err
+		}
+		c.protected, c.protectSource = protected, slices.Clone(c.Protect)
+	}
+
+	return c.protected, nil
+}
+
 // GetLatestTag returns latest (by LastUpdated field) docker image tag from docker hub
 func (c *Client) GetLatestTag(image string) (string, error) {
-	tags, err := c.ListTags(image)
+	return c.GetLatestTagContext(context.Background(), image)
+}
+
+// GetLatestTagContext returns latest (by LastUpdated field) docker image tag from docker hub, using provided context
+func (c *Client) GetLatestTagContext(ctx context.Context, image string) (string, error) {
+	tags, err := c.ListTagsContext(ctx, image)
 	if err != nil {
 		return "", err
 	}
@@ -140,38 +208,65 @@ func (c *Client) GetLatestTag(image string) (string, error) {
 	return tags[0].Name, nil
 }
 
-// TruncateTags deletes docker image tags tags that match `regularExpression` OR are older than `expiredRange` except latest `leaveTagsCounter` ones
-func (c *Client) TruncateTags(image string, truncateInactive bool, regularExpression string) error {
-	var tagsToRemove []string
-	var leaveTagsCounter = 0
+// TruncateTags deletes docker image tags selected by provided selector
+func (c *Client) TruncateTags(image string, selector *TagSelector) error {
+	return c.TruncateTagsContext(context.Background(), image, selector)
+}
 
-	tags, err := c.ListTags(image)
+// TruncateTagsContext deletes docker image tags selected by provided selector, stopping when context is cancelled
+func (c *Client) TruncateTagsContext(ctx context.Context, image string, selector *TagSelector) error {
+	plan, err := c.PlanTruncateTagsContext(ctx, image, selector)
 	if err != nil {
 		return err
 	}
 
-	if regularExpression != "" {
-		regexPattern := fmt.Sprintf(`(?i)%s`, regularExpression)
-		for _, tag := range tags {
-			matched, _ := regexp.MatchString(regexPattern, tag.Name)
-			if matched {
-				tagsToRemove = append(tagsToRemove, tag.Name)
-			}
+	return c.ApplyTruncatePlanContext(ctx, plan)
+}
+
+// ApplyTruncatePlan deletes docker image tags selected for deletion in provided plan
+func (c *Client) ApplyTruncatePlan(plan *TruncatePlan) error {
+	return c.ApplyTruncatePlanContext(context.Background(), plan)
+}
+
+// ApplyTruncatePlanContext deletes docker image tags selected for deletion in provided plan,
+// after context is cancelled remaining tags are skipped, while started deletion is finished,
+// failure of one deletion doesn't stop others and all failures are returned together
+func (c *Client) ApplyTruncatePlanContext(ctx context.Context, plan *TruncatePlan) error {
+	for _, tag := range plan.Protected {
+		color.Yellow("Skip protected tag %s", BW(plan.Repository+":"+tag.Name))
+	}
+
+	for _, tag := range plan.Shared {
+		color.Yellow("Skip tag %s sharing digest with kept tags", BW(plan.Repository+":"+tag.Name))
+	}
+
+	shared := plan.SharedDigests()
+	protected, err := c.protectedPatterns()
+	if err != nil {
+		return err
+	}
+	protectedDigests := digestsMatching(protected, slices.Concat(plan.Delete, plan.Protected, plan.Shared, plan.Keep))
+
+	var errs []error
+	for _, tag := range plan.Delete {
+		if ctx.Err() != nil {
+			c.Progress.skip(1)
+			continue
 		}
-	} else {
-		for _, tag := range tags {
-			if tag.TagStatus == "inactive" {
-				tagsToRemove = append(tagsToRemove, tag.Name)
-				leaveTagsCounter = 1
-			}
+		if kept, ok := shared[TagDigest(tag)]; ok {
+			color.Yellow("Warning: tag %s shares digest with kept tags %s", BW(tag.Name), BW(strings.Join(kept, ", ")))
+		}
+		color.Green("\u2714  Delete tag %s", BW(tag.Name))
+		if err := c.deleteDockerImageTag(ctx, plan.Repository, tag, protectedDigests); err != nil && !errors.Is(err, ErrProtectedTag) {
+			errs = append(errs, fmt.Errorf("%s:%s: %w", plan.Repository, tag.Name, err))
 		}
 	}
 
-	for i := leaveTagsCounter; i < len(tagsToRemove); i++ {
-		color.Green("\u2714  Delete tag %s", BW(tagsToRemove[i]))
-		if err := c.deleteDockerImageTag(image, tagsToRemove[i]); err != nil {
-			color.Red("Error while deleting image tag: %s", err)
-		}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to delete %d of %d tags:\n%w", len(errs), len(plan.Delete), errors.Join(errs...))
 	}
 
 	return nil
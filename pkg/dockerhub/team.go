@@ -0,0 +1,212 @@
+/*
+Copyright © 2020 Yevhen Lebid ealebed@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dockerhub
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+)
+
+// Repository permissions of teams and collaborators
+const (
+	PermissionRead  = "read"
+	PermissionWrite = "write"
+	PermissionAdmin = "admin"
+)
+
+// Permissions lists valid repository permissions, from the lowest to the highest
+var Permissions = []string{PermissionRead, PermissionWrite, PermissionAdmin}
+
+// Team represents organization team (group) information returned from hub.docker.com
+type Team struct {
+	ID          int64  `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	MemberCount int    `json:"member_count"`
+}
+
+// Member represents organization or team member returned from hub.docker.com
+type Member struct {
+	ID       string `json:"id"`
+	Username string `json:"username"`
+	FullName string `json:"full_name"`
+}
+
+// TeamPermission represents team permission on docker repository returned from hub.docker.com
+type TeamPermission struct {
+	TeamID     int64  `json:"group_id"`
+	TeamName   string `json:"group_name"`
+	Permission string `json:"permission"`
+}
+
+// page represents single page of docker hub list results
+type page[T any] struct {
+	Count   int    `json:"count"`
+	Next    string `json:"next"`
+	Results []T    `json:"results"`
+}
+
+// ValidatePermission checks that permission is one of read, write or admin
+func ValidatePermission(permission string) error {
+	if !slices.Contains(Permissions, permission) {
+		return fmt.Errorf("invalid permission %q, should be one of: read, write, admin", permission)
+	}
+
+	return nil
+}
+
+// listPages returns results of all pages of docker hub list endpoint, following next page links
+func listPages[T any](ctx context.Context, c *Client, url string) ([]T, error) {
+	results := []T{}
+	for url != "" {
+		data, err := c.doRequest(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		output := &page[T]{}
+		if err := json.NewDecoder(bytes.NewReader(data)).Decode(output); err != nil {
+			return nil, err
+		}
+
+		results = append(results, output.Results...)
+		url = output.Next
+	}
+
+	return results, nil
+}
+
+// sendJSON sends request with JSON encoded payload to docker hub
+func (c *Client) sendJSON(ctx context.Context, method, url string, payload any) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.doRequest(ctx, method, url, bytes.NewReader(data))
+
+	return err
+}
+
+// ListTeams returns teams of client organization from docker hub
+func (c *Client) ListTeams() ([]*Team, error) {
+	return c.ListTeamsContext(context.Background())
+}
+
+// ListTeamsContext returns teams of client organization from docker hub, using provided context
+func (c *Client) ListTeamsContext(ctx context.Context) ([]*Team, error) {
+	return listPages[*Team](ctx, c, c.apiURL("orgs/%s/groups/?page_size=100", c.ORG))
+}
+
+// GetTeamContext returns team of client organization with provided name, using provided context
+func (c *Client) GetTeamContext(ctx context.Context, name string) (*Team, error) {
+	teams, err := c.ListTeamsContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, team := range teams {
+		if team.Name == name {
+			return team, nil
+		}
+	}
+
+	return nil, fmt.Errorf("team %q not found in organization %s", name, c.ORG)
+}
+
+// ListMembers returns members of client organization from docker hub
+func (c *Client) ListMembers() ([]*Member, error) {
+	return c.ListMembersContext(context.Background())
+}
+
+// ListMembersContext returns members of client organization from docker hub, using provided context
+func (c *Client) ListMembersContext(ctx context.Context) ([]*Member, error) {
+	return listPages[*Member](ctx, c, c.apiURL("orgs/%s/members/?page_size=100", c.ORG))
+}
+
+// ListTeamMembers returns members of organization team from docker hub
+func (c *Client) ListTeamMembers(team string) ([]*Member, error) {
+	return c.ListTeamMembersContext(context.Background(), team)
+}
+
+// ListTeamMembersContext returns members of organization team from docker hub, using provided context
+func (c *Client) ListTeamMembersContext(ctx context.Context, team string) ([]*Member, error) {
+	return listPages[*Member](ctx, c, c.apiURL("orgs/%s/groups/%s/members/?page_size=100", c.ORG, team))
+}
+
+// ListRepositoryTeams returns permissions of teams on docker repository from docker hub
+func (c *Client) ListRepositoryTeams(image string) ([]*TeamPermission, error) {
+	return c.ListRepositoryTeamsContext(context.Background(), image)
+}
+
+// ListRepositoryTeamsContext returns permissions of teams on docker repository from docker hub, using provided context
+func (c *Client) ListRepositoryTeamsContext(ctx context.Context, image string) ([]*TeamPermission, error) {
+	return listPages[*TeamPermission](ctx, c, c.apiURL("repositories/%s/%s/groups/?page_size=100", c.ORG, image))
+}
+
+// SetTeamPermission grants team permission on docker repository, replacing permission team already has
+/* curl \
+   -H "Authorization: JWT ${TOKEN}" \
+   -H "Content-Type: application/json" \
+   -X POST \
+   -d '{"group_id": ${TEAM_ID}, "permission": "write"}' \
+   https://hub.docker.com/v2/repositories/${ORG}/${IMAGE}/groups/
+*/
+func (c *Client) SetTeamPermission(image string, team *Team, permission string) error {
+	return c.SetTeamPermissionContext(context.Background(), image, team, permission)
+}
+
+// SetTeamPermissionContext grants team permission on docker repository, using provided context,
+// existing permission of team is changed in place
+func (c *Client) SetTeamPermissionContext(ctx context.Context, image string, team *Team, permission string) error {
+	if err := ValidatePermission(permission); err != nil {
+		return err
+	}
+
+	permissions, err := c.ListRepositoryTeamsContext(ctx, image)
+	if err != nil {
+		return err
+	}
+	for _, p := range permissions {
+		if p.TeamID == team.ID {
+			return c.sendJSON(ctx, http.MethodPut, c.apiURL("repositories/%s/%s/groups/%d/", c.ORG, image, team.ID),
+				map[string]string{"permission": permission})
+		}
+	}
+
+	return c.sendJSON(ctx, http.MethodPost, c.apiURL("repositories/%s/%s/groups/", c.ORG, image),
+		map[string]any{"group_id": team.ID, "permission": permission})
+}
+
+// RemoveTeamPermission revokes permission of team on docker repository
+/* curl \
+   -H "Authorization: JWT ${TOKEN}" \
+   -X DELETE \
+   https://hub.docker.com/v2/repositories/${ORG}/${IMAGE}/groups/${TEAM_ID}/
+*/
+func (c *Client) RemoveTeamPermission(image string, team *Team) error {
+	return c.RemoveTeamPermissionContext(context.Background(), image, team)
+}
+
+// RemoveTeamPermissionContext revokes permission of team on docker repository, unless context is already cancelled
+func (c *Client) RemoveTeamPermissionContext(ctx context.Context, image string, team *Team) error {
+	return c.deleteResource(ctx, c.apiURL("repositories/%s/%s/groups/%d/", c.ORG, image, team.ID))
+}
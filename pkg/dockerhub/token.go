@@ -0,0 +1,152 @@
+/*
+Copyright © 2020 Yevhen Lebid ealebed@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dockerhub
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// tokenExpiryLeeway represents time before JWT Token expiry, when it is already considered expired
+const tokenExpiryLeeway = time.Minute
+
+// TokenCache represents directory with JWT Tokens, reused by consecutive dha runs until they expire
+type TokenCache struct {
+	Dir string
+}
+
+// cachedToken represents JWT Token cache file
+type cachedToken struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// DefaultTokenCache returns token cache in user cache directory (~/.cache/dha on linux), or nil when there is none
+func DefaultTokenCache() *TokenCache {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return nil
+	}
+
+	return &TokenCache{Dir: filepath.Join(dir, "dha")}
+}
+
+// Load returns cached JWT Token with its expiry, when it is still valid
+func (tc *TokenCache) Load(key string) (string, time.Time, bool) {
+	data, err := os.ReadFile(tc.path(key))
+	if err != nil {
+		return "", time.Time{}, false
+	}
+
+	cached := &cachedToken{}
+	if err := json.Unmarshal(data, cached); err != nil || cached.Token == "" || tokenExpired(cached.ExpiresAt, time.Now()) {
+		return "", time.Time{}, false
+	}
+
+	return cached.Token, cached.ExpiresAt, true
+}
+
+// Store saves JWT Token with its expiry, readable by current user only
+func (tc *TokenCache) Store(key, token string, expiresAt time.Time) error {
+	if err := os.MkdirAll(tc.Dir, 0o700); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(&cachedToken{Token: token, ExpiresAt: expiresAt})
+	if err != nil {
+		return err
+	}
+
+	// write to temporary file first, so concurrent runs never read partially written token
+	file, err := os.CreateTemp(tc.Dir, ".token-*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = os.Remove(file.Name())
+	}()
+
+	if _, err := file.Write(data); err != nil {
+		_ = file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(file.Name(), tc.path(key))
+}
+
+// Delete removes cached JWT Token, rejected by docker hub
+func (tc *TokenCache) Delete(key string) error {
+	if err := os.Remove(tc.path(key)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	return nil
+}
+
+func (tc *TokenCache) path(key string) string {
+	return filepath.Join(tc.Dir, "token-"+key+".json")
+}
+
+// tokenCacheKey returns cache key of JWT Token for docker hub address and credentials,
+// secrets are hashed, so changed password or access token never reuses old JWT Token
+func tokenCacheKey(url string, credentials *Credentials) string {
+	hash := sha256.New()
+	for _, part := range []string{url, credentials.Username, credentials.Password, credentials.Token} {
+		hash.Write([]byte(part))
+		hash.Write([]byte{0})
+	}
+
+	return hex.EncodeToString(hash.Sum(nil))[:32]
+}
+
+// TokenExpiry returns expiry time from "exp" claim of JWT Token
+func TokenExpiry(token string) (time.Time, bool) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}, false
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	claims := &struct {
+		Exp int64 `json:"exp"`
+	}{}
+	if err := json.Unmarshal(payload, claims); err != nil || claims.Exp == 0 {
+		return time.Time{}, false
+	}
+
+	return time.Unix(claims.Exp, 0), true
+}
+
+// tokenExpired reports whether JWT Token with provided expiry should be renewed, tokens without expiry never expire
+func tokenExpired(expiresAt, now time.Time) bool {
+	return !expiresAt.IsZero() && !now.Before(expiresAt.Add(-tokenExpiryLeeway))
+}
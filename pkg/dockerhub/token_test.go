@@ -0,0 +1,216 @@
+/*
+Copyright © 2020 Yevhen Lebid ealebed@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dockerhub
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testJWT returns unsigned JWT Token with provided id and expiry
+func testJWT(id int, expiresAt time.Time) string {
+	encode := base64.RawURLEncoding.EncodeToString
+	payload := fmt.Sprintf(`{"sub": "user", "jti": "%d", "exp": %d}`, id, expiresAt.Unix())
+
+	return encode([]byte(`{"alg": "none"}`)) + "." + encode([]byte(payload)) + "."
+}
+
+// tokenServer issues new JWT Token on every login, accepting the latest one only
+func tokenServer(t *testing.T, ttl time.Duration) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var logins atomic.Int32
+	var mu sync.Mutex
+	var current string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.URL.Path == "/v2/users/login" {
+			current = testJWT(int(logins.Add(1)), time.Now().Add(ttl))
+			_, _ = fmt.Fprintf(w, `{"token": %q}`, current)
+			return
+		}
+		if r.Header.Get("Authorization") != "JWT "+current {
+			http.Error(w, `{"detail": "Token is invalid or expired"}`, http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte(`{"name": "api"}`))
+	}))
+	t.Cleanup(server.Close)
+
+	return server, &logins
+}
+
+func newTokenClient(url string, cache *TokenCache) *Client {
+	client := NewClient("testorg", url)
+	client.Retry = nil
+	client.Credentials = &Credentials{Username: "user", Password: "secret"}
+	client.TokenCache = cache
+
+	return client
+}
+
+func TestTokenExpiry(t *testing.T) {
+	expiresAt := time.Unix(1893456000, 0)
+	if got, ok := TokenExpiry(testJWT(1, expiresAt)); !ok || !got.Equal(expiresAt) {
+		t.Errorf("TokenExpiry() = %v, %v, want %v", got, ok, expiresAt)
+	}
+
+	for _, token := range []string{"", "opaque-token", "a.b.c", "a." + base64.RawURLEncoding.EncodeToString([]byte(`{"sub": "user"}`)) + ".c"} {
+		if _, ok := TokenExpiry(token); ok {
+			t.Errorf("TokenExpiry(%q) should fail", token)
+		}
+	}
+}
+
+func TestTokenCache(t *testing.T) {
+	cache := &TokenCache{Dir: t.TempDir()}
+	token := testJWT(1, time.Now().Add(time.Hour))
+	expiresAt, _ := TokenExpiry(token)
+
+	if _, _, ok := cache.Load("key"); ok {
+		t.Fatal("Load() of missing token should fail")
+	}
+	if err := cache.Store("key", token, expiresAt); err != nil {
+		t.Fatalf("Store() error = %v", err)
+	}
+	if got, _, ok := cache.Load("key"); !ok || got != token {
+		t.Errorf("Load() = %v, %v, want stored token", got, ok)
+	}
+
+	if err := cache.Store("expired", token, time.Now().Add(30*time.Second)); err != nil {
+		t.Fatalf("Store() error = %v", err)
+	}
+	if _, _, ok := cache.Load("expired"); ok {
+		t.Error("Load() of token expiring within leeway should fail")
+	}
+
+	if err := cache.Delete("key"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, _, ok := cache.Load("key"); ok {
+		t.Error("Load() of deleted token should fail")
+	}
+	if err := cache.Delete("key"); err != nil {
+		t.Errorf("Delete() of missing token error = %v", err)
+	}
+}
+
+func TestTokenCacheKey(t *testing.T) {
+	key := tokenCacheKey("https://hub.docker.com", &Credentials{Username: "user", Password: "secret"})
+	if key != tokenCacheKey("https://hub.docker.com", &Credentials{Username: "user", Password: "secret"}) {
+		t.Error("tokenCacheKey() should be stable")
+	}
+	if key == tokenCacheKey("https://hub.docker.com", &Credentials{Username: "user", Password: "changed"}) {
+		t.Error("tokenCacheKey() should change with password")
+	}
+	if key == tokenCacheKey("http://localhost:8080", &Credentials{Username: "user", Password: "secret"}) {
+		t.Error("tokenCacheKey() should change with docker hub address")
+	}
+}
+
+func TestClientReusesCachedToken(t *testing.T) {
+	server, logins := tokenServer(t, time.Hour)
+	cache := &TokenCache{Dir: t.TempDir()}
+
+	for run := 0; run < 3; run++ {
+		if _, err := newTokenClient(server.URL, cache).DescribeRepository("api"); err != nil {
+			t.Fatalf("run %d: DescribeRepository() error = %v", run, err)
+		}
+	}
+
+	if got := logins.Load(); got != 1 {
+		t.Errorf("logins = %d, want 1", got)
+	}
+}
+
+func TestClientRefreshesRejectedToken(t *testing.T) {
+	server, logins := tokenServer(t, time.Hour)
+	cache := &TokenCache{Dir: t.TempDir()}
+
+	// token cached by previous run is revoked, e.g. by password change on other machine
+	stale := newTokenClient(server.URL, cache)
+	if _, err := stale.GetAuthToken(); err != nil {
+		t.Fatalf("GetAuthToken() error = %v", err)
+	}
+	if _, err := newTokenClient(server.URL, nil).GetAuthToken(); err != nil {
+		t.Fatalf("GetAuthToken() error = %v", err)
+	}
+
+	client := newTokenClient(server.URL, cache)
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := client.DescribeRepositoryContext(context.Background(), "api")
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("DescribeRepository() error = %v", err)
+		}
+	}
+	if got := logins.Load(); got != 3 {
+		t.Errorf("logins = %d, want 3 (concurrent requests should share single refresh)", got)
+	}
+
+	if token, _, ok := cache.Load(tokenCacheKey(server.URL, client.Credentials)); !ok || token != client.AuthToken {
+		t.Error("refreshed token should replace cached one")
+	}
+}
+
+func TestClientRenewsExpiringToken(t *testing.T) {
+	server, logins := tokenServer(t, 30*time.Second)
+	client := newTokenClient(server.URL, nil)
+
+	for i := 0; i < 2; i++ {
+		if _, err := client.DescribeRepository("api"); err != nil {
+			t.Fatalf("DescribeRepository() error = %v", err)
+		}
+	}
+
+	// tokens expiring within leeway are renewed before every request
+	if got := logins.Load(); got != 2 {
+		t.Errorf("logins = %d, want 2", got)
+	}
+}
+
+func TestClientStaticTokenNotRefreshed(t *testing.T) {
+	server, logins := tokenServer(t, time.Hour)
+	client := newTokenClient(server.URL, nil)
+	client.AuthToken = "static-token"
+
+	if _, err := client.DescribeRepository("api"); err == nil {
+		t.Error("DescribeRepository() with rejected static token should fail")
+	}
+	if got := logins.Load(); got != 0 {
+		t.Errorf("logins = %d, want 0", got)
+	}
+}
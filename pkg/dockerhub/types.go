@@ -28,6 +28,7 @@ type Repository struct {
 	RepositoryType    string    `json:"repository_type"`
 	Status            int       `json:"status"`
 	Description       string    `json:"description"`
+	FullDescription   string    `json:"full_description,omitempty"`
 	IsPrivate         bool      `json:"is_private"`
 	IsAutomated       bool      `json:"is_automated"`
 	CanEdit           bool      `json:"can_edit"`
@@ -66,6 +67,7 @@ type Image struct {
 // Tag represents docker tag information returned from hub.docker.com
 type Tag struct {
 	Creator         int64     `json:"creator"`
+	Digest          string    `json:"digest"`
 	ID              int64     `json:"id"`
 	ImageID         string    `json:"image_id"`
 	Images          []*Image  `json:"images"`
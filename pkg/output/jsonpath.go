@@ -0,0 +1,220 @@
+/*
+Copyright © 2020 Yevhen Lebid ealebed@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// JSONPathExpression represents kubectl-like JSONPath template, e.g. '{.name}{"\t"}{.images[*].architecture}',
+// where text outside braces is printed as is and every path prints all matched values separated by space
+type JSONPathExpression struct {
+	parts []jsonPathPart
+}
+
+// jsonPathPart represents literal text or path of JSONPath template
+type jsonPathPart struct {
+	text  string
+	steps []jsonPathStep
+	path  bool
+}
+
+// jsonPathStep represents object field, array index or all elements ("[*]" or ".*") selection
+type jsonPathStep struct {
+	field string
+	index int
+	all   bool
+}
+
+// ParseJSONPath parses JSONPath template
+func ParseJSONPath(expression string) (*JSONPathExpression, error) {
+	e := &JSONPathExpression{}
+	for rest := expression; rest != ""; {
+		start := strings.IndexByte(rest, '{')
+		if start < 0 {
+			e.parts = append(e.parts, jsonPathPart{text: rest})
+			break
+		}
+		if start > 0 {
+			e.parts = append(e.parts, jsonPathPart{text: rest[:start]})
+		}
+
+		end := strings.IndexByte(rest[start:], '}')
+		if end < 0 {
+			return nil, fmt.Errorf("invalid jsonpath %q: unclosed '{'", expression)
+		}
+		part, err := parseJSONPathPart(strings.TrimSpace(rest[start+1 : start+end]))
+		if err != nil {
+			return nil, fmt.Errorf("invalid jsonpath %q: %w", expression, err)
+		}
+		e.parts = append(e.parts, part)
+		rest = rest[start+end+1:]
+	}
+
+	if len(e.parts) == 0 {
+		return nil, fmt.Errorf("empty jsonpath")
+	}
+
+	return e, nil
+}
+
+// parseJSONPathPart parses content of braces: quoted string literal or path starting with '.', '$' or '@'
+func parseJSONPathPart(s string) (jsonPathPart, error) {
+	if strings.HasPrefix(s, `"`) {
+		text, err := strconv.Unquote(s)
+		if err != nil {
+			return jsonPathPart{}, fmt.Errorf("invalid string literal %s", s)
+		}
+		return jsonPathPart{text: text}, nil
+	}
+
+	if s == "" || !strings.ContainsRune(".$@", rune(s[0])) {
+		return jsonPathPart{}, fmt.Errorf("path %q should start with '.'", s)
+	}
+	if s[0] != '.' {
+		s = s[1:]
+	}
+
+	part := jsonPathPart{path: true}
+	for s != "" {
+		switch s[0] {
+		case '.':
+			s = s[1:]
+			end := strings.IndexAny(s, ".[")
+			if end < 0 {
+				end = len(s)
+			}
+			field := s[:end]
+			s = s[end:]
+			switch field {
+			case "":
+				if s != "" {
+					return jsonPathPart{}, fmt.Errorf("empty field name")
+				}
+			case "*":
+				part.steps = append(part.steps, jsonPathStep{all: true})
+			default:
+				part.steps = append(part.steps, jsonPathStep{field: field})
+			}
+		case '[':
+			end := strings.IndexByte(s, ']')
+			if end < 0 {
+				return jsonPathPart{}, fmt.Errorf("unclosed '['")
+			}
+			selector := s[1:end]
+			s = s[end+1:]
+			if selector == "*" {
+				part.steps = append(part.steps, jsonPathStep{all: true})
+				continue
+			}
+			if field, err := strconv.Unquote(strings.ReplaceAll(selector, "'", `"`)); err == nil {
+				part.steps = append(part.steps, jsonPathStep{field: field})
+				continue
+			}
+			index, err := strconv.Atoi(selector)
+			if err != nil {
+				return jsonPathPart{}, fmt.Errorf("invalid array index %q", selector)
+			}
+			part.steps = append(part.steps, jsonPathStep{index: index})
+		default:
+			return jsonPathPart{}, fmt.Errorf("unexpected %q", s)
+		}
+	}
+
+	return part, nil
+}
+
+// Execute writes JSONPath template applied to generic JSON value (maps, slices and scalars)
+func (e *JSONPathExpression) Execute(w io.Writer, data any) error {
+	for _, part := range e.parts {
+		if !part.path {
+			if _, err := io.WriteString(w, part.text); err != nil {
+				return err
+			}
+			continue
+		}
+
+		values := []string{}
+		for _, value := range evaluate(part.steps, data) {
+			values = append(values, formatJSONValue(value))
+		}
+		if _, err := io.WriteString(w, strings.Join(values, " ")); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// evaluate returns values matched by path steps, missing fields and indexes match nothing
+func evaluate(steps []jsonPathStep, value any) []any {
+	if len(steps) == 0 {
+		return []any{value}
+	}
+
+	step, rest := steps[0], steps[1:]
+	switch v := value.(type) {
+	case map[string]any:
+		if step.all {
+			matched := []any{}
+			for _, key := range slices.Sorted(maps.Keys(v)) {
+				matched = append(matched, evaluate(rest, v[key])...)
+			}
+			return matched
+		}
+		if child, ok := v[step.field]; ok && step.field != "" {
+			return evaluate(rest, child)
+		}
+	case []any:
+		if step.all {
+			matched := []any{}
+			for _, child := range v {
+				matched = append(matched, evaluate(rest, child)...)
+			}
+			return matched
+		}
+		index := step.index
+		if index < 0 {
+			index += len(v)
+		}
+		if step.field == "" && index >= 0 && index < len(v) {
+			return evaluate(rest, v[index])
+		}
+	}
+
+	return nil
+}
+
+// formatJSONValue returns strings as is, and other values as compact JSON
+func formatJSONValue(value any) string {
+	if s, ok := value.(string); ok {
+		return s
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+
+	return string(data)
+}
@@ -0,0 +1,79 @@
+/*
+Copyright © 2020 Yevhen Lebid ealebed@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestJSONPath(t *testing.T) {
+	var data any
+	if err := json.Unmarshal([]byte(`{
+		"name": "1.2.0",
+		"full_size": 1024,
+		"v2": true,
+		"images": [
+			{"architecture": "amd64", "os": "linux"},
+			{"architecture": "arm64", "os": "linux", "variant": "v8"}
+		],
+		"labels": {"b": "2", "a": "1"}
+	}`), &data); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		expression string
+		want       string
+	}{
+		{expression: "{.name}", want: "1.2.0"},
+		{expression: "{$.full_size} {@.v2}", want: "1024 true"},
+		{expression: "tag={.name}", want: "tag=1.2.0"},
+		{expression: "{.images[*].architecture}", want: "amd64 arm64"},
+		{expression: "{.images[1].variant}", want: "v8"},
+		{expression: "{.images[-1].architecture}", want: "arm64"},
+		{expression: "{.images[*]['os']}", want: "linux linux"},
+		{expression: "{.labels.*}", want: "1 2"},
+		{expression: "{.images[0]}", want: `{"architecture":"amd64","os":"linux"}`},
+		{expression: `{.name}{"\t"}{.missing}{.images[5].os}`, want: "1.2.0\t"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			e, err := ParseJSONPath(tt.expression)
+			if err != nil {
+				t.Fatalf("ParseJSONPath() error = %v", err)
+			}
+			var buf bytes.Buffer
+			if err := e.Execute(&buf, data); err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+			if buf.String() != tt.want {
+				t.Errorf("Execute() = %q, want %q", buf.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestParseJSONPathErrors(t *testing.T) {
+	for _, expression := range []string{"", "{name}", "{.name", "{.images[}", "{.images[x]}", `{"unterminated}`, "{.a..b}"} {
+		if _, err := ParseJSONPath(expression); err == nil {
+			t.Errorf("ParseJSONPath(%q) should fail", expression)
+		}
+	}
+}
@@ -0,0 +1,339 @@
+/*
+Copyright © 2020 Yevhen Lebid ealebed@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package output prints command results as table, JSON, YAML, CSV, TSV, Markdown, Go template or JSONPath expression
+package output
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Output formats
+const (
+	Table    = "table"
+	Wide     = "wide"
+	JSON     = "json"
+	YAML     = "yaml"
+	CSV      = "csv"
+	TSV      = "tsv"
+	Markdown = "markdown"
+	Template = "template"
+	JSONPath = "jsonpath"
+)
+
+// Formats lists values accepted by '--output' flag
+var Formats = []string{Table, Wide, JSON, YAML, CSV, TSV, Markdown, Template, JSONPath + "=..."}
+
+// Column represents table, CSV and TSV column of printed item
+type Column[T any] struct {
+	Header string
+	// Wide columns are printed in wide table, CSV and TSV only
+	Wide  bool
+	Value func(item T) string
+}
+
+// Printer represents output format of command results
+type Printer struct {
+	Format   string
+	Out      io.Writer
+	template *template.Template
+	jsonPath *JSONPathExpression
+}
+
+// NewPrinter returns printer for '--output' format ("jsonpath=EXPRESSION" for JSONPath) and '--template' Go template,
+// provided template selects template format
+func NewPrinter(out io.Writer, format, tmpl string) (*Printer, error) {
+	p := &Printer{Format: format, Out: out}
+	if p.Format == "" {
+		p.Format = Table
+	}
+
+	if tmpl != "" {
+		if p.Format != Table && p.Format != Template {
+			return nil, fmt.Errorf("'--template' can't be used with %s output", p.Format)
+		}
+		p.Format = Template
+	}
+
+	if expression, ok := strings.CutPrefix(p.Format, JSONPath+"="); ok {
+		jsonPath, err := ParseJSONPath(expression)
+		if err != nil {
+			return nil, err
+		}
+		p.Format, p.jsonPath = JSONPath, jsonPath
+	}
+
+	switch p.Format {
+	case Table, Wide, JSON, YAML, CSV, TSV, Markdown:
+	case Template:
+		if tmpl == "" {
+			return nil, fmt.Errorf("template output requires '--template'")
+		}
+		t, err := template.New("output").Option("missingkey=error").Parse(tmpl)
+		if err != nil {
+			return nil, fmt.Errorf("invalid template: %w", err)
+		}
+		p.template = t
+	case JSONPath:
+		if p.jsonPath == nil {
+			return nil, fmt.Errorf("jsonpath output requires expression, e.g. 'jsonpath={.name}'")
+		}
+	default:
+		return nil, fmt.Errorf("unsupported output %q, should be one of: %s", format, strings.Join(Formats, ", "))
+	}
+
+	return p, nil
+}
+
+// IsTable reports whether results are printed as human readable table
+func (p *Printer) IsTable() bool {
+	return p.Format == Table || p.Format == Wide
+}
+
+// IsWide reports whether table includes wide columns
+func (p *Printer) IsWide() bool {
+	return p.Format == Wide
+}
+
+// PrintList prints items: JSON and YAML as array, template and JSONPath once per item, table, CSV, TSV and Markdown as rows
+func PrintList[T any](p *Printer, items []T, columns []Column[T]) error {
+	switch p.Format {
+	case JSON:
+		return p.printJSON(items)
+	case YAML:
+		return p.printYAML(items)
+	case Template, JSONPath:
+		for _, item := range items {
+			if err := p.printItem(item); err != nil {
+				return err
+			}
+		}
+		return nil
+	case CSV, TSV:
+		return printDelimited(p.Out, p.Format, items, columns)
+	case Markdown:
+		return printMarkdown(p.Out, items, columns)
+	}
+
+	return printTable(p.Out, items, columns, p.IsWide())
+}
+
+// PrintObject prints single item: JSON and YAML as object, table as "Header: value" lines, Markdown as field/value table
+func PrintObject[T any](p *Printer, item T, columns []Column[T]) error {
+	switch p.Format {
+	case JSON:
+		return p.printJSON(item)
+	case YAML:
+		return p.printYAML(item)
+	case Template, JSONPath:
+		return p.printItem(item)
+	case CSV, TSV:
+		return printDelimited(p.Out, p.Format, []T{item}, columns)
+	case Markdown:
+		if _, err := fmt.Fprint(p.Out, "| field | value |\n| --- | --- |\n"); err != nil {
+			return err
+		}
+		for _, column := range columns {
+			if _, err := fmt.Fprintf(p.Out, "| %s | %s |\n", markdownCell(column.Header), markdownCell(column.Value(item))); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	w := tabwriter.NewWriter(p.Out, 0, 0, 1, ' ', 0)
+	for _, column := range columns {
+		if column.Wide && !p.IsWide() {
+			continue
+		}
+		if _, err := fmt.Fprintf(w, "%s:\t%s\n", column.Header, column.Value(item)); err != nil {
+			return err
+		}
+	}
+
+	return w.Flush()
+}
+
+func (p *Printer) printJSON(v any) error {
+	encoder := json.NewEncoder(p.Out)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(v)
+}
+
+// printYAML prints value with the same field names as JSON, converting it through JSON document
+func (p *Printer) printYAML(v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	node := &yaml.Node{}
+	if err := yaml.Unmarshal(data, node); err != nil {
+		return err
+	}
+	blockStyle(node)
+
+	encoder := yaml.NewEncoder(p.Out)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(node); err != nil {
+		return err
+	}
+
+	return encoder.Close()
+}
+
+// blockStyle resets flow style and quoting of YAML nodes decoded from JSON document
+func blockStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		blockStyle(child)
+	}
+}
+
+// printItem prints single item with Go template or JSONPath expression, followed by newline
+func (p *Printer) printItem(item any) error {
+	var buf bytes.Buffer
+	if p.template != nil {
+		if err := p.template.Execute(&buf, item); err != nil {
+			return fmt.Errorf("failed to execute template: %w", err)
+		}
+	} else {
+		data, err := toJSONValue(item)
+		if err != nil {
+			return err
+		}
+		if err := p.jsonPath.Execute(&buf, data); err != nil {
+			return err
+		}
+	}
+
+	if !bytes.HasSuffix(buf.Bytes(), []byte("\n")) {
+		buf.WriteByte('\n')
+	}
+	_, err := p.Out.Write(buf.Bytes())
+
+	return err
+}
+
+// toJSONValue returns generic JSON representation of item, used by JSONPath expressions
+func toJSONValue(item any) (any, error) {
+	data, err := json.Marshal(item)
+	if err != nil {
+		return nil, err
+	}
+
+	var value any
+	if err := json.Unmarshal(data, &value); err != nil {
+		return nil, err
+	}
+
+	return value, nil
+}
+
+// printDelimited prints header and rows with all columns, separated by comma (CSV) or tab (TSV)
+func printDelimited[T any](out io.Writer, format string, items []T, columns []Column[T]) error {
+	w := csv.NewWriter(out)
+	if format == TSV {
+		w.Comma = '\t'
+	}
+
+	row := make([]string, len(columns))
+	for i, column := range columns {
+		row[i] = column.Header
+	}
+	if err := w.Write(row); err != nil {
+		return err
+	}
+
+	for _, item := range items {
+		for i, column := range columns {
+			row[i] = column.Value(item)
+		}
+		if err := w.Write(row); err != nil {
+			return err
+		}
+	}
+	w.Flush()
+
+	return w.Error()
+}
+
+// printTable prints items as aligned table, wide columns are included in wide table only
+func printTable[T any](out io.Writer, items []T, columns []Column[T], wide bool) error {
+	visible := []Column[T]{}
+	for _, column := range columns {
+		if !column.Wide || wide {
+			visible = append(visible, column)
+		}
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 3, ' ', 0)
+	row := make([]string, len(visible))
+	for i, column := range visible {
+		row[i] = strings.ToUpper(column.Header)
+	}
+	if _, err := fmt.Fprintln(w, strings.Join(row, "\t")); err != nil {
+		return err
+	}
+
+	for _, item := range items {
+		for i, column := range visible {
+			row[i] = column.Value(item)
+		}
+		if _, err := fmt.Fprintln(w, strings.Join(row, "\t")); err != nil {
+			return err
+		}
+	}
+
+	return w.Flush()
+}
+
+// printMarkdown prints items as Markdown (GitHub flavored) table with all columns
+func printMarkdown[T any](out io.Writer, items []T, columns []Column[T]) error {
+	row := make([]string, len(columns))
+	for i, column := range columns {
+		row[i] = markdownCell(column.Header)
+	}
+	if _, err := fmt.Fprintf(out, "| %s |\n|%s\n", strings.Join(row, " | "), strings.Repeat(" --- |", len(columns))); err != nil {
+		return err
+	}
+
+	for _, item := range items {
+		for i, column := range columns {
+			row[i] = markdownCell(column.Value(item))
+		}
+		if _, err := fmt.Fprintf(out, "| %s |\n", strings.Join(row, " | ")); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// markdownCell escapes pipes and line breaks, which would break Markdown table row
+func markdownCell(s string) string {
+	return strings.NewReplacer("|", `\|`, "\r\n", " ", "\n", " ").Replace(s)
+}
@@ -0,0 +1,143 @@
+/*
+Copyright © 2020 Yevhen Lebid ealebed@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package output
+
+import (
+	"bytes"
+	"strconv"
+	"testing"
+)
+
+type testItem struct {
+	Name   string   `json:"name"`
+	Size   int      `json:"size"`
+	Labels []string `json:"labels,omitempty"`
+}
+
+var testColumns = []Column[*testItem]{
+	{Header: "name", Value: func(i *testItem) string { return i.Name }},
+	{Header: "size", Wide: true, Value: func(i *testItem) string { return strconv.Itoa(i.Size) }},
+}
+
+var testItems = []*testItem{{Name: "api", Size: 10, Labels: []string{"a", "b"}}, {Name: "web, front", Size: 20}}
+
+func TestPrintList(t *testing.T) {
+	tests := []struct {
+		format   string
+		template string
+		want     string
+	}{
+		{format: JSON, want: `[
+  {
+    "name": "api",
+    "size": 10,
+    "labels": [
+      "a",
+      "b"
+    ]
+  },
+  {
+    "name": "web, front",
+    "size": 20
+  }
+]
+`},
+		{format: YAML, want: `- name: api
+  size: 10
+  labels:
+    - a
+    - b
+- name: web, front
+  size: 20
+`},
+		{format: CSV, want: "name,size\napi,10\n\"web, front\",20\n"},
+		{format: TSV, want: "name\tsize\napi\t10\nweb, front\t20\n"},
+		{format: Markdown, want: "| name | size |\n| --- | --- |\n| api | 10 |\n| web, front | 20 |\n"},
+		{format: Table, want: "NAME\napi\nweb, front\n"},
+		{format: Wide, want: "NAME         SIZE\napi          10\nweb, front   20\n"},
+		{template: "{{.Name}}={{.Size}}", want: "api=10\nweb, front=20\n"},
+		{format: `jsonpath={.name}{"\t"}{.labels[*]}`, want: "api\ta b\nweb, front\t\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format+tt.template, func(t *testing.T) {
+			var buf bytes.Buffer
+			printer, err := NewPrinter(&buf, tt.format, tt.template)
+			if err != nil {
+				t.Fatalf("NewPrinter() error = %v", err)
+			}
+			if err := PrintList(printer, testItems, testColumns); err != nil {
+				t.Fatalf("PrintList() error = %v", err)
+			}
+			if buf.String() != tt.want {
+				t.Errorf("PrintList() =\n%s\nwant\n%s", buf.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestPrintObject(t *testing.T) {
+	var buf bytes.Buffer
+	printer, err := NewPrinter(&buf, JSON, "")
+	if err != nil {
+		t.Fatalf("NewPrinter() error = %v", err)
+	}
+	if err := PrintObject(printer, testItems[1], testColumns); err != nil {
+		t.Fatalf("PrintObject() error = %v", err)
+	}
+	if want := "{\n  \"name\": \"web, front\",\n  \"size\": 20\n}\n"; buf.String() != want {
+		t.Errorf("PrintObject() = %q, want %q", buf.String(), want)
+	}
+
+	buf.Reset()
+	printer, _ = NewPrinter(&buf, Wide, "")
+	if err := PrintObject(printer, testItems[0], testColumns); err != nil {
+		t.Fatalf("PrintObject() error = %v", err)
+	}
+	if want := "name: api\nsize: 10\n"; buf.String() != want {
+		t.Errorf("PrintObject() = %q, want %q", buf.String(), want)
+	}
+
+	buf.Reset()
+	printer, _ = NewPrinter(&buf, Markdown, "")
+	if err := PrintObject(printer, &testItem{Name: "a|b", Size: 1}, testColumns); err != nil {
+		t.Fatalf("PrintObject() error = %v", err)
+	}
+	if want := "| field | value |\n| --- | --- |\n| name | a\\|b |\n| size | 1 |\n"; buf.String() != want {
+		t.Errorf("PrintObject() = %q, want %q", buf.String(), want)
+	}
+}
+
+func TestNewPrinterErrors(t *testing.T) {
+	tests := []struct {
+		format   string
+		template string
+	}{
+		{format: "xml"},
+		{format: Template},
+		{format: JSONPath},
+		{format: "jsonpath={.name"},
+		{format: JSON, template: "{{.Name}}"},
+		{template: "{{.Name"},
+	}
+
+	for _, tt := range tests {
+		if _, err := NewPrinter(&bytes.Buffer{}, tt.format, tt.template); err == nil {
+			t.Errorf("NewPrinter(%q, %q) should fail", tt.format, tt.template)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+/*
+Copyright © 2020 Yevhen Lebid ealebed@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package pool runs bulk docker hub operations concurrently with bounded number of workers and shared rate limit
+package pool
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// Pool represents bounded worker pool
+type Pool struct {
+	// Concurrency limits number of items processed at once, runtime.NumCPU() is used when not positive
+	Concurrency int
+	// Limiter limits rate of starting items, shared by all workers, items aren't limited when nil
+	Limiter *Limiter
+}
+
+// New returns worker pool with provided concurrency, starting items not more often than once per interval
+func New(concurrency int, interval time.Duration) *Pool {
+	return &Pool{Concurrency: concurrency, Limiter: NewLimiter(interval)}
+}
+
+// Results represents outcome of processing items, values and errors are in order of items
+type Results[T any] struct {
+	Values []T
+	Errors []error
+	// Started counts items started before context cancellation, items after them aren't processed
+	Started int
+}
+
+// Failed returns count of items failed with error
+func (r *Results[T]) Failed() int {
+	failed := 0
+	for _, err := range r.Errors {
+		if err != nil {
+			failed++
+		}
+	}
+
+	return failed
+}
+
+// Err returns errors of all failed items joined in order of items, or nil
+func (r *Results[T]) Err() error {
+	return errors.Join(r.Errors...)
+}
+
+// Map processes items with fn concurrently and waits for all started items,
+// on context cancellation it stops starting new items and lets started ones finish
+func Map[I, O any](ctx context.Context, p *Pool, items []I, fn func(ctx context.Context, index int, item I) (O, error)) *Results[O] {
+	results := &Results[O]{Values: make([]O, len(items)), Errors: make([]error, len(items))}
+	workers := make(chan struct{}, p.concurrency())
+	var wg sync.WaitGroup
+
+schedule:
+	for i, item := range items {
+		select {
+		case <-ctx.Done():
+			break schedule
+		case workers <- struct{}{}:
+		}
+		if err := p.Limiter.Wait(ctx); err != nil {
+			<-workers
+			break
+		}
+
+		results.Started++
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-workers }()
+			results.Values[i], results.Errors[i] = fn(ctx, i, item)
+		}()
+	}
+
+	wg.Wait()
+
+	return results
+}
+
+// Run processes items with fn concurrently, like Map for functions without result
+func Run[I any](ctx context.Context, p *Pool, items []I, fn func(ctx context.Context, index int, item I) error) *Results[struct{}] {
+	return Map(ctx, p, items, func(ctx context.Context, index int, item I) (struct{}, error) {
+		return struct{}{}, fn(ctx, index, item)
+	})
+}
+
+func (p *Pool) concurrency() int {
+	if p.Concurrency <= 0 {
+		return runtime.NumCPU()
+	}
+
+	return p.Concurrency
+}
+
+// Limiter represents rate limit, allowing single event per interval, shared by concurrent goroutines
+type Limiter struct {
+	interval time.Duration
+	mu       sync.Mutex
+	next     time.Time
+}
+
+// NewLimiter returns rate limiter allowing single event per interval, or nil (no limit) for not positive interval
+func NewLimiter(interval time.Duration) *Limiter {
+	if interval <= 0 {
+		return nil
+	}
+
+	return &Limiter{interval: interval}
+}
+
+// Wait blocks until next event is allowed or context is cancelled, nil limiter never blocks
+func (l *Limiter) Wait(ctx context.Context) error {
+	if l == nil {
+		return ctx.Err()
+	}
+
+	l.mu.Lock()
+	now := time.Now()
+	at := l.next
+	if at.Before(now) {
+		at = now
+	}
+	l.next = at.Add(l.interval)
+	l.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if !at.After(now) {
+		return nil
+	}
+
+	timer := time.NewTimer(at.Sub(now))
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,125 @@
+/*
+Copyright © 2020 Yevhen Lebid ealebed@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMapOrderedResults(t *testing.T) {
+	items := []int{5, 1, 4, 2, 3}
+
+	results := Map(context.Background(), &Pool{Concurrency: 3}, items, func(_ context.Context, index, item int) (string, error) {
+		// later items finish first
+		time.Sleep(time.Duration(len(items)-index) * time.Millisecond)
+		if item == 4 {
+			return "", fmt.Errorf("item %d failed", item)
+		}
+		return fmt.Sprint(item * 10), nil
+	})
+
+	want := []string{"50", "10", "", "20", "30"}
+	for i := range want {
+		if results.Values[i] != want[i] {
+			t.Errorf("Values[%d] = %q, want %q", i, results.Values[i], want[i])
+		}
+	}
+	if results.Started != len(items) {
+		t.Errorf("Started = %d, want %d", results.Started, len(items))
+	}
+	if results.Failed() != 1 || results.Errors[2] == nil {
+		t.Errorf("Errors = %v, want single error of item 4", results.Errors)
+	}
+	if err := results.Err(); err == nil || err.Error() != "item 4 failed" {
+		t.Errorf("Err() = %v, want item 4 failed", err)
+	}
+}
+
+func TestMapConcurrencyLimit(t *testing.T) {
+	var running, peak atomic.Int32
+
+	Run(context.Background(), &Pool{Concurrency: 2}, make([]struct{}, 10), func(context.Context, int, struct{}) error {
+		n := running.Add(1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		running.Add(-1)
+		return nil
+	})
+
+	if got := peak.Load(); got != 2 {
+		t.Errorf("peak concurrency = %d, want 2", got)
+	}
+}
+
+func TestMapCancellation(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var finished atomic.Int32
+	results := Run(ctx, &Pool{Concurrency: 1}, make([]int, 5), func(ctx context.Context, index, _ int) error {
+		if index == 1 {
+			cancel()
+		}
+		finished.Add(1)
+		return nil
+	})
+
+	if results.Started != 2 {
+		t.Errorf("Started = %d, want 2", results.Started)
+	}
+	if got := finished.Load(); got != 2 {
+		t.Errorf("finished = %d, started items should finish", got)
+	}
+	if results.Err() != nil {
+		t.Errorf("Err() = %v, not started items aren't errors", results.Err())
+	}
+}
+
+func TestLimiter(t *testing.T) {
+	limiter := NewLimiter(20 * time.Millisecond)
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if err := limiter.Wait(context.Background()); err != nil {
+			t.Fatalf("Wait() error = %v", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 40*time.Millisecond {
+		t.Errorf("3 events took %v, want at least 40ms", elapsed)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := limiter.Wait(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("Wait() with cancelled context error = %v", err)
+	}
+
+	var unlimited *Limiter
+	if NewLimiter(0) != nil || unlimited.Wait(context.Background()) != nil {
+		t.Error("nil limiter should never block")
+	}
+}